@@ -0,0 +1,160 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+)
+
+// OutputLimit bounds the output of a command kept in memory.
+type OutputLimit struct {
+	MaxSize int64  // Maximum number of bytes of each stream kept in memory. Unbounded if not positive.
+	Dir     string // Directory of the files to which streams larger than MaxSize are written. Defaults to os.TempDir().
+}
+
+// OutputFile references a file containing the whole content of a stream that
+// exceeded the in-memory limit. The file is not removed by the executor.
+type OutputFile struct {
+	Path   string `json:"path" bson:"path,omitempty"`     // Path of the file.
+	Size   int64  `json:"size" bson:"size,omitempty"`     // Size of the stream, in bytes.
+	SHA256 string `json:"sha256" bson:"sha256,omitempty"` // Hex-encoded SHA-256 of the stream.
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// capture is the writer used by runtimes to capture a stream. It keeps up to
+// MaxSize bytes in memory. When the stream gets larger, all of it is written
+// to a file and only the beginning is kept in memory as a preview.
+type capture struct {
+	limit OutputLimit
+	name  string // Prefix of the file name.
+	buf   bytes.Buffer
+	file  *os.File
+	hash  hash.Hash
+	size  int64
+	err   error       // Error writing to the file, if any.
+	ref   *OutputFile // Reference to the closed file.
+
+	logger   *slog.Logger
+	redactor io.Writer // Redacts the secrets before storing the stream, if any.
+}
+
+// newCapture returns a capture of the stream, whose file is named after name.
+// Errors writing the file are logged with the logger carried by ctx and the
+// secrets carried by ctx are redacted from the stream.
+func newCapture(ctx context.Context, limit OutputLimit, name string) *capture {
+	c := &capture{limit: limit, name: unsafeFileChars.ReplaceAllString(name, "_"), logger: loggerFrom(ctx)}
+	c.redactor = newRedactWriter(storeWriter{c}, secretsFrom(ctx))
+	return c
+}
+
+// storeWriter stores the stream written to the capture.
+type storeWriter struct{ c *capture }
+
+func (w storeWriter) Write(p []byte) (int, error) {
+	return w.c.store(p)
+}
+
+func (c *capture) Write(p []byte) (int, error) {
+	if c.redactor != nil {
+		return c.redactor.Write(p)
+	}
+	return c.store(p)
+}
+
+// store keeps the stream in memory and, once it exceeds the limit, in the
+// file.
+func (c *capture) store(p []byte) (int, error) {
+	max := c.limit.MaxSize
+	if max <= 0 || (c.file == nil && c.err == nil && int64(c.buf.Len()+len(p)) <= max) {
+		c.buf.Write(p)
+		return len(p), nil
+	}
+	if c.file == nil && c.err == nil {
+		c.spill()
+	}
+	if c.file != nil {
+		if _, err := c.file.Write(p); err != nil {
+			c.fail(err)
+		}
+		c.hash.Write(p)
+		c.size += int64(len(p))
+	}
+	if remaining := max - int64(c.buf.Len()); remaining > 0 {
+		c.buf.Write(p[:min(int64(len(p)), remaining)])
+	}
+	return len(p), nil
+}
+
+// spill creates the file and writes the content in memory to it.
+func (c *capture) spill() {
+	f, err := os.CreateTemp(c.limit.Dir, c.name+"-*.log")
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	c.file = f
+	c.hash = sha256.New()
+	c.size = int64(c.buf.Len())
+	c.hash.Write(c.buf.Bytes())
+	if _, err := f.Write(c.buf.Bytes()); err != nil {
+		c.fail(err)
+	}
+}
+
+// fail stops writing to the file, keeping only the preview. The command
+// result is still useful, so the error is only logged.
+func (c *capture) fail(err error) {
+	c.err = err
+	c.logger.Warn("error writing output to file, keeping only its beginning", "output", c.name, "max_size", c.limit.MaxSize, errAttr(err))
+	if c.file != nil {
+		c.file.Close()
+		os.Remove(c.file.Name())
+		c.file = nil
+	}
+}
+
+// String returns the content kept in memory: the whole stream or its preview.
+func (c *capture) String() string {
+	flush(c.redactor)
+	return c.buf.String()
+}
+
+// File closes the file and returns its reference, or nil if the stream fit in
+// memory.
+func (c *capture) File() *OutputFile {
+	flush(c.redactor)
+	if c.file != nil {
+		if err := c.file.Close(); err != nil {
+			c.fail(err)
+			return nil
+		}
+		c.ref = &OutputFile{
+			Path:   c.file.Name(),
+			Size:   c.size,
+			SHA256: hex.EncodeToString(c.hash.Sum(nil)),
+		}
+		c.file = nil
+	}
+	return c.ref
+}
+
+// openStdin returns the standard input of the run: the content of StdinFile,
+// if set, or Stdin.
+func (spec RunSpec) openStdin() (io.ReadCloser, error) {
+	if spec.StdinFile == "" {
+		return io.NopCloser(bytes.NewReader([]byte(spec.Stdin))), nil
+	}
+	f, err := os.Open(spec.StdinFile)
+	if err != nil {
+		return nil, fmt.Errorf("error opening stdin file: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	data := []string{"0123", "4567", "89"}
+	testCases := []struct {
+		desc        string
+		maxSize     int64
+		wantPreview string
+		wantFile    bool
+	}{
+		{"Unbounded", 0, "0123456789", false},
+		{"Fits in memory", 10, "0123456789", false},
+		{"Spilled to disk", 6, "012345", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := newCapture(context.Background(), OutputLimit{MaxSize: tc.maxSize, Dir: t.TempDir()}, "stage/run:stdout")
+			for _, d := range data {
+				io.WriteString(c, d)
+			}
+			if c.String() != tc.wantPreview {
+				t.Errorf("want preview %q, got %q", tc.wantPreview, c.String())
+			}
+			f := c.File()
+			if (f != nil) != tc.wantFile {
+				t.Fatalf("want file %v, got %+v", tc.wantFile, f)
+			}
+			if f == nil {
+				return
+			}
+			b, err := os.ReadFile(f.Path)
+			if err != nil || string(b) != "0123456789" {
+				t.Errorf("want whole output in file, got %q (%v)", b, err)
+			}
+			sum := sha256.Sum256([]byte("0123456789"))
+			if f.Size != 10 || f.SHA256 != hex.EncodeToString(sum[:]) {
+				t.Errorf("want size 10 and sha256 of output, got %+v", f)
+			}
+			if strings.ContainsAny(f.Path[len(os.TempDir()):], ":") {
+				t.Errorf("want unsafe characters removed from file name, got %s", f.Path)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	checkpointFile      = "checkpoint.json"
+	checkpointVolumeDir = "volume"
+	checkpointStdoutDir = "stdout"
+)
+
+// Checkpoint is the state of a pipeline execution after its last successful
+// stage. It is saved in the pipeline CheckpointDir and allows resuming a failed
+// execution without executing the completed stages again.
+type Checkpoint struct {
+	PipelineHash string            `json:"pipeline-hash"` // Hash of the pipeline definition.
+	Stages       []StageCheckpoint `json:"stages"`        // Completed stages, in the order they finished.
+	SavedAt      time.Time         `json:"saved-at"`      // Time at which the checkpoint was saved.
+
+	dir string // Directory from where the checkpoint has been loaded.
+}
+
+// StageCheckpoint is the state of a completed stage.
+type StageCheckpoint struct {
+	Name       string      `json:"name"`        // Stage's name.
+	Stdout     string      `json:"stdout"`      // Standard output of the stage run, used as input of the stages depending on it.
+	StdoutFile *OutputFile `json:"stdout-file"` // File containing the whole standard output, if it exceeded the in-memory limit. It is copied to the checkpoint dir, relative to which its path is saved.
+	CommitID   string      `json:"commit"`      // Commit of the stage repo, if any.
+}
+
+// LoadCheckpoint reads the checkpoint saved in dir, which is the
+// CheckpointDir of the failed pipeline.
+func LoadCheckpoint(dir string) (*Checkpoint, error) {
+	b, err := os.ReadFile(filepath.Join(dir, checkpointFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint: %w", err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, fmt.Errorf("error decoding checkpoint: %w", err)
+	}
+	for _, sc := range cp.Stages {
+		if sc.StdoutFile != nil && !filepath.IsAbs(sc.StdoutFile.Path) {
+			sc.StdoutFile.Path = filepath.Join(dir, sc.StdoutFile.Path)
+		}
+	}
+	cp.dir = dir
+	return &cp, nil
+}
+
+// hash returns the hash of the pipeline definition. The checkpoint, log and
+// output directories are not part of the definition.
+func (p Pipeline) hash() (string, error) {
+	p.CheckpointDir = ""
+	p.LogDir = ""
+	p.GitCacheDir = ""
+	p.OutputDir = ""
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("error encoding pipeline definition: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkResume verifies whether the pipeline can be resumed from the
+// checkpoint: the pipeline definition and the commits of the completed stages
+// must not have changed. If force is true, those are only logged. The saved
+// stdout files must be intact, even if force is true.
+func (p *Pipeline) checkResume(ctx context.Context, cp *Checkpoint, force bool) error {
+	for _, sc := range cp.Stages {
+		if sc.StdoutFile == nil {
+			continue
+		}
+		if err := checkOutputFile(*sc.StdoutFile); err != nil {
+			return fmt.Errorf("can not resume pipeline: stdout of stage %s: %w", sc.Name, err)
+		}
+	}
+	var problems []string
+	h, err := p.hash()
+	if err != nil {
+		return err
+	}
+	if h != cp.PipelineHash {
+		problems = append(problems, "pipeline definition has changed")
+	}
+	for _, sc := range cp.Stages {
+		i := p.stageIndex(sc.Name)
+		if i < 0 {
+			problems = append(problems, fmt.Sprintf("completed stage %s is no longer in the pipeline", sc.Name))
+			continue
+		}
+		if p.Stages[i].Repo == "" {
+			continue
+		}
+		stage := p.Stages[i]
+		stage.setDefaults(*p)
+		if stage.Commit != "" {
+			// Pinned commits are compared without reaching the remote.
+			if !strings.HasPrefix(sc.CommitID, strings.ToLower(stage.Commit)) {
+				problems = append(problems, fmt.Sprintf("commit of stage %s has changed from %s to %s", sc.Name, sc.CommitID, stage.Commit))
+			}
+			continue
+		}
+		commit, err := remoteCommit(ctx, stage.Repo, stage.Ref, stage.GitAuth)
+		if err != nil {
+			return fmt.Errorf("error checking commit of stage %s: %w", sc.Name, err)
+		}
+		if commit != sc.CommitID {
+			problems = append(problems, fmt.Sprintf("commit of stage %s has changed from %s to %s", sc.Name, sc.CommitID, commit))
+		}
+	}
+	for _, pr := range problems {
+		if !force {
+			return fmt.Errorf("can not resume pipeline: %s", pr)
+		}
+		loggerFrom(ctx).Warn("forcing resume", "problem", pr)
+	}
+	return nil
+}
+
+// stageIndex returns the position of the stage in the pipeline or -1.
+func (p *Pipeline) stageIndex(name string) int {
+	for i, s := range p.Stages {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkOutputFile returns an error if the file is missing or its content is
+// not the one referenced.
+func checkOutputFile(f OutputFile) error {
+	in, err := os.Open(f.Path)
+	if err != nil {
+		return fmt.Errorf("error opening output file: %w", err)
+	}
+	defer in.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, in)
+	if err != nil {
+		return fmt.Errorf("error reading output file(%s): %w", f.Path, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); n != f.Size || sum != f.SHA256 {
+		return fmt.Errorf("output file(%s) has changed: want %d bytes with sha256 %s, got %d bytes with sha256 %s", f.Path, f.Size, f.SHA256, n, sum)
+	}
+	return nil
+}
+
+// saveCheckpoint writes the checkpoint, the stdout files of its stages and a
+// copy of the shared volume directory to the pipeline CheckpointDir. The
+// stdout files are copied because the files in OutputDir are not kept.
+func (p *Pipeline) saveCheckpoint(cp *Checkpoint) error {
+	cp.SavedAt = time.Now()
+	if err := os.MkdirAll(p.CheckpointDir, 0755); err != nil {
+		return fmt.Errorf("error creating checkpoint dir(%s): %w", p.CheckpointDir, err)
+	}
+	saved := *cp
+	saved.Stages = make([]StageCheckpoint, len(cp.Stages))
+	for i, sc := range cp.Stages {
+		if sc.StdoutFile != nil {
+			f, err := p.saveStdoutFile(sc.Name, *sc.StdoutFile)
+			if err != nil {
+				return err
+			}
+			// Saved files are not copied again.
+			cp.Stages[i].StdoutFile = &f
+			f.Path, _ = filepath.Rel(p.CheckpointDir, f.Path)
+			sc.StdoutFile = &f
+		}
+		saved.Stages[i] = sc
+	}
+	if p.VolumeDir != "" {
+		snapshot := filepath.Join(p.CheckpointDir, checkpointVolumeDir)
+		if err := os.RemoveAll(snapshot); err != nil {
+			return fmt.Errorf("error removing previous volume dir snapshot(%s): %w", snapshot, err)
+		}
+		if err := copyDir(p.VolumeDir, snapshot); err != nil {
+			return fmt.Errorf("error saving volume dir snapshot: %w", err)
+		}
+	}
+	b, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding checkpoint: %w", err)
+	}
+	// Writing to a temporary file first, so a failure never leaves a
+	// corrupted checkpoint behind.
+	tmp := filepath.Join(p.CheckpointDir, checkpointFile+".tmp")
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(p.CheckpointDir, checkpointFile)); err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	return nil
+}
+
+// saveStdoutFile copies the stdout file of the stage to the checkpoint dir,
+// unless it is already there, and returns the reference to the copy.
+func (p *Pipeline) saveStdoutFile(stage string, f OutputFile) (OutputFile, error) {
+	dir := filepath.Join(p.CheckpointDir, checkpointStdoutDir)
+	dst := filepath.Join(dir, unsafeFileChars.ReplaceAllString(stage, "_")+".log")
+	if src, err := os.Stat(f.Path); err == nil {
+		if saved, err := os.Stat(dst); err == nil && os.SameFile(src, saved) {
+			return f, nil
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return OutputFile{}, fmt.Errorf("error creating checkpoint stdout dir(%s): %w", dir, err)
+	}
+	if err := copyFile(f.Path, dst, 0644); err != nil {
+		return OutputFile{}, fmt.Errorf("error saving stdout of stage %s: %w", stage, err)
+	}
+	f.Path = dst
+	return f, nil
+}
+
+// removeCheckpoint removes the files saved by saveCheckpoint.
+func (p *Pipeline) removeCheckpoint() error {
+	if err := os.RemoveAll(filepath.Join(p.CheckpointDir, checkpointStdoutDir)); err != nil {
+		return fmt.Errorf("error removing stdout files: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Join(p.CheckpointDir, checkpointVolumeDir)); err != nil {
+		return fmt.Errorf("error removing volume dir snapshot: %w", err)
+	}
+	if err := os.Remove(filepath.Join(p.CheckpointDir, checkpointFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing checkpoint: %w", err)
+	}
+	return nil
+}
+
+// restoreVolume copies the volume directory snapshot of the checkpoint back to
+// the pipeline VolumeDir.
+func (p *Pipeline) restoreVolume(cp *Checkpoint) error {
+	snapshot := filepath.Join(cp.dir, checkpointVolumeDir)
+	if p.VolumeDir == "" {
+		return nil
+	}
+	if _, err := os.Stat(snapshot); os.IsNotExist(err) {
+		return nil
+	}
+	if err := copyDir(snapshot, p.VolumeDir); err != nil {
+		return fmt.Errorf("error restoring volume dir from checkpoint: %w", err)
+	}
+	return nil
+}
+
+// copyDir recursively copies the contents of src into dst, preserving file
+// modes and symbolic links.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
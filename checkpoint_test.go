@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointVolume(t *testing.T) {
+	p := Pipeline{
+		VolumeDir:     filepath.Join(t.TempDir(), "output"),
+		CheckpointDir: t.TempDir(),
+	}
+	if err := os.MkdirAll(filepath.Join(p.VolumeDir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(p.VolumeDir, "sub", "data.csv"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.saveCheckpoint(&Checkpoint{PipelineHash: "hash"}); err != nil {
+		t.Fatalf("want no error saving checkpoint, got %v", err)
+	}
+	cp, err := LoadCheckpoint(p.CheckpointDir)
+	if err != nil {
+		t.Fatalf("want no error loading checkpoint, got %v", err)
+	}
+	if cp.PipelineHash != "hash" {
+		t.Errorf("want hash %q, got %q", "hash", cp.PipelineHash)
+	}
+
+	if err := os.RemoveAll(p.VolumeDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.restoreVolume(cp); err != nil {
+		t.Fatalf("want no error restoring volume, got %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(p.VolumeDir, "sub", "data.csv"))
+	if err != nil || string(b) != "data" {
+		t.Errorf("want data.csv restored, got %q (%v)", b, err)
+	}
+
+	if err := p.removeCheckpoint(); err != nil {
+		t.Fatalf("want no error removing checkpoint, got %v", err)
+	}
+	if _, err := LoadCheckpoint(p.CheckpointDir); err == nil {
+		t.Errorf("want error loading removed checkpoint")
+	}
+}
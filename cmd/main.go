@@ -1,84 +1,422 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/dadosjusbr/executor"
 	"github.com/dadosjusbr/executor/status"
 	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
 )
 
-var (
-	input          = pflag.String("in", "", "Path for the descriptor file.")
-	volumeName     = pflag.String("volume-name", "", "Shared volume name.")
-	volumeDir      = pflag.String("volume-dir", "", "Shared volume full path.")
-	defaultBaseDir = pflag.String("def-base-dir", "", "Base path to search for stages and to place the cloned repositorie")
-	defaultEnvFlag = pflag.StringSlice("def-run-env", []string{}, "Environment variables that override the default vars.")
-)
+// version is set at build time with -ldflags "-X main.version=v1.2.3". If it
+// is not set, the module version or the VCS revision is used.
+var version = ""
 
-func main() {
-	pflag.Parse()
+const usage = `Usage: executor <command> [flags] [pipeline file]
+
+Commands:
+  run       Execute the pipeline.
+  validate  Check the pipeline spec, without executing anything.
+  plan      Print the resolved stage settings and the commands to be executed.
+  graph     Print the stage dependencies in DOT or Mermaid.
+  prune-cache
+            Remove the git cache mirrors not used recently.
+  version   Print the executor version.
+
+The pipeline file is given by --in or as the first argument, in JSON, YAML
+(.yaml or .yml) or TOML (.toml). Run "executor <command> --help" for the
+command flags.
+`
+
+// pipelineFlags are the flags shared by the commands loading a pipeline.
+type pipelineFlags struct {
+	input          *string
+	volumeName     *string
+	volumeDir      *string
+	defaultBaseDir *string
+	defaultEnv     *[]string
+	gitCacheDir    *string
+	logFormat      *string
+	logLevel       *string
+}
+
+func newPipelineFlags(fs *pflag.FlagSet) pipelineFlags {
+	return pipelineFlags{
+		input:          fs.String("in", "", "Path for the descriptor file, in JSON, YAML (.yaml or .yml) or TOML (.toml)."),
+		volumeName:     fs.String("volume-name", "", "Shared volume name."),
+		volumeDir:      fs.String("volume-dir", "", "Shared volume full path."),
+		defaultBaseDir: fs.String("def-base-dir", "", "Base path to search for stages and to place the cloned repositorie"),
+		defaultEnv:     fs.StringSlice("def-run-env", []string{}, "Environment variables that override the default vars."),
+		gitCacheDir:    fs.String("git-cache-dir", "", "Directory of the local git mirrors the stage repositories are cloned from, shared by the pipelines of the host."),
+		logFormat:      fs.String("log-format", "text", "Format of the log records: text or json."),
+		logLevel:       fs.String("log-level", "info", "Minimum level of the log records: debug, info, warn or error."),
+	}
+}
+
+// load sets up the logger and loads the pipeline, applying the flag overrides.
+func (f pipelineFlags) load(fs *pflag.FlagSet) executor.Pipeline {
+	logger, err := newLogger(*f.logFormat, *f.logLevel)
+	if err != nil {
+		fatal("Invalid log flags", "error", err)
+	}
+	slog.SetDefault(logger)
 
 	defaultEnv := make(map[string]string)
-	for _, e := range *defaultEnvFlag {
+	for _, e := range *f.defaultEnv {
 		env := strings.Split(e, ":")
 		if len(env) != 2 {
-			log.Fatalf("Invalid env var spec: %s", e)
+			fatal("Invalid env var spec", "env", e)
 		}
 		defaultEnv[env[0]] = env[1]
 	}
 
-	if *input == "" {
-		log.Fatal("Path to the input file not found. Forgot --in?")
+	input := *f.input
+	if input == "" && fs.NArg() > 0 {
+		input = fs.Arg(0)
 	}
-
-	in, err := ioutil.ReadFile(*input)
-	if err != nil {
-		log.Fatalf("Erro lendo dados da entrada padrão: %q", err)
+	if input == "" {
+		fatal("Path to the input file not found. Forgot --in?")
 	}
-
-	var p executor.Pipeline
-	if err := json.Unmarshal(in, &p); err != nil {
-		log.Fatalf("Erro convertendo pipeline da entrada padrão: %q\n\"%s\"", err, string(in))
+	p, err := executor.LoadPipeline(input)
+	if err != nil {
+		fatal("Error loading pipeline", "error", err)
 	}
+	p.Logger = logger
 
 	p.DefaultRunEnv = mergeMaps(p.DefaultRunEnv, defaultEnv) // merging maps.
-	log.Printf("Pipeline: %+v\n\n", p)
+	logger.Debug("Pipeline loaded", "pipeline", p.Name, "definition", fmt.Sprintf("%+v", p))
 
 	// the flag replaces the pipeline description. Useful at runtime.
-	if *volumeName != "" {
-		p.VolumeName = *volumeName
+	if *f.volumeName != "" {
+		p.VolumeName = *f.volumeName
+	}
+	if *f.volumeDir != "" {
+		p.VolumeDir = *f.volumeDir
+	}
+	if *f.defaultBaseDir != "" {
+		p.DefaultBaseDir = *f.defaultBaseDir
+	}
+	if *f.gitCacheDir != "" {
+		p.GitCacheDir = *f.gitCacheDir
+	}
+	return p
+}
+
+func main() {
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		// Invocations without command predate the commands.
+		runCmd(os.Args[1:], true)
+		return
 	}
-	if p.VolumeName == "" {
-		log.Printf("Você não setou o campo volume-name, usando \"dadosjusbr\"")
-		p.VolumeName = "dadosjusbr"
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "run":
+		runCmd(args, false)
+	case "validate":
+		validateCmd(args)
+	case "plan":
+		planCmd(args)
+	case "graph":
+		graphCmd(args)
+	case "prune-cache":
+		pruneCacheCmd(args)
+	case "version":
+		fmt.Println(executorVersion())
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+// runCmd executes the pipeline. The legacy invocation, without command, uses
+// the dadosjusbr volume if the pipeline does not set one.
+func runCmd(args []string, legacy bool) {
+	fs := pflag.NewFlagSet("run", pflag.ExitOnError)
+	pf := newPipelineFlags(fs)
+	runtimeFlag := fs.String("runtime", "cli", "Container runtime: cli (docker command-line client) or engine (Docker Engine API through its Unix socket).")
+	checkpointDir := fs.String("checkpoint-dir", "", "Directory in which the execution state is saved after each successful stage.")
+	resume := fs.String("resume", "", "Checkpoint directory of a failed execution to resume from. Also used as checkpoint dir if --checkpoint-dir is not set.")
+	forceResume := fs.Bool("force-resume", false, "Resume even if the pipeline definition or the commit of a completed stage has changed.")
+	maxOutputSize := fs.Int64("max-output-size", 0, "Maximum number of bytes of each stage stdout and stderr kept in memory. Larger outputs are written to files in --output-dir.")
+	outputDir := fs.String("output-dir", "", "Directory of the files containing the outputs larger than --max-output-size.")
+	logDir := fs.String("log-dir", "", "Directory in which the stdout and stderr of each stage are written while it is executed.")
+	stream := fs.Bool("stream", true, "Print the stdout and stderr of the stages, prefixed by the stage name, while they are executed.")
+	dryRun := fs.Bool("dry-run", false, "Print the resolved execution plan without executing anything. Same as the plan command.")
+	planFormat := fs.String("plan-format", "text", "Format of the plan printed by --dry-run: text or json.")
+	resultOut := fs.String("result-out", "", "File in which the pipeline result is written, or - for the standard output.")
+	resultFormat := fs.String("result-format", "", "Format of the result written to --result-out: json, prototext or proto (binary PipelineExecution). Defaults to the one matching the file extension, or json.")
+	fs.Parse(args)
+
+	p := pf.load(fs)
+	if legacy {
+		if p.VolumeName == "" {
+			slog.Info("volume-name not set, using default", "volume-name", "dadosjusbr")
+			p.VolumeName = "dadosjusbr"
+		}
+		if p.VolumeDir == "" {
+			slog.Info("volume-dir not set, using default", "volume-dir", "/output")
+			p.VolumeDir = "/output"
+		}
 	}
 
-	if *volumeDir != "" {
-		p.VolumeDir = *volumeDir
+	if *maxOutputSize > 0 {
+		p.MaxOutputSize = *maxOutputSize
 	}
-	if p.VolumeDir == "" {
-		log.Printf("Você não setou o campo volume-name, usando \"dadosjusbr\"")
-		p.VolumeDir = "/output"
+	if *outputDir != "" {
+		p.OutputDir = *outputDir
+	}
+	if *logDir != "" {
+		p.LogDir = *logDir
+	}
+	if *stream {
+		p.OutputSinks = append(p.OutputSinks, executor.ConsoleSink{Writer: os.Stderr})
 	}
 
-	if *defaultBaseDir != "" {
-		p.DefaultBaseDir = *defaultBaseDir
+	if *checkpointDir != "" {
+		p.CheckpointDir = *checkpointDir
+	}
+	var checkpoint *executor.Checkpoint
+	if *resume != "" {
+		var err error
+		checkpoint, err = executor.LoadCheckpoint(*resume)
+		if err != nil {
+			fatal("Error loading checkpoint", "dir", *resume, "error", err)
+		}
+		if p.CheckpointDir == "" {
+			p.CheckpointDir = *resume
+		}
 	}
 
-	log.Printf("Executando pipeline %s", p.Name)
-	result := p.Run()
-	if result.Status != status.OK {
-		log.Printf("Erro executando pipeline: %s. Imprimindo resultado:\n\n", p.Name)
-		log.Printf("%+v", result)
+	switch *runtimeFlag {
+	case "cli":
+		p.Runtime = executor.DockerCLI{}
+	case "engine":
+		p.Runtime = executor.DockerEngine{}
+	default:
+		fatal("Invalid runtime", "runtime", *runtimeFlag)
+	}
+
+	if *dryRun {
+		printPlan(p, *planFormat)
 		return
 	}
-	log.Printf("Pipeline %s executado com sucesso! Imprimindo resultado:\n\n", p.Name)
-	fmt.Printf("%+v", result)
+
+	// Interrupting the command stops the running stage and its container.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var result executor.PipelineResult
+	if checkpoint != nil {
+		slog.Info("Resuming pipeline from checkpoint", "pipeline", p.Name, "dir", *resume)
+		result = p.ResumeContext(ctx, checkpoint, *forceResume)
+	} else {
+		slog.Info("Running pipeline", "pipeline", p.Name)
+		result = p.RunContext(ctx)
+	}
+	if *resultOut != "" {
+		if err := writeResult(p, result, *resultOut, *resultFormat); err != nil {
+			slog.Error("Error writing pipeline result", "out", *resultOut, "error", err)
+			os.Exit(int(status.SystemError))
+		}
+	}
+	// The exit code is the pipeline status, so that scripts can handle the
+	// failures.
+	if result.Status != status.OK {
+		status.ExitFromError(status.NewError(result.Status, fmt.Errorf("pipeline %s failed: %s", p.Name, statusText(result.Status))))
+	}
+	slog.Info("Pipeline executed successfully", "pipeline", p.Name)
+}
+
+// writeResult writes the pipeline result to the file, or to the standard
+// output if out is "-". The format, if empty, is inferred from the file
+// extension.
+func writeResult(p executor.Pipeline, result executor.PipelineResult, out, format string) error {
+	if format == "" {
+		switch filepath.Ext(out) {
+		case ".txtpb", ".textproto", ".prototext":
+			format = "prototext"
+		case ".pb", ".binpb":
+			format = "proto"
+		default:
+			format = "json"
+		}
+	}
+	var b []byte
+	var err error
+	switch format {
+	case "json":
+		b, err = json.MarshalIndent(result, "", "  ")
+		b = append(b, '\n')
+	case "prototext":
+		b, err = prototext.MarshalOptions{Multiline: true}.Marshal(executor.NewPipelineExecution(p, result))
+	case "proto":
+		b, err = proto.Marshal(executor.NewPipelineExecution(p, result))
+	default:
+		return fmt.Errorf("invalid result format: %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("error marshaling result to %s: %w", format, err)
+	}
+	if out == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(out, b, 0644)
+}
+
+// statusText returns the name of the code, or its number if it is unknown.
+func statusText(c status.Code) string {
+	if t := status.Text(c); t != "" {
+		return t
+	}
+	return strconv.Itoa(int(c))
+}
+
+// validateCmd checks the pipeline spec, printing every problem found. It exits
+// with a non-zero code if the spec is invalid.
+func validateCmd(args []string) {
+	fs := pflag.NewFlagSet("validate", pflag.ExitOnError)
+	pf := newPipelineFlags(fs)
+	fs.Parse(args)
+
+	p := pf.load(fs)
+	err := p.Validate()
+	var errs executor.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, e)
+		}
+		os.Exit(int(status.InvalidParameters))
+	case err != nil:
+		fatal("Error validating pipeline", "error", err)
+	}
+	fmt.Printf("Pipeline %s is valid\n", p.Name)
+}
+
+// planCmd prints the resolved execution plan.
+func planCmd(args []string) {
+	fs := pflag.NewFlagSet("plan", pflag.ExitOnError)
+	pf := newPipelineFlags(fs)
+	format := fs.String("format", "text", "Format of the plan: text or json.")
+	fs.Parse(args)
+
+	printPlan(pf.load(fs), *format)
+}
+
+// graphCmd prints the stage dependencies.
+func graphCmd(args []string) {
+	fs := pflag.NewFlagSet("graph", pflag.ExitOnError)
+	pf := newPipelineFlags(fs)
+	format := fs.String("format", executor.GraphDOT, "Format of the graph: dot or mermaid.")
+	fs.Parse(args)
+
+	p := pf.load(fs)
+	g, err := p.Graph(*format)
+	if err != nil {
+		fatal("Error building stage graph", "pipeline", p.Name, "error", err)
+	}
+	fmt.Print(g)
+}
+
+// pruneCacheCmd removes the mirrors of the git cache dir not used recently.
+func pruneCacheCmd(args []string) {
+	fs := pflag.NewFlagSet("prune-cache", pflag.ExitOnError)
+	dir := fs.String("git-cache-dir", "", "Directory of the local git mirrors.")
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "Remove the mirrors not used for longer than this duration.")
+	fs.Parse(args)
+
+	if *dir == "" {
+		fatal("Path to the git cache dir not found. Forgot --git-cache-dir?")
+	}
+	removed, err := executor.PruneGitCache(context.Background(), *dir, *olderThan)
+	for _, m := range removed {
+		fmt.Println(m)
+	}
+	if err != nil {
+		slog.Error("Error pruning git cache", "dir", *dir, "error", err)
+		os.Exit(int(status.SystemError))
+	}
+}
+
+func printPlan(p executor.Pipeline, format string) {
+	plan, err := p.Plan()
+	if err != nil {
+		fatal("Error planning pipeline", "pipeline", p.Name, "error", err)
+	}
+	switch format {
+	case "text":
+		fmt.Print(plan)
+	case "json":
+		b, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			fatal("Error marshaling plan to JSON", "error", err)
+		}
+		fmt.Println(string(b))
+	default:
+		fatal("Invalid plan format", "format", format)
+	}
+}
+
+// executorVersion returns the version set at build time or, if not set, the
+// one recorded in the build info.
+func executorVersion() string {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return "(devel)"
+}
+
+// newLogger returns the logger writing records in the format to stderr.
+func newLogger(format, level string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", format)
+	}
+}
+
+// fatal logs the error and exits. Failures before executing the pipeline are
+// caused by the flags or the pipeline spec.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(int(status.InvalidParameters))
 }
 
 // mergeMaps adds all elements of sec to first.
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dadosjusbr/executor"
+	"github.com/dadosjusbr/executor/status"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestMain runs the command instead of the tests when the test binary is
+// executed by runExecutor.
+func TestMain(m *testing.M) {
+	if os.Getenv("EXECUTOR_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runExecutor executes the command with the arguments and returns its stdout.
+// The docker client is replaced by a script that succeeds without doing
+// anything.
+func runExecutor(t *testing.T, args ...string) []byte {
+	t.Helper()
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncase \"$1\" in\n  image) echo sha256:fake ;;\n  run) cat >/dev/null ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "EXECUTOR_TEST_MAIN=1", "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("want no error executing %q, got %v: %s", args, err, stderr.String())
+	}
+	return stdout.Bytes()
+}
+
+// newStageRepo returns the URL of a local repository with a stage.
+func newStageRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM alpine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add("Dockerfile"); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "dadosjusbr", Email: "dadosjusbr@gmail.com", When: time.Now()}
+	if _, err := w.Commit("stage", &git.CommitOptions{Author: sig}); err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + dir
+}
+
+func TestRun_ResultOutStdout(t *testing.T) {
+	spec, err := json.Marshal(map[string]any{
+		"name":   "test",
+		"stages": []map[string]any{{"name": "coleta", "repo": newStageRepo(t)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := filepath.Join(t.TempDir(), "pipeline.json")
+	if err := os.WriteFile(in, spec, 0644); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		format string
+		decode func([]byte) (status.Code, error)
+	}{
+		{"json", func(b []byte) (status.Code, error) {
+			var r executor.PipelineResult
+			err := json.Unmarshal(b, &r)
+			return r.Status, err
+		}},
+		{"proto", func(b []byte) (status.Code, error) {
+			var e executor.PipelineExecution
+			err := proto.Unmarshal(b, &e)
+			return status.Code(e.GetStatus()), err
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			out := runExecutor(t, "run", "--def-base-dir", t.TempDir(), "--result-out", "-", "--result-format", tc.format, in)
+			code, err := tc.decode(out)
+			if err != nil {
+				t.Fatalf("want result in stdout, got %v: %q", err, out)
+			}
+			if code != status.OK {
+				t.Errorf("want status OK, got %v", code)
+			}
+		})
+	}
+}
+
+func TestExamples(t *testing.T) {
+	examples, err := filepath.Glob("*.json")
+	if err != nil || len(examples) == 0 {
+		t.Fatalf("want examples, got %v (%v)", examples, err)
+	}
+	for _, e := range examples {
+		t.Run(e, func(t *testing.T) {
+			p, err := executor.LoadPipeline(e)
+			if err != nil {
+				t.Fatalf("want no error loading example, got %q", err)
+			}
+			if err := p.Validate(); err != nil {
+				t.Errorf("want valid example, got %q", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// stageGraph is the dependency graph of the pipeline stages, indexed by the
+// stage position in Pipeline.Stages.
+//
+// If no stage declares dependencies, the stages form a chain in the order
+// they are defined, each one depending on the previous. Otherwise, stages
+// without dependencies are roots of the graph and receive the pipeline
+// input as stdin.
+type stageGraph struct {
+	parents  [][]int // Dependencies of each stage, in the order they were declared.
+	children [][]int // Stages depending on each stage, in ascending order.
+}
+
+// newStageGraph builds and validates the dependency graph of the stages.
+func newStageGraph(stages []Stage) (stageGraph, error) {
+	g := stageGraph{
+		parents:  make([][]int, len(stages)),
+		children: make([][]int, len(stages)),
+	}
+	explicit := false
+	for _, s := range stages {
+		if len(s.DependsOn) > 0 {
+			explicit = true
+		}
+	}
+	// Stages are referenced by name only when dependencies are explicit.
+	byName := make(map[string]int)
+	for i, s := range stages {
+		if _, ok := byName[s.Name]; ok && explicit {
+			return g, fmt.Errorf("duplicate stage name %q", s.Name)
+		}
+		byName[s.Name] = i
+	}
+	for i, s := range stages {
+		switch {
+		case !explicit && i > 0:
+			g.parents[i] = []int{i - 1}
+		case explicit:
+			for _, d := range s.DependsOn {
+				p, ok := byName[d]
+				if !ok {
+					return g, fmt.Errorf("stage %q depends on unknown stage %q", s.Name, d)
+				}
+				if p == i {
+					return g, fmt.Errorf("stage %q depends on itself", s.Name)
+				}
+				g.parents[i] = append(g.parents[i], p)
+			}
+		}
+		for _, p := range g.parents[i] {
+			g.children[p] = append(g.children[p], i)
+		}
+	}
+	if cycle := g.cycle(); cycle != nil {
+		var names []string
+		for _, i := range cycle {
+			names = append(names, stages[i].Name)
+		}
+		return g, fmt.Errorf("stage dependencies have a cycle: %s", strings.Join(names, " -> "))
+	}
+	return g, nil
+}
+
+// cycle returns the stages forming a dependency cycle, if any.
+func (g stageGraph) cycle() []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.parents))
+	var path []int
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		state[i] = visiting
+		path = append(path, i)
+		for _, c := range g.children[i] {
+			switch state[c] {
+			case visiting:
+				for j, p := range path {
+					if p == c {
+						return append(append([]int{}, path[j:]...), c)
+					}
+				}
+			case unvisited:
+				if cycle := visit(c); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range g.parents {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// stageOutput is an element of the stdin of stages with more than one
+// dependency: a JSON array with the stdout of each dependency, in the order
+// they are declared in DependsOn.
+type stageOutput struct {
+	Stage  string `json:"stage"`
+	Stdout string `json:"stdout"`
+}
+
+// stageInput is the standard input of a stage: the data in memory or, if the
+// stdout it comes from exceeded the in-memory limit, the file containing it.
+type stageInput struct {
+	data string      // Whole input, or its beginning if file is set.
+	file *OutputFile // File containing the whole input, if any.
+}
+
+// runOutput returns the stdout of a run as input of other stages.
+func runOutput(r CmdResult) stageInput {
+	return stageInput{data: r.Stdout, file: r.StdoutFile}
+}
+
+// content returns the whole input, reading the file if needed.
+func (in stageInput) content() (string, error) {
+	if in.file == nil {
+		return in.data, nil
+	}
+	b, err := os.ReadFile(in.file.Path)
+	if err != nil {
+		return "", fmt.Errorf("error reading output file: %w", err)
+	}
+	return string(b), nil
+}
+
+// stdin returns the standard input of the stage i, given the pipeline input
+// and the stdout of the stages already executed. The outputs of the
+// dependencies of stages with more than one are combined in memory.
+func (g stageGraph) stdin(i int, stages []Stage, input string, stdouts map[int]stageInput) (stageInput, error) {
+	switch parents := g.parents[i]; len(parents) {
+	case 0:
+		return stageInput{data: input}, nil
+	case 1:
+		return stdouts[parents[0]], nil
+	default:
+		var out []stageOutput
+		for _, p := range parents {
+			stdout, err := stdouts[p].content()
+			if err != nil {
+				return stageInput{}, fmt.Errorf("error combining the output of dependencies of stage %s: %w", stages[i].Name, err)
+			}
+			out = append(out, stageOutput{Stage: stages[p].Name, Stdout: stdout})
+		}
+		b, err := json.Marshal(out)
+		if err != nil {
+			return stageInput{}, fmt.Errorf("error combining the output of dependencies of stage %s: %w", stages[i].Name, err)
+		}
+		return stageInput{data: string(b)}, nil
+	}
+}
+
+// insertSorted adds v to the sorted slice s.
+func insertSorted(s []int, v int) []int {
+	i := sort.SearchInts(s, v)
+	s = append(s, 0)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewStageGraph(t *testing.T) {
+	testCases := []struct {
+		name    string
+		stages  []Stage
+		parents [][]int
+		err     string
+	}{
+		{
+			name:    "linear when no dependencies are declared",
+			stages:  []Stage{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			parents: [][]int{nil, {0}, {1}},
+		},
+		{
+			name:    "explicit dependencies",
+			stages:  []Stage{{Name: "a"}, {Name: "b"}, {Name: "c", DependsOn: []string{"b", "a"}}},
+			parents: [][]int{nil, nil, {1, 0}},
+		},
+		{
+			name:   "unknown dependency",
+			stages: []Stage{{Name: "a", DependsOn: []string{"z"}}},
+			err:    `stage "a" depends on unknown stage "z"`,
+		},
+		{
+			name:   "self dependency",
+			stages: []Stage{{Name: "a", DependsOn: []string{"a"}}},
+			err:    `stage "a" depends on itself`,
+		},
+		{
+			name:   "duplicate names",
+			stages: []Stage{{Name: "a"}, {Name: "a", DependsOn: []string{"a"}}},
+			err:    `duplicate stage name "a"`,
+		},
+		{
+			name: "cycle",
+			stages: []Stage{
+				{Name: "a"},
+				{Name: "b", DependsOn: []string{"a", "d"}},
+				{Name: "c", DependsOn: []string{"b"}},
+				{Name: "d", DependsOn: []string{"c"}},
+			},
+			err: "cycle: b -> c -> d -> b",
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := newStageGraph(tt.stages)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("want error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got %v", err)
+			}
+			for i := range tt.parents {
+				if len(g.parents[i]) != len(tt.parents[i]) {
+					t.Fatalf("want parents %v of stage %d, got %v", tt.parents[i], i, g.parents[i])
+				}
+				for j := range tt.parents[i] {
+					if g.parents[i][j] != tt.parents[i][j] {
+						t.Errorf("want parents %v of stage %d, got %v", tt.parents[i], i, g.parents[i])
+					}
+				}
+			}
+		})
+	}
+}
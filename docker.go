@@ -2,35 +2,53 @@ package executor
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// buildImage executes the 'docker build' for a image, considering the
-// parameters defined for it and returns a CmdResult and an error, if any.
-func buildImage(id, baseDir, stageDir string, env map[string]string) (CmdResult, error) {
-	dir := filepath.Join(baseDir, stageDir)
-	var b strings.Builder
-	for k, v := range env {
-		fmt.Fprintf(&b, "--build-arg %s=%s ", k, fmt.Sprintf(`"%s"`, v))
+const (
+	defaultDockerBinary = "docker"
+	// Time to wait for the client process to exit after the container has
+	// been killed due to a cancellation.
+	cancelWaitDelay = 10 * time.Second
+)
+
+// DockerCLI is the default ContainerRuntime. It executes the docker
+// command-line client through bash.
+type DockerCLI struct {
+	Binary string // Name or path of the client binary, e.g. podman. Defaults to docker.
+}
+
+func (d DockerCLI) binary() string {
+	if d.Binary == "" {
+		return defaultDockerBinary
 	}
-	envStr := b.String()
+	return d.Binary
+}
 
-	cmdStr := fmt.Sprintf("docker build %s-t %s .", envStr, id)
+// Build executes the 'docker build' for a image, considering the
+// parameters defined for it and returns a CmdResult and an error, if any.
+func (d DockerCLI) Build(ctx context.Context, spec BuildSpec) (CmdResult, error) {
+	dir := spec.Dir
+	cmdStr := d.buildCmd(spec)
 	// sh -c is a workaround that allow us to have double quotes around environment variable values.
 	// Those are needed when the environment variables have whitespaces, for instance a NAME, like in
 	// TREPB.
-	cmd := exec.Command("bash", "-c", cmdStr)
+	cmd := exec.CommandContext(ctx, "bash", "-c", cmdStr)
+	cmd.WaitDelay = cancelWaitDelay
 	cmd.Dir = dir
-	var outb, errb bytes.Buffer
-	cmd.Stdout = &outb
-	cmd.Stderr = &errb
+	outb := newCapture(ctx, spec.Limit, spec.Image+"-build-stdout")
+	errb := newCapture(ctx, spec.Limit, spec.Image+"-build-stderr")
+	cmd.Stdout = withSink(outb, spec.Stdout)
+	cmd.Stderr = withSink(errb, spec.Stderr)
 
-	log.Printf("$ %s", cmdStr)
+	loggerFrom(ctx).Info("executing command", cmdKey, cmdStr)
 	err := cmd.Run()
 	switch err.(type) {
 	case *exec.Error:
@@ -43,46 +61,59 @@ func buildImage(id, baseDir, stageDir string, env map[string]string) (CmdResult,
 
 	cmdResult := CmdResult{
 		Stdout:     outb.String(),
+		StdoutFile: outb.File(),
 		Stderr:     errb.String(),
+		StderrFile: errb.File(),
 		Cmd:        cmdStr,
 		CmdDir:     dir,
 		ExitStatus: statusCode(err),
-		Env:        os.Environ(),
+		Env:        envList(spec.Env),
 	}
 
 	return cmdResult, err
 }
 
-// runImage executes the 'docker run' for a image, considering the
+// Run executes the 'docker run' for a image, considering the
 // parameters defined for it and returns a CmdResult and an error, if any.
 // It uses the stdout from the previous stage as the stdin for this new command.
 // Associates a volume to the running docker image if volumeName and volumeDir are not empty strings.
-func runImage(id, baseDir, stageDir, volumeName, volumeDir, stdin string, env map[string]string) (CmdResult, error) {
-	dir := filepath.Join(baseDir, stageDir)
-	var builder strings.Builder
-	for key, value := range env {
-		fmt.Fprintf(&builder, "--env %s=%s ", key, fmt.Sprintf(`"%s"`, value))
-	}
-	envStr := strings.TrimRight(builder.String(), " ")
-
-	volumeStr := ""
-	if volumeName != "" && volumeDir != "" {
-		volumeStr = fmt.Sprintf("-v %s:%s", volumeName, volumeDir)
+// The container is removed after it exits.
+// If the context is done before the container exits, the container is forcefully removed.
+func (d DockerCLI) Run(ctx context.Context, spec RunSpec) (CmdResult, error) {
+	dir := spec.Dir
+	name := spec.Name
+	if name == "" {
+		name = containerName("executor")
 	}
-
-	cmdStr := fmt.Sprintf("docker run -i %s --rm %s %s", volumeStr, envStr, id)
+	stdin := spec.Stdin
+	cmdStr := d.runCmd(spec, name)
 	// sh -c is a workaround that allow us to have double quotes around environment variable values.
 	// Those are needed when the environment variables have whitespaces, for instance a NAME, like in
 	// TREPB.
-	cmd := exec.Command("bash", "-c", cmdStr)
+	cmd := exec.CommandContext(ctx, "bash", "-c", cmdStr)
+	// Killing the client is not enough to stop the container.
+	cmd.Cancel = func() error {
+		d.removeContainer(ctx, name)
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = cancelWaitDelay
 	cmd.Dir = dir
-	cmd.Stdin = strings.NewReader(stdin)
-	var outb, errb bytes.Buffer
-	cmd.Stdout = &outb
-	cmd.Stderr = &errb
+	// The secrets are passed by name in the command line, so docker reads
+	// their values from its own environment.
+	cmd.Env = secretEnv(spec.Secrets)
+	in, err := spec.openStdin()
+	if err != nil {
+		return CmdResult{ExitStatus: noExitError, Cmd: cmdStr}, err
+	}
+	defer in.Close()
+	cmd.Stdin = in
+	outb := newCapture(ctx, spec.Limit, name+"-stdout")
+	errb := newCapture(ctx, spec.Limit, name+"-stderr")
+	cmd.Stdout = withSink(outb, spec.Stdout)
+	cmd.Stderr = withSink(errb, spec.Stderr)
 
-	log.Printf("$ %s", cmdStr)
-	err := cmd.Run()
+	loggerFrom(ctx).Info("executing command", cmdKey, cmdStr)
+	err = cmd.Run()
 	switch err.(type) {
 	case *exec.Error:
 		cmdResultError := CmdResult{
@@ -95,30 +126,61 @@ func runImage(id, baseDir, stageDir, volumeName, volumeDir, stdin string, env ma
 	cmdResult := CmdResult{
 		Stdin:      stdin,
 		Stdout:     outb.String(),
+		StdoutFile: outb.File(),
 		Stderr:     errb.String(),
+		StderrFile: errb.File(),
 		Cmd:        cmdStr,
 		CmdDir:     cmd.Dir,
 		ExitStatus: statusCode(err),
-		Env:        os.Environ(),
+		Env:        envList(spec.Env),
+	}
+	// Failed containers are inspected before removing them.
+	if err != nil && ctx.Err() == nil {
+		cmdResult.OOMKilled = d.oomKilled(ctx, name)
 	}
+	d.removeContainer(ctx, name)
 
 	return cmdResult, err
 }
 
-func pullImage(id, baseDir, stageDir string) (CmdResult, error) {
-	dir := filepath.Join(baseDir, stageDir)
+// oomKilled returns whether the container has been killed for exceeding its
+// memory limit. Errors are logged and reported as false.
+func (d DockerCLI) oomKilled(ctx context.Context, name string) bool {
+	cmd := exec.CommandContext(ctx, d.binary(), "container", "inspect", "--format", "{{.State.OOMKilled}}", name)
+	out, err := cmd.Output()
+	if err != nil {
+		loggerFrom(ctx).Warn("error inspecting container", "container", name, errAttr(err))
+		return false
+	}
+	return strings.TrimSpace(string(out)) == "true"
+}
 
-	cmdStr := fmt.Sprintf("docker pull %s", id)
+// removeContainer forcefully removes the container. Errors are logged, as the
+// container result is already known.
+func (d DockerCLI) removeContainer(ctx context.Context, name string) {
+	logger := loggerFrom(ctx)
+	logger.Info("executing command", cmdKey, d.removeContainerCmd(name))
+	if err := exec.Command(d.binary(), "rm", "-f", name).Run(); err != nil {
+		logger.Warn("error removing container", "container", name, errAttr(err))
+	}
+}
+
+// Pull executes the 'docker pull' for a image and returns a CmdResult and
+// an error, if any.
+func (d DockerCLI) Pull(ctx context.Context, id, dir string, limit OutputLimit) (CmdResult, error) {
+	cmdStr := d.pullCmd(id)
 	// sh -c is a workaround that allow us to have double quotes around environment variable values.
 	// Those are needed when the environment variables have whitespaces, for instance a NAME, like in
 	// TREPB.
-	cmd := exec.Command("bash", "-c", cmdStr)
+	cmd := exec.CommandContext(ctx, "bash", "-c", cmdStr)
+	cmd.WaitDelay = cancelWaitDelay
 	cmd.Dir = dir
-	var outb, errb bytes.Buffer
-	cmd.Stdout = &outb
-	cmd.Stderr = &errb
+	outb := newCapture(ctx, limit, id+"-pull-stdout")
+	errb := newCapture(ctx, limit, id+"-pull-stderr")
+	cmd.Stdout = outb
+	cmd.Stderr = errb
 
-	log.Printf("$ %s", cmdStr)
+	loggerFrom(ctx).Info("executing command", cmdKey, cmdStr)
 	err := cmd.Run()
 	switch err.(type) {
 	case *exec.Error:
@@ -131,29 +193,44 @@ func pullImage(id, baseDir, stageDir string) (CmdResult, error) {
 
 	cmdResult := CmdResult{
 		Stdout:     outb.String(),
+		StdoutFile: outb.File(),
 		Stderr:     errb.String(),
+		StderrFile: errb.File(),
 		Cmd:        cmdStr,
 		CmdDir:     dir,
 		ExitStatus: statusCode(err),
-		Env:        os.Environ(),
 	}
 
 	return cmdResult, err
 }
 
-func createVolume(dir, name string) error {
+// ImageID executes 'docker image inspect' to get the ID of a local image.
+func (d DockerCLI) ImageID(ctx context.Context, image string) (string, error) {
+	cmd := exec.CommandContext(ctx, d.binary(), "image", "inspect", "--format", "{{.Id}}", image)
+	var errb bytes.Buffer
+	cmd.Stderr = &errb
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error inspecting image %s: %q: %s", image, err, errb.String())
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CreateVolume creates a local volume bound to dir, or kept by docker if dir
+// is empty.
+func (d DockerCLI) CreateVolume(ctx context.Context, dir, name string) error {
 	baseDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("error getting working directory:%v", err)
 	}
-	cmdList := strings.Split(fmt.Sprintf("docker volume create --driver local --opt type=none --opt device=%s --opt o=bind --name=%s", dir, name), " ")
-	cmd := exec.Command(cmdList[0], cmdList[1:]...)
+	cmdList := strings.Split(d.createVolumeCmd(dir, name), " ")
+	cmd := exec.CommandContext(ctx, cmdList[0], cmdList[1:]...)
 	cmd.Dir = baseDir
 	var outb, errb bytes.Buffer
 	cmd.Stdout = &outb
 	cmd.Stderr = &errb
 
-	log.Printf("$ %s", strings.Join(cmdList, " "))
+	loggerFrom(ctx).Info("executing command", cmdKey, strings.Join(cmdList, " "))
 	switch cmd.Run().(type) {
 	case *exec.Error:
 		r := CmdResult{
@@ -165,12 +242,280 @@ func createVolume(dir, name string) error {
 	return nil
 }
 
-func removeVolume(volume string) error {
-	cmdList := strings.Split(fmt.Sprintf("docker volume rm -f %s", volume), " ")
-	cmd := exec.Command(cmdList[0], cmdList[1:]...)
-	log.Printf("$ %s", strings.Join(cmdList, " "))
+// RemoveVolume forcefully removes the volume.
+func (d DockerCLI) RemoveVolume(ctx context.Context, volume string) error {
+	cmdList := strings.Split(d.removeVolumeCmd(volume), " ")
+	cmd := exec.CommandContext(ctx, cmdList[0], cmdList[1:]...)
+	loggerFrom(ctx).Info("executing command", cmdKey, strings.Join(cmdList, " "))
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("error removing existing volume %s: %q", volume, err)
 	}
 	return nil
 }
+
+// CreateNetwork creates a user-defined bridge network.
+func (d DockerCLI) CreateNetwork(ctx context.Context, name string, internal bool) error {
+	if err := execCmd(ctx, d.createNetworkCmd(name, internal)); err != nil {
+		return fmt.Errorf("error creating network %s: %w", name, err)
+	}
+	return nil
+}
+
+// RemoveNetwork removes the network.
+func (d DockerCLI) RemoveNetwork(ctx context.Context, name string) error {
+	if err := execCmd(ctx, d.removeNetworkCmd(name)); err != nil {
+		return fmt.Errorf("error removing network %s: %w", name, err)
+	}
+	return nil
+}
+
+// StartService executes 'docker run --detach' for the service container.
+func (d DockerCLI) StartService(ctx context.Context, spec ServiceSpec) error {
+	args := d.startServiceArgs(spec)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = secretEnv(spec.Secrets)
+	var errb bytes.Buffer
+	cmd.Stderr = &errb
+	loggerFrom(ctx).Info("executing command", cmdKey, strings.Join(args, " "))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error starting container %s: %q: %s", spec.Name, err, strings.TrimSpace(errb.String()))
+	}
+	return nil
+}
+
+// ServiceIP executes 'docker container inspect' to get the IP address of the
+// container in the network.
+func (d DockerCLI) ServiceIP(ctx context.Context, name, network string) (string, error) {
+	format := fmt.Sprintf(`{{(index .NetworkSettings.Networks %q).IPAddress}}`, network)
+	cmd := exec.CommandContext(ctx, d.binary(), "container", "inspect", "--format", format, name)
+	var errb bytes.Buffer
+	cmd.Stderr = &errb
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error inspecting container %s: %q: %s", name, err, strings.TrimSpace(errb.String()))
+	}
+	ip := strings.TrimSpace(string(out))
+	if ip == "" {
+		return "", fmt.Errorf("container %s has no address in network %s", name, network)
+	}
+	return ip, nil
+}
+
+// ExecService executes 'docker exec' in the service container.
+func (d DockerCLI) ExecService(ctx context.Context, name string, cmd []string) (int, error) {
+	c := exec.CommandContext(ctx, d.binary(), append([]string{"exec", name}, cmd...)...)
+	err := c.Run()
+	if _, ok := err.(*exec.ExitError); ok {
+		return statusCode(err), nil
+	}
+	if err != nil {
+		return noExitError, fmt.Errorf("error executing command in container %s: %q", name, err)
+	}
+	return 0, nil
+}
+
+// StopService executes 'docker stop' for the service container and collects
+// its logs and exit code before removing it.
+func (d DockerCLI) StopService(ctx context.Context, name string, limit OutputLimit) (CmdResult, error) {
+	logger := loggerFrom(ctx)
+	cmdResult := CmdResult{Cmd: d.stopServiceCmd(name), ExitStatus: noExitError}
+	defer d.removeContainer(ctx, name)
+	logger.Info("executing command", cmdKey, cmdResult.Cmd)
+	if err := exec.CommandContext(ctx, d.binary(), "stop", name).Run(); err != nil {
+		return cmdResult, fmt.Errorf("error stopping container %s: %q", name, err)
+	}
+
+	outb := newCapture(ctx, limit, name+"-stdout")
+	errb := newCapture(ctx, limit, name+"-stderr")
+	cmd := exec.CommandContext(ctx, d.binary(), "logs", name)
+	cmd.Stdout = outb
+	cmd.Stderr = errb
+	logger.Info("executing command", cmdKey, d.serviceLogsCmd(name))
+	err := cmd.Run()
+	cmdResult.Stdout, cmdResult.StdoutFile = outb.String(), outb.File()
+	cmdResult.Stderr, cmdResult.StderrFile = errb.String(), errb.File()
+	if err != nil {
+		return cmdResult, fmt.Errorf("error getting logs of container %s: %q", name, err)
+	}
+
+	out, err := exec.CommandContext(ctx, d.binary(), "container", "inspect", "--format", "{{.State.ExitCode}}", name).Output()
+	if err != nil {
+		return cmdResult, fmt.Errorf("error inspecting container %s: %q", name, err)
+	}
+	if cmdResult.ExitStatus, err = strconv.Atoi(strings.TrimSpace(string(out))); err != nil {
+		cmdResult.ExitStatus = noExitError
+		return cmdResult, fmt.Errorf("error parsing exit code of container %s: %q", name, err)
+	}
+	return cmdResult, nil
+}
+
+// execCmd executes the command line, which is split at spaces instead of
+// being interpreted by a shell. The error includes the command stderr.
+func execCmd(ctx context.Context, cmdStr string) error {
+	cmdList := strings.Split(cmdStr, " ")
+	cmd := exec.CommandContext(ctx, cmdList[0], cmdList[1:]...)
+	var errb bytes.Buffer
+	cmd.Stderr = &errb
+	loggerFrom(ctx).Info("executing command", cmdKey, cmdStr)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%q: %s", err, strings.TrimSpace(errb.String()))
+	}
+	return nil
+}
+
+// buildCmd returns the bash command line that builds the image. Build args are
+// sorted by name.
+func (d DockerCLI) buildCmd(spec BuildSpec) string {
+	var b strings.Builder
+	for _, k := range sortedKeys(spec.Env) {
+		fmt.Fprintf(&b, "--build-arg %s=%s ", k, fmt.Sprintf(`"%s"`, spec.Env[k]))
+	}
+	return fmt.Sprintf("%s build %s-t %s .", d.binary(), b.String(), spec.Image)
+}
+
+// runCmd returns the bash command line that runs the container with the given
+// name. Environment variables are sorted by name. Secrets are only named, so
+// their values are taken from the environment of the command. The container
+// is not removed when it exits, so that Run can check whether it has been
+// killed for exceeding its memory limit.
+func (d DockerCLI) runCmd(spec RunSpec, name string) string {
+	args := []string{d.binary(), "run", "-i", "--name", name}
+	if spec.VolumeName != "" && spec.VolumeDir != "" {
+		args = append(args, fmt.Sprintf("-v %s:%s", spec.VolumeName, spec.VolumeDir))
+	}
+	args = append(args, mountFlags(spec.Mounts)...)
+	if spec.Network != "" {
+		args = append(args, "--network "+spec.Network)
+	}
+	args = append(args, resourceFlags(spec.Resources)...)
+	for _, key := range sortedKeys(spec.Env) {
+		args = append(args, fmt.Sprintf("--env %s=%s", key, fmt.Sprintf(`"%s"`, spec.Env[key])))
+	}
+	for _, key := range sortedKeys(spec.Secrets) {
+		args = append(args, "--env "+key)
+	}
+	return strings.Join(append(args, spec.Image), " ")
+}
+
+// secretEnv returns the environment of the docker commands receiving the
+// secrets, which is the executor environment plus the secrets, or nil, which
+// means the executor environment, if there are no secrets.
+func secretEnv(secrets map[string]string) []string {
+	if len(secrets) == 0 {
+		return nil
+	}
+	return append(os.Environ(), envList(secrets)...)
+}
+
+// mountFlags returns the docker run flags mounting the filesystems.
+func mountFlags(mounts []Mount) []string {
+	var flags []string
+	for _, m := range mounts {
+		flags = append(flags, "--mount "+m.String())
+	}
+	return flags
+}
+
+// resourceFlags returns the docker run flags limiting the container resources.
+func resourceFlags(r Resources) []string {
+	var flags []string
+	if r.Memory != 0 {
+		flags = append(flags, fmt.Sprintf("--memory %s", r.Memory))
+	}
+	if r.MemorySwap != 0 {
+		flags = append(flags, fmt.Sprintf("--memory-swap %s", r.MemorySwap))
+	}
+	if r.CPUs != 0 {
+		flags = append(flags, "--cpus "+strconv.FormatFloat(r.CPUs, 'f', -1, 64))
+	}
+	if r.CPUShares != 0 {
+		flags = append(flags, fmt.Sprintf("--cpu-shares %d", r.CPUShares))
+	}
+	if r.PidsLimit != 0 {
+		flags = append(flags, fmt.Sprintf("--pids-limit %d", r.PidsLimit))
+	}
+	for _, u := range r.Ulimits {
+		flags = append(flags, fmt.Sprintf("--ulimit %s=%d:%d", u.Name, u.Soft, u.hard()))
+	}
+	if r.TmpfsSize != 0 {
+		flags = append(flags, fmt.Sprintf("--tmpfs %s:size=%s", tmpDir, r.TmpfsSize))
+	}
+	return flags
+}
+
+// startServiceArgs returns the arguments of the command that starts the
+// service container in background. They are not interpreted by a shell.
+func (d DockerCLI) startServiceArgs(spec ServiceSpec) []string {
+	args := []string{d.binary(), "run", "--detach", "--name", spec.Name}
+	for _, m := range spec.Mounts {
+		args = append(args, "--mount", m.String())
+	}
+	args = append(args, "--network", spec.Network)
+	// Aliases are not supported by the host network.
+	if spec.Network != HostNetwork {
+		args = append(args, "--network-alias", spec.Alias)
+	}
+	for _, f := range resourceFlags(spec.Resources) {
+		args = append(args, strings.Fields(f)...)
+	}
+	for _, key := range sortedKeys(spec.Env) {
+		args = append(args, "--env", key+"="+spec.Env[key])
+	}
+	for _, key := range sortedKeys(spec.Secrets) {
+		args = append(args, "--env", key)
+	}
+	return append(append(args, spec.Image), spec.Command...)
+}
+
+func (d DockerCLI) stopServiceCmd(name string) string {
+	return fmt.Sprintf("%s stop %s", d.binary(), name)
+}
+
+func (d DockerCLI) serviceLogsCmd(name string) string {
+	return fmt.Sprintf("%s logs %s", d.binary(), name)
+}
+
+func (d DockerCLI) removeContainerCmd(name string) string {
+	return fmt.Sprintf("%s rm -f %s", d.binary(), name)
+}
+
+func (d DockerCLI) pullCmd(image string) string {
+	return fmt.Sprintf("%s pull %s", d.binary(), image)
+}
+
+func (d DockerCLI) createVolumeCmd(dir, name string) string {
+	if dir == "" {
+		return fmt.Sprintf("%s volume create --driver local --name=%s", d.binary(), name)
+	}
+	return fmt.Sprintf("%s volume create --driver local --opt type=none --opt device=%s --opt o=bind --name=%s", d.binary(), dir, name)
+}
+
+func (d DockerCLI) removeVolumeCmd(name string) string {
+	return fmt.Sprintf("%s volume rm -f %s", d.binary(), name)
+}
+
+func (d DockerCLI) createNetworkCmd(name string, internal bool) string {
+	if internal {
+		return fmt.Sprintf("%s network create --internal %s", d.binary(), name)
+	}
+	return fmt.Sprintf("%s network create %s", d.binary(), name)
+}
+
+func (d DockerCLI) removeNetworkCmd(name string) string {
+	return fmt.Sprintf("%s network rm %s", d.binary(), name)
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containerName returns an unique container name with the given prefix.
+func containerName(prefix string) string {
+	return fmt.Sprintf("%s-%s", prefix, strconv.FormatInt(time.Now().UnixNano(), 36))
+}
@@ -0,0 +1,876 @@
+package executor
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// DockerEngine is a ContainerRuntime that talks to the Docker daemon through
+// the Engine HTTP API exposed on its Unix socket, instead of shelling out to
+// the docker client. Environment variables and build arguments are sent as
+// structured data, so their values never go through a shell.
+//
+// The Cmd field of the returned CmdResult lists the API calls performed.
+// Build contexts are sent as-is: .dockerignore files are not taken into account.
+type DockerEngine struct {
+	SocketPath string // Path of the daemon Unix socket. Defaults to /var/run/docker.sock.
+	APIVersion string // API version prefix, e.g. v1.41. Empty means the daemon's default version.
+}
+
+func (d DockerEngine) socket() string {
+	if d.SocketPath == "" {
+		return defaultDockerSocket
+	}
+	return d.SocketPath
+}
+
+// engineClients holds the HTTP client of each daemon socket, so the
+// connections to the daemon are kept alive and reused across API calls.
+var engineClients sync.Map
+
+func (d DockerEngine) client() *http.Client {
+	socket := d.socket()
+	if c, ok := engineClients.Load(socket); ok {
+		return c.(*http.Client)
+	}
+	c, _ := engineClients.LoadOrStore(socket, &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     30 * time.Second,
+		},
+	})
+	return c.(*http.Client)
+}
+
+func (d DockerEngine) path(p string) string {
+	if d.APIVersion == "" {
+		return p
+	}
+	return "/" + strings.Trim(d.APIVersion, "/") + p
+}
+
+// url returns the URL of the API path p, whose segments are already escaped,
+// e.g. with url.PathEscape.
+func (d DockerEngine) url(p string, query url.Values) string {
+	u := "http://docker" + d.path(p)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// do sends a request to the Engine API. If in is not nil it is encoded as the
+// JSON body of the request. Responses with status codes other than 2xx and
+// 101 are turned into errors.
+func (d DockerEngine) do(ctx context.Context, method, p string, query url.Values, in interface{}) (*http.Response, error) {
+	var body io.Reader
+	contentType := ""
+	switch b := in.(type) {
+	case nil:
+	case io.Reader:
+		body = b
+		contentType = "application/x-tar"
+	default:
+		buf, err := json.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request body of %s %s: %w", method, p, err)
+		}
+		body = bytes.NewReader(buf)
+		contentType = "application/json"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.url(p, query), body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request %s %s: %w", method, p, err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := d.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting %s %s: %w", method, p, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, apiError(method, p, resp)
+	}
+	if resp.ContentLength >= 0 && resp.ContentLength <= maxDrainSize {
+		resp.Body = drainCloser{resp.Body}
+	}
+	return resp, nil
+}
+
+// maxDrainSize is the size of the largest response bodies drained when closed.
+const maxDrainSize = 64 << 10
+
+// drainCloser reads what is left of a short response body before closing it,
+// e.g. the newline after a JSON document, so its connection can be reused.
+// Streamed responses are not drained, as they may never end.
+type drainCloser struct {
+	io.ReadCloser
+}
+
+func (d drainCloser) Close() error {
+	io.Copy(io.Discard, d.ReadCloser)
+	return d.ReadCloser.Close()
+}
+
+func apiError(method, p string, resp *http.Response) error {
+	var msg struct {
+		Message string `json:"message"`
+	}
+	b, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(b, &msg); err != nil || msg.Message == "" {
+		msg.Message = strings.TrimSpace(string(b))
+	}
+	return fmt.Errorf("%s %s returned status %d: %s", method, p, resp.StatusCode, msg.Message)
+}
+
+// jsonMessage is an entry of the progress stream returned by the build and
+// pull endpoints.
+type jsonMessage struct {
+	Stream   string `json:"stream"`
+	Status   string `json:"status"`
+	Progress string `json:"progress"`
+	ID       string `json:"id"`
+	Error    string `json:"error"`
+}
+
+// readJSONMessages decodes the progress stream into stdout and stderr. It
+// returns 1 if the stream reported an error, mimicking the docker client.
+func readJSONMessages(r io.Reader, stdout, stderr io.Writer) (int, error) {
+	exitStatus := 0
+	dec := json.NewDecoder(r)
+	for {
+		var m jsonMessage
+		if err := dec.Decode(&m); err == io.EOF {
+			return exitStatus, nil
+		} else if err != nil {
+			return exitStatus, fmt.Errorf("error decoding engine progress stream: %w", err)
+		}
+		switch {
+		case m.Error != "":
+			exitStatus = 1
+			fmt.Fprintln(stderr, m.Error)
+		case m.Stream != "":
+			io.WriteString(stdout, m.Stream)
+		case m.Status != "":
+			if m.ID != "" {
+				fmt.Fprintf(stdout, "%s: ", m.ID)
+			}
+			fmt.Fprintln(stdout, strings.TrimSpace(m.Status+" "+m.Progress))
+		}
+	}
+}
+
+// Build sends the stage directory as build context to the daemon and builds
+// the image tagged with spec.Image.
+func (d DockerEngine) Build(ctx context.Context, spec BuildSpec) (r CmdResult, err error) {
+	q := url.Values{}
+	q.Set("t", spec.Image)
+	q.Set("rm", "1")
+	if len(spec.Env) > 0 {
+		args, err := json.Marshal(spec.Env)
+		if err != nil {
+			return CmdResult{ExitStatus: noExitError}, fmt.Errorf("error encoding build args: %w", err)
+		}
+		q.Set("buildargs", string(args))
+	}
+	r = CmdResult{
+		Cmd:       fmt.Sprintf("POST %s?t=%s", d.path("/build"), spec.Image),
+		CmdDir:    spec.Dir,
+		Env:       envList(spec.Env),
+		StartTime: time.Now(),
+	}
+	defer func() { r.FinishTime = time.Now() }()
+
+	loggerFrom(ctx).Info("executing command", cmdKey, r.Cmd)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(tarDir(spec.Dir, pw))
+	}()
+	defer pr.Close()
+	resp, err := d.do(ctx, http.MethodPost, "/build", q, pr)
+	if err != nil {
+		r.ExitStatus = noExitError
+		r.Stderr = err.Error()
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	outb := newCapture(ctx, spec.Limit, spec.Image+"-build-stdout")
+	errb := newCapture(ctx, spec.Limit, spec.Image+"-build-stderr")
+	r.ExitStatus, err = readJSONMessages(resp.Body, withSink(outb, spec.Stdout), withSink(errb, spec.Stderr))
+	r.Stdout, r.StdoutFile = outb.String(), outb.File()
+	r.Stderr, r.StderrFile = errb.String(), errb.File()
+	return r, err
+}
+
+// Pull downloads the image from its registry.
+func (d DockerEngine) Pull(ctx context.Context, image, dir string, limit OutputLimit) (r CmdResult, err error) {
+	q := url.Values{}
+	q.Set("fromImage", image)
+	r = CmdResult{
+		Cmd:       fmt.Sprintf("POST %s?fromImage=%s", d.path("/images/create"), image),
+		CmdDir:    dir,
+		StartTime: time.Now(),
+	}
+	defer func() { r.FinishTime = time.Now() }()
+
+	loggerFrom(ctx).Info("executing command", cmdKey, r.Cmd)
+	resp, err := d.do(ctx, http.MethodPost, "/images/create", q, nil)
+	if err != nil {
+		r.ExitStatus = noExitError
+		r.Stderr = err.Error()
+		return r, err
+	}
+	defer resp.Body.Close()
+
+	outb := newCapture(ctx, limit, image+"-pull-stdout")
+	errb := newCapture(ctx, limit, image+"-pull-stderr")
+	r.ExitStatus, err = readJSONMessages(resp.Body, outb, errb)
+	r.Stdout, r.StdoutFile = outb.String(), outb.File()
+	r.Stderr, r.StderrFile = errb.String(), errb.File()
+	return r, err
+}
+
+// ImageID returns the ID of a local image.
+func (d DockerEngine) ImageID(ctx context.Context, image string) (string, error) {
+	resp, err := d.do(ctx, http.MethodGet, "/images/"+url.PathEscape(image)+"/json", nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("error inspecting image %s: %w", image, err)
+	}
+	defer resp.Body.Close()
+	var inspect struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return "", fmt.Errorf("error decoding image %s: %w", image, err)
+	}
+	return inspect.ID, nil
+}
+
+// Run creates the container, attaches to its standard streams, starts it,
+// waits for it to finish and removes it. If the context is done before the
+// container exits, the container is killed.
+func (d DockerEngine) Run(ctx context.Context, spec RunSpec) (r CmdResult, err error) {
+	r = CmdResult{
+		Stdin:     spec.Stdin,
+		CmdDir:    spec.Dir,
+		Env:       envList(spec.Env),
+		StartTime: time.Now(),
+	}
+	var calls []string
+	defer func() {
+		r.Cmd = strings.Join(calls, "\n")
+		r.FinishTime = time.Now()
+	}()
+	fail := func(err error) (CmdResult, error) {
+		r.ExitStatus = noExitError
+		r.Stderr += err.Error()
+		return r, err
+	}
+
+	in, err := spec.openStdin()
+	if err != nil {
+		return fail(err)
+	}
+	defer in.Close()
+
+	// The secrets are only sent in the request body, not in the result.
+	config := containerConfig{
+		Image:        spec.Image,
+		Env:          append(envList(spec.Env), envList(spec.Secrets)...),
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		OpenStdin:    true,
+		StdinOnce:    true,
+	}
+	if spec.VolumeName != "" && spec.VolumeDir != "" {
+		config.HostConfig.Binds = []string{fmt.Sprintf("%s:%s", spec.VolumeName, spec.VolumeDir)}
+	}
+	for _, m := range spec.Mounts {
+		config.HostConfig.Mounts = append(config.HostConfig.Mounts, newEngineMount(m))
+	}
+	config.HostConfig.NetworkMode = spec.Network
+	config.HostConfig.setResources(spec.Resources)
+	calls = append(calls, fmt.Sprintf("POST %s (image %s)", d.path("/containers/create"), spec.Image))
+	loggerFrom(ctx).Info("executing command", cmdKey, calls[len(calls)-1])
+	q := url.Values{}
+	if spec.Name != "" {
+		q.Set("name", spec.Name)
+	}
+	resp, err := d.do(ctx, http.MethodPost, "/containers/create", q, config)
+	if err != nil {
+		return fail(err)
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if err != nil {
+		return fail(fmt.Errorf("error decoding created container: %w", err))
+	}
+	containerPath := "/containers/" + url.PathEscape(created.ID)
+	// Cleaning up must happen even if the context is done.
+	cleanupCtx := context.WithoutCancel(ctx)
+	defer func() {
+		calls = append(calls, fmt.Sprintf("DELETE %s", d.path(containerPath)))
+		q := url.Values{}
+		q.Set("force", "1")
+		if resp, err := d.do(cleanupCtx, http.MethodDelete, containerPath, q, nil); err != nil {
+			loggerFrom(ctx).Warn("error removing container", "container", created.ID, errAttr(err))
+		} else {
+			resp.Body.Close()
+		}
+	}()
+
+	calls = append(calls, fmt.Sprintf("POST %s", d.path(containerPath+"/attach")))
+	conn, stream, err := d.attach(ctx, containerPath)
+	if err != nil {
+		return fail(err)
+	}
+	defer conn.Close()
+
+	calls = append(calls, fmt.Sprintf("POST %s", d.path(containerPath+"/start")))
+	resp, err = d.do(ctx, http.MethodPost, containerPath+"/start", nil, nil)
+	if err != nil {
+		return fail(err)
+	}
+	resp.Body.Close()
+
+	// Killing the container closes the attached streams, unblocking demux.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			loggerFrom(ctx).Info("executing command", cmdKey, "POST "+d.path(containerPath+"/kill"))
+			if resp, err := d.do(cleanupCtx, http.MethodPost, containerPath+"/kill", nil, nil); err != nil {
+				loggerFrom(ctx).Warn("error killing container", "container", created.ID, errAttr(err))
+			} else {
+				resp.Body.Close()
+			}
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	go func() {
+		io.Copy(conn, in)
+		if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+			cw.CloseWrite()
+		}
+	}()
+	outb := newCapture(ctx, spec.Limit, spec.Name+"-stdout")
+	errb := newCapture(ctx, spec.Limit, spec.Name+"-stderr")
+	err = demux(stream, withSink(outb, spec.Stdout), withSink(errb, spec.Stderr))
+	r.Stdout, r.StdoutFile = outb.String(), outb.File()
+	r.Stderr, r.StderrFile = errb.String(), errb.File()
+	if ctx.Err() != nil {
+		return fail(fmt.Errorf("container %s killed: %w", created.ID, ctx.Err()))
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	calls = append(calls, fmt.Sprintf("POST %s", d.path(containerPath+"/wait")))
+	resp, err = d.do(ctx, http.MethodPost, containerPath+"/wait", nil, nil)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	var waited struct {
+		StatusCode int
+		Error      *struct{ Message string }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&waited); err != nil {
+		return fail(fmt.Errorf("error decoding container exit status: %w", err))
+	}
+	if waited.Error != nil && waited.Error.Message != "" {
+		return fail(fmt.Errorf("error waiting for container %s: %s", created.ID, waited.Error.Message))
+	}
+	r.ExitStatus = waited.StatusCode
+	if r.ExitStatus != 0 {
+		calls = append(calls, fmt.Sprintf("GET %s", d.path(containerPath+"/json")))
+		// The exit status is already known, so inspection errors only
+		// prevent telling whether the container ran out of memory.
+		if r.OOMKilled, err = d.oomKilled(ctx, containerPath); err != nil {
+			loggerFrom(ctx).Warn("error inspecting container", "container", created.ID, errAttr(err))
+		}
+	}
+	return r, nil
+}
+
+// oomKilled returns whether the container has been killed for exceeding its
+// memory limit.
+func (d DockerEngine) oomKilled(ctx context.Context, containerPath string) (bool, error) {
+	resp, err := d.do(ctx, http.MethodGet, containerPath+"/json", nil, nil)
+	if err != nil {
+		return false, fmt.Errorf("error inspecting container: %w", err)
+	}
+	defer resp.Body.Close()
+	var inspect struct {
+		State struct{ OOMKilled bool }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return false, fmt.Errorf("error decoding container state: %w", err)
+	}
+	return inspect.State.OOMKilled, nil
+}
+
+// containerConfig is the body of the container creation request.
+type containerConfig struct {
+	Image        string
+	Env          []string
+	AttachStdin  bool
+	AttachStdout bool
+	AttachStderr bool
+	OpenStdin    bool
+	StdinOnce    bool
+	Cmd          []string `json:",omitempty"`
+	HostConfig   hostConfig
+	// Only set for services, which are reached by an alias in the network.
+	NetworkingConfig *networkingConfig `json:",omitempty"`
+}
+
+// networkingConfig configures the endpoints of the container in each network.
+type networkingConfig struct {
+	EndpointsConfig map[string]endpointConfig
+}
+
+type endpointConfig struct {
+	Aliases []string `json:",omitempty"`
+}
+
+// hostConfig is the part of the container configuration that depends on the
+// host, like mounts and resource limits.
+type hostConfig struct {
+	Binds       []string          `json:",omitempty"`
+	Mounts      []engineMount     `json:",omitempty"`
+	NetworkMode string            `json:",omitempty"`
+	Memory      int64             `json:",omitempty"`
+	MemorySwap  int64             `json:",omitempty"`
+	NanoCpus    int64             `json:",omitempty"`
+	CpuShares   int64             `json:",omitempty"`
+	PidsLimit   int64             `json:",omitempty"`
+	Ulimits     []engineUlimit    `json:",omitempty"`
+	Tmpfs       map[string]string `json:",omitempty"`
+}
+
+type engineUlimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+type engineMount struct {
+	Type         MountType
+	Source       string `json:",omitempty"`
+	Target       string
+	ReadOnly     bool `json:",omitempty"`
+	TmpfsOptions *struct {
+		SizeBytes int64
+	} `json:",omitempty"`
+}
+
+func newEngineMount(m Mount) engineMount {
+	em := engineMount{Type: m.Type, Source: m.Source, Target: m.Target, ReadOnly: m.ReadOnly}
+	if m.Type == TmpfsMount && m.Size != 0 {
+		em.TmpfsOptions = &struct{ SizeBytes int64 }{int64(m.Size)}
+	}
+	return em
+}
+
+// setResources sets the limits of the container resources.
+func (h *hostConfig) setResources(r Resources) {
+	h.Memory = int64(r.Memory)
+	h.MemorySwap = int64(r.MemorySwap)
+	h.NanoCpus = int64(r.CPUs * 1e9)
+	h.CpuShares = r.CPUShares
+	h.PidsLimit = r.PidsLimit
+	for _, u := range r.Ulimits {
+		h.Ulimits = append(h.Ulimits, engineUlimit{Name: u.Name, Soft: u.Soft, Hard: u.hard()})
+	}
+	if r.TmpfsSize != 0 {
+		h.Tmpfs = map[string]string{tmpDir: fmt.Sprintf("size=%d", r.TmpfsSize)}
+	}
+}
+
+// attach hijacks a connection to the container standard streams. The
+// returned reader must be used to read the container output, since it may
+// hold data already buffered from the connection.
+func (d DockerEngine) attach(ctx context.Context, containerPath string) (net.Conn, io.Reader, error) {
+	q := url.Values{}
+	for _, k := range []string{"stream", "stdin", "stdout", "stderr"} {
+		q.Set(k, "1")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url(containerPath+"/attach", q), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating attach request: %w", err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", d.socket())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error connecting to %s: %w", d.socket(), err)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("error attaching to container: %w", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("error attaching to container: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		defer conn.Close()
+		return nil, nil, apiError(http.MethodPost, containerPath+"/attach", resp)
+	}
+	return conn, br, nil
+}
+
+// demux splits the multiplexed attach stream into stdout and stderr. Each
+// frame has an 8 bytes header: the stream type, three empty bytes and the
+// big endian size of the payload.
+func demux(r io.Reader, stdout, stderr io.Writer) error {
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading container output: %w", err)
+		}
+		var w io.Writer
+		switch header[0] {
+		case 0, 1:
+			w = stdout
+		case 2:
+			w = stderr
+		default:
+			return fmt.Errorf("error reading container output: unknown stream %d", header[0])
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return fmt.Errorf("error reading container output: %w", err)
+		}
+	}
+}
+
+// CreateVolume creates a local volume bound to dir, or kept by docker if dir
+// is empty.
+func (d DockerEngine) CreateVolume(ctx context.Context, dir, name string) error {
+	body := map[string]interface{}{
+		"Name":   name,
+		"Driver": "local",
+	}
+	if dir != "" {
+		body["DriverOpts"] = map[string]string{
+			"type":   "none",
+			"device": dir,
+			"o":      "bind",
+		}
+	}
+	loggerFrom(ctx).Info("executing command", cmdKey, fmt.Sprintf("POST %s (volume %s)", d.path("/volumes/create"), name))
+	resp, err := d.do(ctx, http.MethodPost, "/volumes/create", nil, body)
+	if err != nil {
+		return fmt.Errorf("error creating volume %s: %w", name, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// RemoveVolume forcefully removes the volume.
+func (d DockerEngine) RemoveVolume(ctx context.Context, name string) error {
+	q := url.Values{}
+	q.Set("force", "1")
+	loggerFrom(ctx).Info("executing command", cmdKey, "DELETE "+d.path("/volumes/"+url.PathEscape(name)))
+	resp, err := d.do(ctx, http.MethodDelete, "/volumes/"+url.PathEscape(name), q, nil)
+	if err != nil {
+		return fmt.Errorf("error removing existing volume %s: %w", name, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// CreateNetwork creates a user-defined bridge network.
+func (d DockerEngine) CreateNetwork(ctx context.Context, name string, internal bool) error {
+	body := map[string]interface{}{
+		"Name":     name,
+		"Driver":   "bridge",
+		"Internal": internal,
+	}
+	loggerFrom(ctx).Info("executing command", cmdKey, fmt.Sprintf("POST %s (network %s)", d.path("/networks/create"), name))
+	resp, err := d.do(ctx, http.MethodPost, "/networks/create", nil, body)
+	if err != nil {
+		return fmt.Errorf("error creating network %s: %w", name, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// RemoveNetwork removes the network.
+func (d DockerEngine) RemoveNetwork(ctx context.Context, name string) error {
+	loggerFrom(ctx).Info("executing command", cmdKey, "DELETE "+d.path("/networks/"+url.PathEscape(name)))
+	resp, err := d.do(ctx, http.MethodDelete, "/networks/"+url.PathEscape(name), nil, nil)
+	if err != nil {
+		return fmt.Errorf("error removing network %s: %w", name, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// StartService creates and starts the service container, without attaching
+// to its streams.
+func (d DockerEngine) StartService(ctx context.Context, spec ServiceSpec) error {
+	config := containerConfig{
+		Image: spec.Image,
+		Env:   append(envList(spec.Env), envList(spec.Secrets)...),
+		Cmd:   spec.Command,
+	}
+	for _, m := range spec.Mounts {
+		config.HostConfig.Mounts = append(config.HostConfig.Mounts, newEngineMount(m))
+	}
+	config.HostConfig.NetworkMode = spec.Network
+	config.HostConfig.setResources(spec.Resources)
+	// Aliases are not supported by the host network.
+	if spec.Network != HostNetwork {
+		config.NetworkingConfig = &networkingConfig{
+			EndpointsConfig: map[string]endpointConfig{spec.Network: {Aliases: []string{spec.Alias}}},
+		}
+	}
+	q := url.Values{}
+	q.Set("name", spec.Name)
+	loggerFrom(ctx).Info("executing command", cmdKey, fmt.Sprintf("POST %s (image %s)", d.path("/containers/create"), spec.Image))
+	resp, err := d.do(ctx, http.MethodPost, "/containers/create", q, config)
+	if err != nil {
+		return fmt.Errorf("error creating container %s: %w", spec.Name, err)
+	}
+	resp.Body.Close()
+	containerPath := "/containers/" + url.PathEscape(spec.Name)
+	loggerFrom(ctx).Info("executing command", cmdKey, "POST "+d.path(containerPath+"/start"))
+	resp, err = d.do(ctx, http.MethodPost, containerPath+"/start", nil, nil)
+	if err != nil {
+		return fmt.Errorf("error starting container %s: %w", spec.Name, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ServiceIP inspects the container to get its IP address in the network.
+func (d DockerEngine) ServiceIP(ctx context.Context, name, network string) (string, error) {
+	resp, err := d.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/json", nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("error inspecting container %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	var inspect struct {
+		NetworkSettings struct {
+			Networks map[string]struct{ IPAddress string }
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return "", fmt.Errorf("error decoding container %s: %w", name, err)
+	}
+	ip := inspect.NetworkSettings.Networks[network].IPAddress
+	if ip == "" {
+		return "", fmt.Errorf("container %s has no address in network %s", name, network)
+	}
+	return ip, nil
+}
+
+// execPollInterval is the wait between checks of whether a command executed
+// in a service container has finished.
+const execPollInterval = 100 * time.Millisecond
+
+// ExecService creates an exec instance in the service container, starts it
+// detached and polls it until the command finishes.
+func (d DockerEngine) ExecService(ctx context.Context, name string, cmd []string) (int, error) {
+	resp, err := d.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/exec", nil, map[string]interface{}{"Cmd": cmd})
+	if err != nil {
+		return noExitError, fmt.Errorf("error creating exec in container %s: %w", name, err)
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if err != nil {
+		return noExitError, fmt.Errorf("error decoding created exec: %w", err)
+	}
+	execPath := "/exec/" + url.PathEscape(created.ID)
+	resp, err = d.do(ctx, http.MethodPost, execPath+"/start", nil, map[string]interface{}{"Detach": true})
+	if err != nil {
+		return noExitError, fmt.Errorf("error starting exec in container %s: %w", name, err)
+	}
+	resp.Body.Close()
+	for {
+		resp, err := d.do(ctx, http.MethodGet, execPath+"/json", nil, nil)
+		if err != nil {
+			return noExitError, fmt.Errorf("error inspecting exec in container %s: %w", name, err)
+		}
+		var inspect struct {
+			Running  bool
+			ExitCode int
+		}
+		err = json.NewDecoder(resp.Body).Decode(&inspect)
+		resp.Body.Close()
+		if err != nil {
+			return noExitError, fmt.Errorf("error decoding exec: %w", err)
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+		select {
+		case <-ctx.Done():
+			return noExitError, ctx.Err()
+		case <-time.After(execPollInterval):
+		}
+	}
+}
+
+// StopService stops the service container and collects its logs and exit
+// code before removing it.
+func (d DockerEngine) StopService(ctx context.Context, name string, limit OutputLimit) (r CmdResult, err error) {
+	containerPath := "/containers/" + url.PathEscape(name)
+	var calls []string
+	defer func() {
+		r.Cmd = strings.Join(calls, "\n")
+		r.FinishTime = time.Now()
+	}()
+	r.ExitStatus = noExitError
+	defer func() {
+		calls = append(calls, fmt.Sprintf("DELETE %s", d.path(containerPath)))
+		q := url.Values{}
+		q.Set("force", "1")
+		if resp, err := d.do(ctx, http.MethodDelete, containerPath, q, nil); err != nil {
+			loggerFrom(ctx).Warn("error removing container", "container", name, errAttr(err))
+		} else {
+			resp.Body.Close()
+		}
+	}()
+
+	calls = append(calls, fmt.Sprintf("POST %s", d.path(containerPath+"/stop")))
+	loggerFrom(ctx).Info("executing command", cmdKey, calls[len(calls)-1])
+	resp, err := d.do(ctx, http.MethodPost, containerPath+"/stop", nil, nil)
+	if err != nil {
+		return r, fmt.Errorf("error stopping container %s: %w", name, err)
+	}
+	resp.Body.Close()
+
+	calls = append(calls, fmt.Sprintf("GET %s", d.path(containerPath+"/logs")))
+	q := url.Values{}
+	q.Set("stdout", "1")
+	q.Set("stderr", "1")
+	resp, err = d.do(ctx, http.MethodGet, containerPath+"/logs", q, nil)
+	if err != nil {
+		return r, fmt.Errorf("error getting logs of container %s: %w", name, err)
+	}
+	outb := newCapture(ctx, limit, name+"-stdout")
+	errb := newCapture(ctx, limit, name+"-stderr")
+	err = demux(resp.Body, outb, errb)
+	resp.Body.Close()
+	r.Stdout, r.StdoutFile = outb.String(), outb.File()
+	r.Stderr, r.StderrFile = errb.String(), errb.File()
+	if err != nil {
+		return r, err
+	}
+
+	calls = append(calls, fmt.Sprintf("GET %s", d.path(containerPath+"/json")))
+	resp, err = d.do(ctx, http.MethodGet, containerPath+"/json", nil, nil)
+	if err != nil {
+		return r, fmt.Errorf("error inspecting container %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	var inspect struct {
+		State struct{ ExitCode int }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return r, fmt.Errorf("error decoding container state: %w", err)
+	}
+	r.ExitStatus = inspect.State.ExitCode
+	return r, nil
+}
+
+// tarDir writes the contents of dir to w as a tar archive.
+func tarDir(dir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error creating build context from %s: %w", dir, err)
+	}
+	return tw.Close()
+}
+
+// envList converts the environment map to a sorted list of key=value strings.
+func envList(env map[string]string) []string {
+	var l []string
+	for k, v := range env {
+		l = append(l, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(l)
+	return l
+}
@@ -0,0 +1,487 @@
+package executor
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEngine is a stand-in for the Docker Engine API. Containers echo their
+// stdin in upper case to stdout and write the image name to stderr.
+type fakeEngine struct {
+	mu         sync.Mutex
+	built      map[string][]string // Files of the build context, by image tag.
+	buildArgs  map[string]string
+	containers map[string]containerConfig
+	removed    []string
+	volumes    map[string]string
+	networks   map[string]bool     // Whether each network is internal, by name.
+	execs      map[string][]string // Commands executed in containers, by exec ID.
+	exitCode   int
+	killed     chan string // Receives the IDs of killed containers.
+	conns      int         // Number of connections accepted.
+}
+
+func newFakeEngine(t *testing.T) (*fakeEngine, DockerEngine) {
+	t.Helper()
+	f := &fakeEngine{
+		built:      make(map[string][]string),
+		containers: make(map[string]containerConfig),
+		volumes:    make(map[string]string),
+		networks:   make(map[string]bool),
+		execs:      make(map[string][]string),
+		killed:     make(chan string, 1),
+	}
+	// Unix socket paths have a small length limit, so we avoid t.TempDir.
+	dir, err := os.MkdirTemp("", "engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewUnstartedServer(f)
+	s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.conns++
+		}
+	}
+	s.Listener = l
+	s.Start()
+	t.Cleanup(s.Close)
+	return f, DockerEngine{SocketPath: socket, APIVersion: "v1.41"}
+}
+
+func (f *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/v1.41")
+	switch {
+	case r.Method == http.MethodPost && path == "/build":
+		tag := r.URL.Query().Get("t")
+		json.Unmarshal([]byte(r.URL.Query().Get("buildargs")), &f.buildArgs)
+		tr := tar.NewReader(r.Body)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				break
+			}
+			f.built[tag] = append(f.built[tag], hdr.Name)
+		}
+		fmt.Fprintf(w, `{"stream":"Step 1/1 : FROM scratch\n"}`)
+		if tag == "broken" {
+			fmt.Fprintf(w, `{"errorDetail":{"message":"failed"},"error":"failed"}`)
+		}
+	case r.Method == http.MethodPost && path == "/images/create":
+		if r.URL.Query().Get("fromImage") == "unknown" {
+			http.Error(w, `{"message":"pull access denied"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"status":"Pulling from library/alpine","id":"latest"}`)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/images/"):
+		// The image reference is a single escaped segment of the path.
+		segment := strings.TrimSuffix(strings.TrimPrefix(r.URL.EscapedPath(), "/v1.41/images/"), "/json")
+		name, err := url.PathUnescape(segment)
+		if _, ok := f.built[name]; !ok || err != nil || strings.Contains(segment, "/") {
+			http.Error(w, `{"message":"No such image"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"Id":"sha256:%x"}`, name)
+	case r.Method == http.MethodPost && path == "/containers/create":
+		var c containerConfig
+		json.NewDecoder(r.Body).Decode(&c)
+		id := fmt.Sprintf("c%d", len(f.containers))
+		if name := r.URL.Query().Get("name"); name != "" {
+			id = name
+		}
+		f.containers[id] = c
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id":%q}`, id)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/attach"):
+		c := f.containers[strings.Split(path, "/")[2]]
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+			if c.Image == "hang" {
+				<-f.killed
+				return
+			}
+			in, _ := io.ReadAll(buf)
+			writeFrame(conn, 1, strings.ToUpper(string(in)))
+			writeFrame(conn, 2, c.Image)
+		}()
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/start"):
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/kill"):
+		f.killed <- strings.Split(path, "/")[2]
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/wait"):
+		fmt.Fprintf(w, `{"StatusCode":%d}`, f.exitCode)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/stop"):
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/logs"):
+		c := f.containers[strings.Split(path, "/")[2]]
+		writeFrame(w, 1, "listening")
+		writeFrame(w, 2, c.Image)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/containers/") && strings.HasSuffix(path, "/json"):
+		c := f.containers[strings.Split(path, "/")[2]]
+		networks := make(map[string]interface{})
+		if c.NetworkingConfig != nil {
+			for n := range c.NetworkingConfig.EndpointsConfig {
+				networks[n] = map[string]string{"IPAddress": "172.18.0.2"}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"State":           map[string]interface{}{"OOMKilled": c.Image == "oom", "ExitCode": f.exitCode},
+			"NetworkSettings": map[string]interface{}{"Networks": networks},
+		})
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/exec"):
+		var e struct{ Cmd []string }
+		json.NewDecoder(r.Body).Decode(&e)
+		id := fmt.Sprintf("e%d", len(f.execs))
+		f.execs[id] = e.Cmd
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id":%q}`, id)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/exec/"):
+		cmd := f.execs[strings.Split(path, "/")[2]]
+		fmt.Fprintf(w, `{"Running":false,"ExitCode":%d}`, len(cmd)-1)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/containers/"):
+		f.removed = append(f.removed, strings.TrimPrefix(path, "/containers/"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && path == "/volumes/create":
+		var v struct {
+			Name       string
+			DriverOpts map[string]string
+		}
+		json.NewDecoder(r.Body).Decode(&v)
+		f.volumes[v.Name] = v.DriverOpts["device"]
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Name":%q}`, v.Name)
+	case r.Method == http.MethodPost && path == "/networks/create":
+		var n struct {
+			Name     string
+			Internal bool
+		}
+		json.NewDecoder(r.Body).Decode(&n)
+		f.networks[n.Name] = n.Internal
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id":%q}`, n.Name)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/networks/"):
+		name := strings.TrimPrefix(path, "/networks/")
+		if _, ok := f.networks[name]; !ok {
+			http.Error(w, `{"message":"network not found"}`, http.StatusNotFound)
+			return
+		}
+		delete(f.networks, name)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/volumes/"):
+		delete(f.volumes, strings.TrimPrefix(path, "/volumes/"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, `{"message":"page not found"}`, http.StatusNotFound)
+	}
+}
+
+func writeFrame(w io.Writer, stream byte, s string) {
+	header := [8]byte{stream}
+	binary.BigEndian.PutUint32(header[4:], uint32(len(s)))
+	w.Write(header[:])
+	io.WriteString(w, s)
+}
+
+func TestDockerEngine_Build(t *testing.T) {
+	f, d := newFakeEngine(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"NAME": `"quoted" $HOME ` + "`cmd`"}
+	r, err := d.Build(context.Background(), BuildSpec{Image: "stage", Dir: dir, Env: env})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if r.ExitStatus != 0 {
+		t.Errorf("want exit status 0, got %d", r.ExitStatus)
+	}
+	if !strings.Contains(r.Stdout, "Step 1/1") {
+		t.Errorf("want build output in stdout, got %q", r.Stdout)
+	}
+	if got := f.built["stage"]; len(got) != 1 || got[0] != "Dockerfile" {
+		t.Errorf("want build context with Dockerfile, got %v", got)
+	}
+	if f.buildArgs["NAME"] != env["NAME"] {
+		t.Errorf("want build arg %q, got %q", env["NAME"], f.buildArgs["NAME"])
+	}
+
+	id, err := d.ImageID(context.Background(), "stage")
+	if err != nil || id != "sha256:7374616765" {
+		t.Errorf("want image ID sha256:7374616765, got %q (%v)", id, err)
+	}
+	// Image references are escaped as a single segment of the API path.
+	if _, err := d.Build(context.Background(), BuildSpec{Image: "ghcr.io/dadosjusbr/stage:v1", Dir: dir}); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if _, err := d.ImageID(context.Background(), "ghcr.io/dadosjusbr/stage:v1"); err != nil {
+		t.Errorf("want image ID of image in registry, got %v", err)
+	}
+
+	r, err = d.Build(context.Background(), BuildSpec{Image: "broken", Dir: dir})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if r.ExitStatus != 1 || !strings.Contains(r.Stderr, "failed") {
+		t.Errorf("want exit status 1 and error in stderr, got %d and %q", r.ExitStatus, r.Stderr)
+	}
+}
+
+func TestDockerEngine_Pull(t *testing.T) {
+	_, d := newFakeEngine(t)
+	r, err := d.Pull(context.Background(), "alpine", "", OutputLimit{})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if !strings.Contains(r.Stdout, "Pulling from library/alpine") {
+		t.Errorf("want pull progress in stdout, got %q", r.Stdout)
+	}
+	r, err = d.Pull(context.Background(), "alpine", "", OutputLimit{MaxSize: 4, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if len(r.Stdout) != 4 || r.StdoutFile == nil {
+		t.Errorf("want pull progress spilled to file, got %q and %+v", r.Stdout, r.StdoutFile)
+	}
+	if _, err := d.Pull(context.Background(), "unknown", "", OutputLimit{}); err == nil || !strings.Contains(err.Error(), "pull access denied") {
+		t.Errorf("want pull access denied error, got %v", err)
+	}
+}
+
+func TestDockerEngine_Run(t *testing.T) {
+	f, d := newFakeEngine(t)
+	f.exitCode = 4
+	var stdout bytes.Buffer
+	r, err := d.Run(context.Background(), RunSpec{
+		Image:      "stage",
+		VolumeName: "vol",
+		VolumeDir:  "/output",
+		Stdin:      "hello",
+		Env:        map[string]string{"B": "$2", "A": `"1"`},
+		Secrets:    map[string]string{"TOKEN": "s3cr3t"},
+		Stdout:     &stdout,
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if r.Stdout != "HELLO" {
+		t.Errorf("want stdout %q, got %q", "HELLO", r.Stdout)
+	}
+	if stdout.String() != "HELLO" {
+		t.Errorf("want stdout %q streamed, got %q", "HELLO", stdout.String())
+	}
+	if r.Stderr != "stage" {
+		t.Errorf("want stderr %q, got %q", "stage", r.Stderr)
+	}
+	if r.ExitStatus != 4 {
+		t.Errorf("want exit status 4, got %d", r.ExitStatus)
+	}
+	c := f.containers["c0"]
+	if got, want := strings.Join(c.Env, ","), `A="1",B=$2,TOKEN=s3cr3t`; got != want {
+		t.Errorf("want env %q, got %q", want, got)
+	}
+	if got := strings.Join(r.Env, ",") + r.Cmd; strings.Contains(got, "s3cr3t") {
+		t.Errorf("want secret out of the result, got env and cmd %q", got)
+	}
+	if got := c.HostConfig.Binds; len(got) != 1 || got[0] != "vol:/output" {
+		t.Errorf("want binds [vol:/output], got %v", got)
+	}
+	if len(f.removed) != 1 || f.removed[0] != "c0" {
+		t.Errorf("want container c0 removed, got %v", f.removed)
+	}
+}
+
+func TestDockerEngine_RunResources(t *testing.T) {
+	f, d := newFakeEngine(t)
+	f.exitCode = 137
+	r, err := d.Run(context.Background(), RunSpec{
+		Image: "oom",
+		Resources: Resources{
+			Memory:     256 << 20,
+			MemorySwap: -1,
+			CPUs:       1.5,
+			PidsLimit:  100,
+			Ulimits:    []Ulimit{{Name: "nofile", Soft: 1024}},
+			TmpfsSize:  64 << 20,
+		},
+		Network: NoNetwork,
+		Mounts: []Mount{
+			{Type: BindMount, Source: "/data/pdfs", Target: "/pdfs", ReadOnly: true},
+			{Type: TmpfsMount, Target: "/scratch", Size: 1 << 30},
+		},
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if !r.OOMKilled {
+		t.Errorf("want container OOM killed")
+	}
+	h := f.containers["c0"].HostConfig
+	if h.Memory != 256<<20 || h.MemorySwap != -1 || h.NanoCpus != 1.5e9 || h.PidsLimit != 100 {
+		t.Errorf("want memory, swap, CPUs and pids limits set, got %+v", h)
+	}
+	if len(h.Ulimits) != 1 || h.Ulimits[0] != (engineUlimit{Name: "nofile", Soft: 1024, Hard: 1024}) {
+		t.Errorf("want ulimit nofile=1024:1024, got %+v", h.Ulimits)
+	}
+	if got, want := h.Tmpfs[tmpDir], "size=67108864"; got != want {
+		t.Errorf("want tmpfs %q, got %q", want, got)
+	}
+	if len(h.Mounts) != 2 || h.Mounts[0].Source != "/data/pdfs" || !h.Mounts[0].ReadOnly || h.Mounts[1].TmpfsOptions == nil || h.Mounts[1].TmpfsOptions.SizeBytes != 1<<30 {
+		t.Errorf("want bind and tmpfs mounts, got %+v", h.Mounts)
+	}
+	if h.NetworkMode != NoNetwork {
+		t.Errorf("want network mode %q, got %q", NoNetwork, h.NetworkMode)
+	}
+}
+
+func TestDockerEngine_RunCanceled(t *testing.T) {
+	f, d := newFakeEngine(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := d.Run(ctx, RunSpec{Image: "hang"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded error, got %v", err)
+	}
+	if len(f.removed) != 1 || f.removed[0] != "c0" {
+		t.Errorf("want container c0 removed, got %v", f.removed)
+	}
+}
+
+func TestDockerEngine_Volume(t *testing.T) {
+	f, d := newFakeEngine(t)
+	if err := d.CreateVolume(context.Background(), "/tmp/output", "vol"); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if f.volumes["vol"] != "/tmp/output" {
+		t.Errorf("want volume bound to /tmp/output, got %q", f.volumes["vol"])
+	}
+	if err := d.RemoveVolume(context.Background(), "vol"); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if len(f.volumes) != 0 {
+		t.Errorf("want no volumes, got %v", f.volumes)
+	}
+	if err := d.CreateVolume(context.Background(), "", "scratch"); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if dir, ok := f.volumes["scratch"]; !ok || dir != "" {
+		t.Errorf("want volume not bound to a dir, got %q", dir)
+	}
+}
+
+func TestDockerEngine_Service(t *testing.T) {
+	f, d := newFakeEngine(t)
+	f.exitCode = 143
+	ctx := context.Background()
+	err := d.StartService(ctx, ServiceSpec{
+		Name:    "browser-x1",
+		Alias:   "browser",
+		Image:   "chromium",
+		Command: []string{"--port", "3000"},
+		Env:     map[string]string{"TOKEN": "a b"},
+		Network: "coleta",
+	})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	c := f.containers["browser-x1"]
+	if strings.Join(c.Cmd, " ") != "--port 3000" || strings.Join(c.Env, ",") != "TOKEN=a b" || c.HostConfig.NetworkMode != "coleta" {
+		t.Errorf("want command, env and network set, got %+v", c)
+	}
+	if got := c.NetworkingConfig.EndpointsConfig["coleta"].Aliases; len(got) != 1 || got[0] != "browser" {
+		t.Errorf("want alias browser in network coleta, got %v", got)
+	}
+	if ip, err := d.ServiceIP(ctx, "browser-x1", "coleta"); err != nil || ip != "172.18.0.2" {
+		t.Errorf("want IP 172.18.0.2, got %q (%v)", ip, err)
+	}
+	if _, err := d.ServiceIP(ctx, "browser-x1", "other"); err == nil {
+		t.Errorf("want error getting IP in other network")
+	}
+	// The fake exec exits with the number of arguments of the command.
+	if code, err := d.ExecService(ctx, "browser-x1", []string{"curl", "-f", "localhost:3000"}); err != nil || code != 2 {
+		t.Errorf("want exit code 2, got %d (%v)", code, err)
+	}
+	r, err := d.StopService(ctx, "browser-x1", OutputLimit{})
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if r.Stdout != "listening" || r.Stderr != "chromium" || r.ExitStatus != 143 {
+		t.Errorf("want service logs and exit status 143, got %+v", r)
+	}
+	if len(f.removed) != 1 || f.removed[0] != "browser-x1" {
+		t.Errorf("want container browser-x1 removed, got %v", f.removed)
+	}
+}
+
+func TestDockerEngine_ReusesConnections(t *testing.T) {
+	f, d := newFakeEngine(t)
+	ctx := context.Background()
+	if err := d.StartService(ctx, ServiceSpec{Name: "db-x1", Alias: "db", Image: "postgres", Network: "coleta"}); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	// Readiness checks execute commands repeatedly.
+	for i := 0; i < 20; i++ {
+		if _, err := d.ExecService(ctx, "db-x1", []string{"pg_isready"}); err != nil {
+			t.Fatalf("want no error, got %v", err)
+		}
+		if _, err := d.ServiceIP(ctx, "db-x1", "coleta"); err != nil {
+			t.Fatalf("want no error, got %v", err)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conns != 1 {
+		t.Errorf("want a single connection reused by the API calls, got %d", f.conns)
+	}
+}
+
+func TestDockerEngine_Network(t *testing.T) {
+	f, d := newFakeEngine(t)
+	if err := d.CreateNetwork(context.Background(), "coleta", true); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if internal, ok := f.networks["coleta"]; !ok || !internal {
+		t.Errorf("want internal network coleta, got %v", f.networks)
+	}
+	if err := d.RemoveNetwork(context.Background(), "coleta"); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if len(f.networks) != 0 {
+		t.Errorf("want no networks, got %v", f.networks)
+	}
+	if err := d.RemoveNetwork(context.Background(), "coleta"); err == nil {
+		t.Errorf("want error removing unknown network")
+	}
+}
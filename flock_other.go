@@ -0,0 +1,15 @@
+//go:build !unix
+
+package executor
+
+import "os"
+
+// tryLock does not lock the file in systems without flock, where concurrent
+// pipelines must not share the git cache.
+func tryLock(f *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build ignore
+
+// gen_schema writes the JSON Schema of the pipeline definition to
+// pipeline.schema.json. Run it with go generate.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/dadosjusbr/executor"
+)
+
+func main() {
+	b, err := executor.JSONSchema()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("pipeline.schema.json", b, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
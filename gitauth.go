@@ -0,0 +1,157 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// defaultHTTPUsername is the username sent with HTTPS tokens. Git hosts
+// accept any non-empty username along with a token.
+const defaultHTTPUsername = "x-access-token"
+
+// GitAuth describes the credentials used to clone a private stage
+// repository. Secrets are never part of the spec: they are read from
+// environment variables of the executor process or from files when the
+// repository is cloned, so they are not in the results, logs or in the proto
+// passed to the error handler.
+type GitAuth struct {
+	Username            string `json:"username" bson:"username,omitempty"`                             // HTTPS username. Defaults to x-access-token, which works with tokens.
+	PasswordEnv         string `json:"password-env" bson:"password-env,omitempty"`                     // Environment variable containing the HTTPS password or token.
+	PasswordFile        string `json:"password-file" bson:"password-file,omitempty"`                   // File containing the HTTPS password or token.
+	SSHUser             string `json:"ssh-user" bson:"ssh-user,omitempty"`                             // SSH user. Defaults to the user of the repository URL or git.
+	SSHKeyFile          string `json:"ssh-key-file" bson:"ssh-key-file,omitempty"`                     // File containing the SSH private key, in PEM format.
+	SSHKeyEnv           string `json:"ssh-key-env" bson:"ssh-key-env,omitempty"`                       // Environment variable containing the SSH private key, in PEM format.
+	SSHKeyPassphraseEnv string `json:"ssh-key-passphrase-env" bson:"ssh-key-passphrase-env,omitempty"` // Environment variable containing the passphrase of the SSH private key, if encrypted.
+	SSHAgent            bool   `json:"ssh-agent" bson:"ssh-agent,omitempty"`                           // Authenticate with the keys of the SSH agent listening at SSH_AUTH_SOCK.
+	SSHKnownHosts       string `json:"ssh-known-hosts" bson:"ssh-known-hosts,omitempty"`               // known_hosts file used to check the host key. Defaults to SSH_KNOWN_HOSTS or ~/.ssh/known_hosts.
+}
+
+// isSSH returns whether the credentials are for SSH repositories.
+func (a GitAuth) isSSH() bool {
+	return a.SSHUser != "" || a.SSHKeyFile != "" || a.SSHKeyEnv != "" || a.SSHKeyPassphraseEnv != "" || a.SSHAgent || a.SSHKnownHosts != ""
+}
+
+// isHTTP returns whether the credentials are for HTTPS repositories.
+func (a GitAuth) isHTTP() bool {
+	return a.Username != "" || a.PasswordEnv != "" || a.PasswordFile != ""
+}
+
+// method returns the go-git authentication for cloning the repository, or
+// nil if no credentials are set. Errors mention where the secrets are read
+// from, never their values.
+func (a GitAuth) method(repoURL string) (transport.AuthMethod, error) {
+	if reflect.ValueOf(a).IsZero() {
+		return nil, nil
+	}
+	ep, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing repository URL: %w", err)
+	}
+	switch ep.Protocol {
+	case "http", "https":
+		if a.isSSH() {
+			return nil, fmt.Errorf("ssh credentials set for %s repository", ep.Protocol)
+		}
+		password, err := secret(a.PasswordEnv, a.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading git password: %w", err)
+		}
+		username := a.Username
+		if username == "" {
+			username = defaultHTTPUsername
+		}
+		return &http.BasicAuth{Username: username, Password: password}, nil
+	case "ssh":
+		if a.isHTTP() {
+			return nil, fmt.Errorf("https credentials set for ssh repository")
+		}
+		return a.sshMethod(ep.User)
+	default:
+		return nil, fmt.Errorf("credentials not supported for %s repositories", ep.Protocol)
+	}
+}
+
+func (a GitAuth) sshMethod(urlUser string) (transport.AuthMethod, error) {
+	user := a.SSHUser
+	if user == "" {
+		user = urlUser
+	}
+	if user == "" {
+		user = ssh.DefaultUsername
+	}
+	var passphrase string
+	if a.SSHKeyPassphraseEnv != "" {
+		var err error
+		if passphrase, err = secret(a.SSHKeyPassphraseEnv, ""); err != nil {
+			return nil, fmt.Errorf("error reading ssh key passphrase: %w", err)
+		}
+	}
+	var helper *ssh.HostKeyCallbackHelper
+	var method transport.AuthMethod
+	switch {
+	case a.SSHKeyFile != "" || a.SSHKeyEnv != "":
+		key, err := secret(a.SSHKeyEnv, a.SSHKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ssh key: %w", err)
+		}
+		// The parsing errors do not include the key.
+		pk, err := ssh.NewPublicKeys(user, []byte(key), passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ssh key: %w", err)
+		}
+		helper, method = &pk.HostKeyCallbackHelper, pk
+	case a.SSHAgent:
+		agent, err := ssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to ssh agent: %w", err)
+		}
+		helper, method = &agent.HostKeyCallbackHelper, agent
+	default:
+		return nil, fmt.Errorf("ssh credentials need ssh-key-file, ssh-key-env or ssh-agent")
+	}
+	// Host keys are always checked. Without a file, the default known_hosts
+	// files are used.
+	var files []string
+	if a.SSHKnownHosts != "" {
+		files = append(files, a.SSHKnownHosts)
+	}
+	callback, err := ssh.NewKnownHostsCallback(files...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading ssh known hosts: %w", err)
+	}
+	helper.HostKeyCallback = callback
+	return method, nil
+}
+
+// secret returns the content of the environment variable or, if env is
+// empty, of the file, without the trailing newline.
+func secret(env, file string) (string, error) {
+	switch {
+	case env != "" && file != "":
+		return "", fmt.Errorf("both environment variable %s and file %s set", env, file)
+	case env != "":
+		v, ok := os.LookupEnv(env)
+		if !ok || v == "" {
+			return "", fmt.Errorf("environment variable %s not set", env)
+		}
+		return v, nil
+	case file != "":
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("error reading file %s: %w", file, err)
+		}
+		v := strings.TrimRight(string(b), "\r\n")
+		if v == "" {
+			return "", fmt.Errorf("file %s is empty", file)
+		}
+		return v, nil
+	default:
+		return "", fmt.Errorf("neither environment variable nor file set")
+	}
+}
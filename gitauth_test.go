@@ -0,0 +1,179 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"log/slog"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dadosjusbr/executor/status"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/protobuf/encoding/prototext"
+)
+
+const testSecret = "s3cr3t-t0ken"
+
+// newTestKey returns a PEM encoded ed25519 private key, encrypted if the
+// passphrase is set, and a known_hosts file.
+func newTestKey(t *testing.T, passphrase string) (string, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(knownHosts, []byte("github.com "+string(ssh.MarshalAuthorizedKey(sshPub))), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(block)), knownHosts
+}
+
+func TestGitAuthMethod(t *testing.T) {
+	key, knownHosts := newTestKey(t, "")
+	encryptedKey, _ := newTestKey(t, testSecret)
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "id_ed25519")
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(keyFile, []byte(key), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(passwordFile, []byte(testSecret+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_TOKEN", testSecret)
+	t.Setenv("GIT_SSH_KEY", encryptedKey)
+	t.Setenv("GIT_SSH_PASSPHRASE", testSecret)
+	t.Setenv("GIT_BAD_KEY", "-----BEGIN "+testSecret)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	testCases := []struct {
+		desc     string
+		url      string
+		auth     GitAuth
+		wantUser string // Username or SSH user of the method. No method if empty.
+		wantErr  bool
+	}{
+		{"None", "https://github.com/dadosjusbr/coletor", GitAuth{}, "", false},
+		{"TokenFromEnv", "https://github.com/dadosjusbr/coletor", GitAuth{PasswordEnv: "GIT_TOKEN"}, defaultHTTPUsername, false},
+		{"BasicFromFile", "https://github.com/dadosjusbr/coletor", GitAuth{Username: "dadosjusbr", PasswordFile: passwordFile}, "dadosjusbr", false},
+		{"MissingEnv", "https://github.com/dadosjusbr/coletor", GitAuth{PasswordEnv: "GIT_MISSING"}, "", true},
+		{"SSHOnHTTPS", "https://github.com/dadosjusbr/coletor", GitAuth{SSHAgent: true}, "", true},
+		{"KeyFile", "git@github.com:dadosjusbr/coletor.git", GitAuth{SSHKeyFile: keyFile, SSHKnownHosts: knownHosts}, "git", false},
+		{"EncryptedKeyFromEnv", "ssh://collector@github.com/dadosjusbr/coletor", GitAuth{SSHKeyEnv: "GIT_SSH_KEY", SSHKeyPassphraseEnv: "GIT_SSH_PASSPHRASE", SSHKnownHosts: knownHosts}, "collector", false},
+		{"SSHUser", "git@github.com:dadosjusbr/coletor.git", GitAuth{SSHUser: "deploy", SSHKeyFile: keyFile, SSHKnownHosts: knownHosts}, "deploy", false},
+		{"InvalidKey", "git@github.com:dadosjusbr/coletor.git", GitAuth{SSHKeyEnv: "GIT_BAD_KEY", SSHKnownHosts: knownHosts}, "", true},
+		{"MissingKnownHosts", "git@github.com:dadosjusbr/coletor.git", GitAuth{SSHKeyFile: keyFile, SSHKnownHosts: filepath.Join(dir, "missing")}, "", true},
+		{"AgentNotRunning", "git@github.com:dadosjusbr/coletor.git", GitAuth{SSHAgent: true, SSHKnownHosts: knownHosts}, "", true},
+		{"HTTPSOnSSH", "git@github.com:dadosjusbr/coletor.git", GitAuth{PasswordEnv: "GIT_TOKEN"}, "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			m, err := tc.auth.method(tc.url)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %v", m)
+				}
+				if strings.Contains(err.Error(), testSecret) {
+					t.Errorf("want error without secret, got %q", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			switch m := m.(type) {
+			case nil:
+				if tc.wantUser != "" {
+					t.Errorf("want auth method for %s, got nil", tc.wantUser)
+				}
+			case *http.BasicAuth:
+				if m.Username != tc.wantUser || m.Password != testSecret {
+					t.Errorf("want basic auth %s:%s, got %s:%s", tc.wantUser, testSecret, m.Username, m.Password)
+				}
+			case *gitssh.PublicKeys:
+				if m.User != tc.wantUser || m.HostKeyCallback == nil {
+					t.Errorf("want ssh key of user %s checking host keys, got %+v", tc.wantUser, m)
+				}
+			default:
+				t.Errorf("unexpected auth method %T", m)
+			}
+			if m != nil && strings.Contains(m.String(), testSecret) {
+				t.Errorf("want auth description without secret, got %q", m.String())
+			}
+		})
+	}
+}
+
+// TestPipelineRun_GitAuth checks the credentials are sent to the git server
+// and are not in the result, logs or in the proto passed to the error handler.
+func TestPipelineRun_GitAuth(t *testing.T) {
+	var mu sync.Mutex
+	var gotUser, gotPassword string
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		mu.Lock()
+		gotUser, gotPassword, _ = r.BasicAuth()
+		mu.Unlock()
+		nethttp.Error(w, "not found", nethttp.StatusNotFound)
+	}))
+	defer srv.Close()
+	t.Setenv("GIT_TOKEN", testSecret)
+
+	var logs bytes.Buffer
+	rt := &fakeRuntime{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		DefaultGitAuth: GitAuth{Username: "dadosjusbr", PasswordEnv: "GIT_TOKEN"},
+		Stages:         []Stage{{Name: "coleta", Repo: srv.URL + "/dadosjusbr/coletor"}},
+		Logger:         slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Runtime:        rt,
+	}
+	result := p.RunContext(context.Background())
+	if result.Status != status.SetupError {
+		t.Fatalf("want status %v, got %v", status.SetupError, result.Status)
+	}
+	mu.Lock()
+	if gotUser != "dadosjusbr" || gotPassword != testSecret {
+		t.Errorf("want credentials dadosjusbr:%s sent, got %s:%s", testSecret, gotUser, gotPassword)
+	}
+	mu.Unlock()
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pe, err := prototext.Marshal(NewPipelineExecution(p, result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]string{"result": string(b), "logs": logs.String(), "proto": string(pe)} {
+		if strings.Contains(out, testSecret) {
+			t.Errorf("want no secret in %s, got %s", name, out)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+const (
+	mirrorSuffix = ".git"                 // Suffix of the mirrors in the git cache dir.
+	lockSuffix   = ".lock"                // Suffix of the mirror lock files.
+	lockRetry    = 100 * time.Millisecond // Wait between attempts to take a held lock.
+)
+
+// mirrorRefSpecs are the references kept by the mirrors. Other references,
+// like GitHub pull requests, are not needed by the stages.
+var mirrorRefSpecs = []config.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+// mirrorName returns the name of the mirror of the repository in the git
+// cache dir: the URL made safe for file names, followed by part of its hash,
+// which tells apart URLs made equal.
+func mirrorName(repoURL string) string {
+	sum := sha256.Sum256([]byte(repoURL))
+	name := repoURL
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+3:]
+	}
+	name = strings.Trim(unsafeFileChars.ReplaceAllString(strings.TrimSuffix(name, mirrorSuffix), "-"), "-.")
+	if len(name) > 64 {
+		name = name[len(name)-64:]
+	}
+	return name + "-" + hex.EncodeToString(sum[:4]) + mirrorSuffix
+}
+
+// lockMirror waits until the exclusive lock of the mirror is taken, so that
+// pipelines on the same host do not update or read it at the same time. The
+// lock is released by calling the function returned.
+func lockMirror(ctx context.Context, mirror string) (func(), error) {
+	f, err := os.OpenFile(mirror+lockSuffix, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file: %w", err)
+	}
+	for waiting := false; ; waiting = true {
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("error locking %s: %w", f.Name(), err)
+		}
+		if ok {
+			break
+		}
+		if !waiting {
+			loggerFrom(ctx).Info("waiting for git cache lock", "mirror", mirror)
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, fmt.Errorf("error waiting for lock of %s: %w", mirror, ctx.Err())
+		case <-time.After(lockRetry):
+		}
+	}
+	return func() {
+		unlock(f)
+		f.Close()
+	}, nil
+}
+
+// cloneFromCache updates the mirror of the repository in the git cache dir
+// and creates the working copy in dir from it, like git clone --local
+// --no-checkout. HEAD points to the ref, or to the default branch.
+func cloneFromCache(ctx context.Context, dir string, repo repoSpec) (*git.Repository, error) {
+	if err := os.MkdirAll(repo.cacheDir, 0775); err != nil {
+		return nil, fmt.Errorf("error creating git cache dir: %w", err)
+	}
+	mirror := filepath.Join(repo.cacheDir, mirrorName(repo.url))
+	unlock, err := lockMirror(ctx, mirror)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := updateMirror(ctx, mirror, repo); err != nil {
+		return nil, fmt.Errorf("error updating mirror %s: %w", mirror, err)
+	}
+	// The modification time tells when the mirror was last used.
+	now := time.Now()
+	if err := os.Chtimes(mirror, now, now); err != nil {
+		return nil, fmt.Errorf("error touching mirror %s: %w", mirror, err)
+	}
+	if err := copyMirror(mirror, filepath.Join(dir, git.GitDirName)); err != nil {
+		return nil, fmt.Errorf("error copying mirror %s: %w", mirror, err)
+	}
+
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error opening working copy: %w", err)
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, fmt.Errorf("error reading working copy config: %w", err)
+	}
+	cfg.Core.IsBare = false
+	if rc, ok := cfg.Remotes[git.DefaultRemoteName]; ok {
+		rc.Fetch = []config.RefSpec{config.RefSpec(fmt.Sprintf(config.DefaultFetchRefSpec, git.DefaultRemoteName))}
+	}
+	if err := r.SetConfig(cfg); err != nil {
+		return nil, fmt.Errorf("error writing working copy config: %w", err)
+	}
+
+	if repo.ref == "" {
+		// HEAD of the mirror, copied above.
+		return r, nil
+	}
+	var name plumbing.ReferenceName
+	// Same rules as git uses to expand short names, e.g. main or v1.0.
+	for _, rule := range plumbing.RefRevParseRules {
+		n := plumbing.ReferenceName(fmt.Sprintf(rule, repo.ref))
+		if _, err := r.Reference(n, false); err == nil {
+			name = n
+			break
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("reference %s not found", repo.ref)
+	}
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, name)
+	if !name.IsBranch() {
+		// Tags are checked out detached, at their commit.
+		hash, err := r.ResolveRevision(plumbing.Revision(name))
+		if err != nil {
+			return nil, fmt.Errorf("error resolving %s: %w", name, err)
+		}
+		head = plumbing.NewHashReference(plumbing.HEAD, *hash)
+	}
+	if err := r.Storer.SetReference(head); err != nil {
+		return nil, fmt.Errorf("error setting HEAD: %w", err)
+	}
+	return r, nil
+}
+
+// updateMirror fetches the branches and tags of the repository into the bare
+// mirror, creating it if it does not exist or can not be opened.
+func updateMirror(ctx context.Context, mirror string, repo repoSpec) error {
+	auth, err := repo.auth.method(repo.url)
+	if err != nil {
+		return fmt.Errorf("error setting up git credentials: %w", err)
+	}
+	r, err := git.PlainOpen(mirror)
+	if err != nil {
+		if err := os.RemoveAll(mirror); err != nil {
+			return fmt.Errorf("error removing unusable mirror: %w", err)
+		}
+		if r, err = git.PlainInit(mirror, true); err != nil {
+			return fmt.Errorf("error creating mirror: %w", err)
+		}
+		if _, err := r.CreateRemote(&config.RemoteConfig{
+			Name:  git.DefaultRemoteName,
+			URLs:  []string{repo.url},
+			Fetch: mirrorRefSpecs,
+		}); err != nil {
+			return fmt.Errorf("error creating mirror remote: %w", err)
+		}
+	}
+	remote, err := r.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return fmt.Errorf("error getting mirror remote: %w", err)
+	}
+	loggerFrom(ctx).Info("updating git cache mirror", "repo", repo.url, "mirror", mirror)
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return fmt.Errorf("error listing references: %q", err)
+	}
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: mirrorRefSpecs,
+		Auth:     auth,
+		Force:    true,
+		Prune:    true,
+		Tags:     git.NoTags, // Fetched by the ref specs.
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("error fetching: %q", err)
+	}
+	// HEAD follows the default branch of the remote.
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD {
+			if err := r.Storer.SetReference(ref); err != nil {
+				return fmt.Errorf("error setting HEAD: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// copyMirror copies the bare mirror to the git dir of a working copy. The
+// objects, which are never modified, are hard linked if possible.
+func copyMirror(mirror, gitDir string) error {
+	return filepath.WalkDir(mirror, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(mirror, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(gitDir, rel)
+		if d.IsDir() {
+			return os.MkdirAll(dst, 0775)
+		}
+		if strings.HasPrefix(rel, "objects"+string(filepath.Separator)) && os.Link(path, dst) == nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return copyFile(path, dst, info.Mode().Perm())
+	})
+}
+
+// PruneGitCache removes the mirrors of the git cache dir that have not been
+// used for longer than maxAge and returns their paths. Mirrors in use are
+// waited for. The lock files are kept: removing them would allow two
+// pipelines to lock different files for the same mirror.
+func PruneGitCache(ctx context.Context, dir string, maxAge time.Duration) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading git cache dir: %w", err)
+	}
+	cutoff := time.Now().Add(-maxAge)
+	var removed []string
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasSuffix(e.Name(), mirrorSuffix) {
+			continue
+		}
+		mirror := filepath.Join(dir, e.Name())
+		ok, err := pruneMirror(ctx, mirror, cutoff)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed = append(removed, mirror)
+		}
+	}
+	return removed, nil
+}
+
+// pruneMirror removes the mirror if it was last used before cutoff.
+func pruneMirror(ctx context.Context, mirror string, cutoff time.Time) (bool, error) {
+	unlock, err := lockMirror(ctx, mirror)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	// Checked with the lock held, as the mirror may have just been used.
+	info, err := os.Stat(mirror)
+	if err != nil {
+		return false, fmt.Errorf("error reading mirror %s: %w", mirror, err)
+	}
+	if info.ModTime().After(cutoff) {
+		return false, nil
+	}
+	if err := os.RemoveAll(mirror); err != nil {
+		return false, fmt.Errorf("error removing mirror %s: %w", mirror, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,193 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestMirrorName(t *testing.T) {
+	testCases := []struct {
+		desc string
+		url  string
+		want string
+	}{
+		{"HTTPS", "https://github.com/dadosjusbr/coletor-cnj.git", "github.com-dadosjusbr-coletor-cnj-"},
+		{"SCPLike", "git@github.com:dadosjusbr/coletor.git", "git-github.com-dadosjusbr-coletor-"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := mirrorName(tc.url)
+			// Prefix, 8 hex digits of the hash and the suffix.
+			if len(got) != len(tc.want)+8+len(mirrorSuffix) || got[:len(tc.want)] != tc.want {
+				t.Errorf("want %s<hash>%s, got %s", tc.want, mirrorSuffix, got)
+			}
+		})
+	}
+	if mirrorName("https://github.com/a/b") == mirrorName("https://github.com/a-b") {
+		t.Errorf("want different mirrors for different URLs")
+	}
+}
+
+func TestCloneRepository_GitCache(t *testing.T) {
+	tr := newTestRepo(t)
+	cacheDir := t.TempDir()
+	clone := func(t *testing.T, ref, commit string) string {
+		t.Helper()
+		dir := filepath.Join(t.TempDir(), "repo")
+		got, err := cloneRepository(context.Background(), dir, repoSpec{url: tr.url, ref: ref, commit: commit, cacheDir: cacheDir})
+		if err != nil {
+			t.Fatalf("want no error, got %q", err)
+		}
+		r, err := git.PlainOpen(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, err := r.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head.Hash().String() != got {
+			t.Errorf("want HEAD at %s, got %s", got, head.Hash())
+		}
+		// The working copy points to the repository, not to the mirror.
+		remote, err := r.Remote(git.DefaultRemoteName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u := remote.Config().URLs[0]; u != tr.url {
+			t.Errorf("want origin %s, got %s", tr.url, u)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, "README"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got + ":" + string(b)
+	}
+
+	testCases := []struct {
+		desc   string
+		ref    string
+		commit string
+		want   string
+	}{
+		{"DefaultBranch", "", "", tr.c2 + ":c2"},
+		{"Branch", "feature", "", tr.c3 + ":c3"},
+		{"AnnotatedTag", "v1", "", tr.c1 + ":c1"},
+		{"Commit", "", tr.c1[:8], tr.c1 + ":c1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := clone(t, tc.ref, tc.commit); got != tc.want {
+				t.Errorf("want %s, got %s", tc.want, got)
+			}
+		})
+	}
+
+	// The mirror is reused and updated with the new commits.
+	c4 := tr.commit(t, "c4")
+	if got, want := clone(t, "", ""), c4+":c4"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	mirrors, err := filepath.Glob(filepath.Join(cacheDir, "*"+mirrorSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mirrors) != 1 {
+		t.Errorf("want 1 mirror, got %v", mirrors)
+	}
+
+	if _, err := cloneRepository(context.Background(), t.TempDir(), repoSpec{url: tr.url, ref: "missing", cacheDir: cacheDir}); err == nil {
+		t.Errorf("want error cloning missing ref, got nil")
+	}
+}
+
+func TestCloneRepository_GitCacheShallow(t *testing.T) {
+	tr := newTestRepo(t)
+	var logs bytes.Buffer
+	ctx := withLogger(context.Background(), slog.New(slog.NewJSONHandler(&logs, nil)))
+	dir := filepath.Join(t.TempDir(), "repo")
+	if _, err := cloneRepository(ctx, dir, repoSpec{url: tr.url, depth: 1, cacheDir: t.TempDir()}); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if !strings.Contains(logs.String(), "clone-depth and single-branch are ignored") {
+		t.Errorf("want warning about clone-depth ignored, got %s", logs.String())
+	}
+}
+
+func TestCloneRepository_GitCacheConcurrent(t *testing.T) {
+	tr := newTestRepo(t)
+	cacheDir := t.TempDir()
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dir := filepath.Join(t.TempDir(), fmt.Sprint("repo", i))
+			_, errs[i] = cloneRepository(context.Background(), dir, repoSpec{url: tr.url, ref: "feature", cacheDir: cacheDir})
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("want no error, got %q", err)
+		}
+	}
+}
+
+func TestLockMirror(t *testing.T) {
+	mirror := filepath.Join(t.TempDir(), "repo.git")
+	unlock, err := lockMirror(context.Background(), mirror)
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lockRetry)
+	defer cancel()
+	if _, err := lockMirror(ctx, mirror); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want %v while locked, got %v", context.DeadlineExceeded, err)
+	}
+	unlock()
+	unlock, err = lockMirror(context.Background(), mirror)
+	if err != nil {
+		t.Fatalf("want no error after unlock, got %q", err)
+	}
+	unlock()
+}
+
+func TestPruneGitCache(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"old.git", "recent.git", "other"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0775); err != nil {
+			t.Fatal(err)
+		}
+		if name != "recent.git" {
+			if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	removed, err := PruneGitCache(context.Background(), dir, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if want := filepath.Join(dir, "old.git"); len(removed) != 1 || removed[0] != want {
+		t.Errorf("want %v removed, got %v", []string{want}, removed)
+	}
+	for name, want := range map[string]bool{"old.git": false, "recent.git": true, "other": true} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Errorf("want %s kept %v, got stat error %v", name, want, err)
+		}
+	}
+}
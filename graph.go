@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Formats of the stage graph.
+const (
+	GraphDOT     = "dot"
+	GraphMermaid = "mermaid"
+)
+
+// Graph returns the dependency graph of the pipeline stages in the format:
+// dot (Graphviz) or mermaid. Each edge goes from a stage to a stage depending
+// on it. The error handler, if any, is a dashed node without edges.
+func (p *Pipeline) Graph(format string) (string, error) {
+	if errs := p.validate(false); len(errs) > 0 {
+		return "", fmt.Errorf("invalid pipeline spec: %w", errs)
+	}
+	graph, err := newStageGraph(p.Stages)
+	if err != nil {
+		return "", fmt.Errorf("invalid stage dependencies: %w", err)
+	}
+	handler := ""
+	if !reflect.ValueOf(p.ErrorHandler).IsZero() {
+		handler = p.ErrorHandler.Name
+	}
+	var b strings.Builder
+	switch format {
+	case GraphDOT:
+		fmt.Fprintf(&b, "digraph %s {\n", strconv.Quote(p.Name))
+		b.WriteString("  rankdir=LR;\n")
+		for i, s := range p.Stages {
+			fmt.Fprintf(&b, "  s%d [label=%s];\n", i, strconv.Quote(s.Name))
+		}
+		for i, children := range graph.children {
+			for _, c := range children {
+				fmt.Fprintf(&b, "  s%d -> s%d;\n", i, c)
+			}
+		}
+		if handler != "" {
+			fmt.Fprintf(&b, "  error_handler [label=%s, style=dashed];\n", strconv.Quote(handler+" (error handler)"))
+		}
+		b.WriteString("}\n")
+	case GraphMermaid:
+		b.WriteString("flowchart LR\n")
+		for i, s := range p.Stages {
+			fmt.Fprintf(&b, "  s%d[%s]\n", i, mermaidLabel(s.Name))
+		}
+		for i, children := range graph.children {
+			for _, c := range children {
+				fmt.Fprintf(&b, "  s%d --> s%d\n", i, c)
+			}
+		}
+		if handler != "" {
+			fmt.Fprintf(&b, "  error_handler[%s]\n", mermaidLabel(handler+" (error handler)"))
+			b.WriteString("  style error_handler stroke-dasharray: 5 5\n")
+		}
+	default:
+		return "", fmt.Errorf("unknown graph format: %q", format)
+	}
+	return b.String(), nil
+}
+
+// mermaidLabel quotes the node label, escaping the quotes it contains.
+func mermaidLabel(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
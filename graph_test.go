@@ -0,0 +1,68 @@
+package executor
+
+import "testing"
+
+func TestPipelineGraph(t *testing.T) {
+	p := Pipeline{
+		Name: "test",
+		Stages: []Stage{
+			{Name: "coleta"},
+			{Name: "validacao", DependsOn: []string{"coleta"}},
+			{Name: `store "s3"`, ContainerID: "store", DependsOn: []string{"coleta", "validacao"}},
+		},
+		ErrorHandler: Stage{Name: "handler"},
+	}
+	testCases := []struct {
+		desc   string
+		format string
+		want   string
+	}{
+		{"DOT", GraphDOT, `digraph "test" {
+  rankdir=LR;
+  s0 [label="coleta"];
+  s1 [label="validacao"];
+  s2 [label="store \"s3\""];
+  s0 -> s1;
+  s0 -> s2;
+  s1 -> s2;
+  error_handler [label="handler (error handler)", style=dashed];
+}
+`},
+		{"Mermaid", GraphMermaid, `flowchart LR
+  s0["coleta"]
+  s1["validacao"]
+  s2["store #quot;s3#quot;"]
+  s0 --> s1
+  s0 --> s2
+  s1 --> s2
+  error_handler["handler (error handler)"]
+  style error_handler stroke-dasharray: 5 5
+`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := p.Graph(tc.format)
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if got != tc.want {
+				t.Errorf("want:\n%s\ngot:\n%s", tc.want, got)
+			}
+		})
+	}
+
+	// Without dependencies, stages form a chain.
+	chain := Pipeline{Name: "chain", Stages: []Stage{{Name: "a"}, {Name: "b"}}}
+	want := "flowchart LR\n  s0[\"a\"]\n  s1[\"b\"]\n  s0 --> s1\n"
+	if got, err := chain.Graph(GraphMermaid); err != nil || got != want {
+		t.Errorf("want %q, got %q (%v)", want, got, err)
+	}
+
+	p.Stages[0].DependsOn = []string{"store \"s3\""}
+	if _, err := p.Graph(GraphDOT); err == nil {
+		t.Errorf("want error for dependency cycle")
+	}
+	if _, err := chain.Graph("png"); err == nil {
+		t.Errorf("want error for unknown format")
+	}
+}
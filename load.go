@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the pipeline definition.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// LoadPipeline reads the pipeline definition from the file at path. The
+// format is detected from the file extension: .yaml or .yml for YAML, .toml
+// for TOML and JSON otherwise.
+func LoadPipeline(path string) (Pipeline, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Pipeline{}, fmt.Errorf("error reading pipeline file: %w", err)
+	}
+	p, err := ParsePipeline(b, formatOf(path))
+	if err != nil {
+		return Pipeline{}, fmt.Errorf("error loading pipeline from %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParsePipeline parses the pipeline definition in the format: json, yaml or
+// toml. The fields have the same names in every format, e.g. default-run-env
+// and run-success-codes. Unknown fields are rejected with ValidationErrors.
+func ParsePipeline(b []byte, format string) (Pipeline, error) {
+	var doc interface{}
+	var err error
+	switch format {
+	case FormatJSON:
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		err = d.Decode(&doc)
+	case FormatYAML:
+		err = yaml.Unmarshal(b, &doc)
+	case FormatTOML:
+		_, err = toml.NewDecoder(bytes.NewReader(b)).Decode(&doc)
+	default:
+		return Pipeline{}, fmt.Errorf("unknown pipeline format: %q", format)
+	}
+	if err != nil {
+		return Pipeline{}, fmt.Errorf("error parsing %s pipeline: %w", format, err)
+	}
+	var errs ValidationErrors
+	unknownFields(&errs, "", doc, reflect.TypeOf(Pipeline{}))
+	if len(errs) > 0 {
+		return Pipeline{}, errs
+	}
+	// The document is converted to JSON, so the JSON field names and the
+	// custom decoding of durations and status codes apply to every format.
+	j, err := json.Marshal(doc)
+	if err != nil {
+		return Pipeline{}, fmt.Errorf("error converting %s pipeline to json: %w", format, err)
+	}
+	var p Pipeline
+	if err := json.Unmarshal(j, &p); err != nil {
+		return Pipeline{}, fmt.Errorf("error parsing %s pipeline: %w", format, err)
+	}
+	return p, nil
+}
+
+// formatOf returns the format of the pipeline file according to its extension.
+func formatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownFields appends the fields of the decoded document doc, whose JSON
+// path is path, that do not exist in the type t. Values of the wrong type are
+// left to the JSON decoding.
+func unknownFields(errs *ValidationErrors, path string, doc interface{}, t reflect.Type) {
+	if reflect.PointerTo(t).Implements(jsonUnmarshaler) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for _, k := range sortedKeys(obj) {
+			p := joinPath(path, k)
+			f, ok := fields[k]
+			if !ok {
+				if s := similarField(fields, k); s != "" {
+					errs.add(p, "unknown field, did you mean %s?", s)
+				} else {
+					errs.add(p, "unknown field")
+				}
+				continue
+			}
+			unknownFields(errs, p, obj[k], f.Type)
+		}
+	case reflect.Map:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, k := range sortedKeys(obj) {
+			unknownFields(errs, joinPath(path, k), obj[k], t.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := doc.([]interface{})
+		if !ok {
+			return
+		}
+		for i, v := range list {
+			unknownFields(errs, fmt.Sprintf("%s[%d]", path, i), v, t.Elem())
+		}
+	case reflect.Pointer:
+		unknownFields(errs, path, doc, t.Elem())
+	}
+}
+
+// jsonFields returns the fields of the struct type t by their JSON name.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// similarField returns the field whose name differs from name only by case,
+// hyphens or underscores, if any.
+func similarField(fields map[string]reflect.StructField, name string) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(s))
+	}
+	for _, f := range sortedKeys(fields) {
+		if normalize(f) == normalize(name) {
+			return f
+		}
+	}
+	return ""
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
@@ -0,0 +1,186 @@
+package executor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadPipeline(t *testing.T) {
+	want := Pipeline{
+		Name:          "coleta-trt13",
+		DefaultRunEnv: map[string]string{"OUTPUT_FOLDER": "/output", "YEAR": "2023"},
+		VolumeDir:     "/output",
+		Stages: []Stage{
+			{
+				Name:            "coleta",
+				Repo:            "github.com/dadosjusbr/coletor-trt13",
+				RunSuccessCodes: []int{0, 4},
+				Timeout:         Duration(2 * time.Hour),
+				Retry: RetryPolicy{
+					MaxAttempts: 3,
+					ExitCodes:   []int{7},
+				},
+			},
+			{
+				Name:      "validacao",
+				Image:     "ghcr.io/dadosjusbr/validador:main",
+				DependsOn: []string{"coleta"},
+			},
+		},
+	}
+	testCases := []struct {
+		desc    string
+		file    string
+		content string
+	}{
+		{"JSON", "pipeline.json", `{
+  "name": "coleta-trt13",
+  "default-run-env": {"OUTPUT_FOLDER": "/output", "YEAR": "2023"},
+  "volume-dir": "/output",
+  "stages": [
+    {
+      "name": "coleta",
+      "repo": "github.com/dadosjusbr/coletor-trt13",
+      "run-success-codes": [0, 4],
+      "timeout": "2h",
+      "retry": {"max-attempts": 3, "exit-codes": [7]}
+    },
+    {
+      "name": "validacao",
+      "image": "ghcr.io/dadosjusbr/validador:main",
+      "depends-on": ["coleta"]
+    }
+  ]
+}`},
+		{"YAML", "pipeline.yaml", `# Coleta do TRT13.
+name: coleta-trt13
+default-run-env:
+  OUTPUT_FOLDER: /output
+  YEAR: "2023"
+volume-dir: /output
+stages:
+  - name: coleta
+    repo: github.com/dadosjusbr/coletor-trt13
+    run-success-codes: [0, 4]
+    timeout: 2h
+    retry:
+      max-attempts: 3
+      exit-codes: [7]
+  - name: validacao
+    image: ghcr.io/dadosjusbr/validador:main
+    depends-on: [coleta]
+`},
+		{"YML", "pipeline.yml", `name: coleta-trt13
+default-run-env: {OUTPUT_FOLDER: /output, YEAR: "2023"}
+volume-dir: /output
+stages:
+  - {name: coleta, repo: github.com/dadosjusbr/coletor-trt13, run-success-codes: [0, 4], timeout: 2h, retry: {max-attempts: 3, exit-codes: [7]}}
+  - {name: validacao, image: "ghcr.io/dadosjusbr/validador:main", depends-on: [coleta]}
+`},
+		{"TOML", "pipeline.toml", `# Coleta do TRT13.
+name = "coleta-trt13"
+volume-dir = "/output"
+
+[default-run-env]
+OUTPUT_FOLDER = "/output"
+YEAR = "2023"
+
+[[stages]]
+name = "coleta"
+repo = "github.com/dadosjusbr/coletor-trt13"
+run-success-codes = [0, 4]
+timeout = "2h"
+retry = { max-attempts = 3, exit-codes = [7] }
+
+[[stages]]
+name = "validacao"
+image = "ghcr.io/dadosjusbr/validador:main"
+depends-on = ["coleta"]
+`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadPipeline(path)
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("want %+v, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestLoadPipeline_Errors(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		file    string
+		content string
+	}{
+		{"InvalidYAML", "pipeline.yaml", "name: [a"},
+		{"InvalidTOML", "pipeline.toml", "name = "},
+		{"WrongType", "pipeline.yaml", "stages: {name: a}"},
+		{"InvalidDuration", "pipeline.toml", `default-timeout = "forever"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadPipeline(path); err == nil {
+				t.Errorf("want error, got nil")
+			}
+		})
+	}
+	if _, err := LoadPipeline(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("want error loading missing file, got nil")
+	}
+}
+
+func TestParsePipeline_UnknownFields(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		format  string
+		content string
+		want    []string
+	}{
+		{"JSON", FormatJSON, `{"name": "p", "stages": [{"name": "a"}, {"name": "b", "run_env": {"A": "1"}}]}`,
+			[]string{"stages[1].run_env: unknown field, did you mean run-env?"}},
+		{"YAML", FormatYAML, "name: p\ncolor: blue\nstages:\n  - name: a\n    retry: {max-attempts: 2, backof: 1s}\n",
+			[]string{"color: unknown field", "stages[0].retry.backof: unknown field"}},
+		{"TOML", FormatTOML, "name = \"p\"\n[error-handler]\nName = \"h\"\n",
+			[]string{"error-handler.Name: unknown field, did you mean name?"}},
+		{"EnvNamesAreNotFields", FormatYAML, "default-run-env: {any_name: x}\n", nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := ParsePipeline([]byte(tc.content), tc.format)
+			if tc.want == nil {
+				if err != nil {
+					t.Errorf("want no error, got %q", err)
+				}
+				return
+			}
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("want ValidationErrors, got %v", err)
+			}
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
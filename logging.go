@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Attribute keys of the log records, shared by all records so they can be
+// indexed by log aggregators.
+const (
+	pipelineKey    = "pipeline"     // Pipeline's name.
+	stageKey       = "stage"        // Stage's name.
+	stepKey        = "step"         // Step of the stage: setup, build, run or teardown.
+	containerIDKey = "container_id" // Stage container ID.
+	commitKey      = "commit"       // Commit of the stage repo.
+	exitStatusKey  = "exit_status"  // Exit status of a command.
+	durationKey    = "duration"     // Duration of a step, stage or pipeline.
+	statusKey      = "status"       // Execution status.
+	cmdKey         = "cmd"          // Command executed.
+	errorKey       = "error"        // Error message.
+)
+
+type loggerKey struct{}
+
+// withLogger returns a context carrying the logger, which is used by the
+// runtimes and helpers receiving it.
+func withLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the logger carried by ctx or the default logger.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// logger returns the pipeline logger with the pipeline attribute.
+func (p *Pipeline) logger() *slog.Logger {
+	return p.baseLogger().With(pipelineKey, p.Name)
+}
+
+// baseLogger returns the Logger of the pipeline, or the default logger,
+// redacting the secrets of the running execution.
+func (p *Pipeline) baseLogger() *slog.Logger {
+	l := p.Logger
+	if l == nil {
+		l = slog.Default()
+	}
+	return redactLogger(l, p.secrets)
+}
+
+// errAttr returns the attribute describing err.
+func errAttr(err error) slog.Attr {
+	return slog.String(errorKey, err.Error())
+}
+
+// log returns the stage logger, which is set when the stage is run.
+func (stage *Stage) log() *slog.Logger {
+	if stage.logger == nil {
+		return slog.Default()
+	}
+	return stage.logger
+}
+
+// stepContext returns a context carrying the stage logger with the step
+// attribute.
+func (stage *Stage) stepContext(ctx context.Context, step string) context.Context {
+	return withLogger(ctx, stage.log().With(stepKey, step))
+}
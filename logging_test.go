@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestPipelineRun_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Stages:         []Stage{{Name: "a"}, {Name: "b"}},
+		Logger:         slog.New(slog.NewJSONHandler(&buf, nil)),
+		Runtime:        &fakeRuntime{exitCodes: map[string]int{"b": 1}},
+	}
+	p.Run()
+
+	out := buf.String()
+	var records []map[string]any
+	s := bufio.NewScanner(&buf)
+	for s.Scan() {
+		var r map[string]any
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			t.Fatalf("invalid record %q: %q", s.Text(), err)
+		}
+		if r[pipelineKey] != "test" {
+			t.Errorf("want pipeline attribute in record %q", s.Text())
+		}
+		records = append(records, r)
+	}
+	// find returns the record with the message and attribute values.
+	find := func(msg string, attrs map[string]any) map[string]any {
+		for _, r := range records {
+			if r["msg"] != msg {
+				continue
+			}
+			match := true
+			for k, v := range attrs {
+				if r[k] != v {
+					match = false
+				}
+			}
+			if match {
+				return r
+			}
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		desc  string
+		msg   string
+		attrs map[string]any
+	}{
+		{"Setup", "step finished", map[string]any{stageKey: "a", stepKey: "setup", containerIDKey: "a"}},
+		{"Build", "step finished", map[string]any{stageKey: "a", stepKey: "build", exitStatusKey: 0.0}},
+		{"Run", "step finished", map[string]any{stageKey: "a", stepKey: "run", exitStatusKey: 0.0}},
+		{"RunFailed", "step failed", map[string]any{stageKey: "b", stepKey: "run", exitStatusKey: 1.0, "level": "ERROR"}},
+		{"Stage", "stage executed", map[string]any{stageKey: "a", statusKey: "OK"}},
+		{"Pipeline", "pipeline failed", map[string]any{statusKey: "Run Error"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			r := find(tc.msg, tc.attrs)
+			if r == nil {
+				t.Fatalf("want record %q with %v, got none in:\n%s", tc.msg, tc.attrs, out)
+			}
+			if _, ok := r[durationKey]; !ok {
+				t.Errorf("want duration in record %v", r)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package executor
+
+import "strings"
+
+// MountType is the kind of filesystem mounted in a container.
+type MountType string
+
+const (
+	// BindMount mounts a directory or file of the host.
+	BindMount MountType = "bind"
+	// VolumeMount mounts a named volume. Volumes of the pipeline, stage and
+	// service mounts are created in the pipeline setup and removed in its
+	// teardown.
+	VolumeMount MountType = "volume"
+	// TmpfsMount mounts a filesystem kept in the host memory, which is
+	// discarded when the container exits.
+	TmpfsMount MountType = "tmpfs"
+)
+
+// mountTypes lists the known mount types.
+var mountTypes = []MountType{BindMount, VolumeMount, TmpfsMount}
+
+// Mount is a filesystem mounted in the stage containers, besides the shared
+// volume, e.g. a read-only cache of documents or a scratch directory.
+type Mount struct {
+	Type     MountType `json:"type" bson:"type,omitempty"`           // Kind of the mount: bind, volume or tmpfs.
+	Source   string    `json:"source" bson:"source,omitempty"`       // Absolute path of the host directory of bind mounts or name of the volume of volume mounts. Not used by tmpfs mounts.
+	Target   string    `json:"target" bson:"target,omitempty"`       // Absolute path of the mount in the container.
+	ReadOnly bool      `json:"read-only" bson:"read-only,omitempty"` // Whether the container can only read the mount.
+	Size     ByteSize  `json:"size" bson:"size,omitempty"`           // Size of tmpfs mounts, e.g. "256m". Not limited if not set.
+}
+
+// String returns the mount in the format of the --mount flag of docker run,
+// e.g. type=bind,source=/data,target=/data,readonly.
+func (m Mount) String() string {
+	fields := []string{"type=" + string(m.Type)}
+	if m.Type != TmpfsMount {
+		fields = append(fields, "source="+m.Source)
+	}
+	fields = append(fields, "target="+m.Target)
+	if m.ReadOnly {
+		fields = append(fields, "readonly")
+	}
+	if m.Type == TmpfsMount && m.Size != 0 {
+		fields = append(fields, "tmpfs-size="+m.Size.String())
+	}
+	return strings.Join(fields, ",")
+}
+
+// mergeMounts returns the default mounts followed by the mounts, which
+// replace the defaults with the same target.
+func mergeMounts(defaults, mounts []Mount) []Mount {
+	var merged []Mount
+	for _, d := range defaults {
+		replaced := false
+		for _, m := range mounts {
+			replaced = replaced || m.Target == d.Target
+		}
+		if !replaced {
+			merged = append(merged, d)
+		}
+	}
+	return append(merged, mounts...)
+}
+
+// volumes returns the names of the volumes of the pipeline, stage, error
+// handler and service mounts, which are created in the pipeline setup, in
+// order and without repetitions.
+func (p *Pipeline) volumes() []string {
+	var names []string
+	seen := make(map[string]bool)
+	mounts := append([]Mount(nil), p.Mounts...)
+	for _, s := range p.Stages {
+		mounts = append(mounts, s.Mounts...)
+	}
+	mounts = append(mounts, p.ErrorHandler.Mounts...)
+	for _, s := range p.Services {
+		mounts = append(mounts, s.Mounts...)
+	}
+	for _, m := range mounts {
+		if m.Type == VolumeMount && !seen[m.Source] {
+			seen[m.Source] = true
+			names = append(names, m.Source)
+		}
+	}
+	return names
+}
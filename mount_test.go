@@ -0,0 +1,59 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMount_String(t *testing.T) {
+	testCases := []struct {
+		m    Mount
+		want string
+	}{
+		{Mount{Type: BindMount, Source: "/data/pdfs", Target: "/pdfs", ReadOnly: true}, "type=bind,source=/data/pdfs,target=/pdfs,readonly"},
+		{Mount{Type: VolumeMount, Source: "scratch", Target: "/scratch"}, "type=volume,source=scratch,target=/scratch"},
+		{Mount{Type: TmpfsMount, Target: "/scratch", Size: 256 << 20}, "type=tmpfs,target=/scratch,tmpfs-size=256m"},
+		{Mount{Type: TmpfsMount, Target: "/scratch"}, "type=tmpfs,target=/scratch"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := tc.m.String(); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeMounts(t *testing.T) {
+	defaults := []Mount{
+		{Type: BindMount, Source: "/data/pdfs", Target: "/pdfs", ReadOnly: true},
+		{Type: VolumeMount, Source: "scratch", Target: "/scratch"},
+	}
+	mounts := []Mount{{Type: TmpfsMount, Target: "/scratch"}}
+	want := []Mount{defaults[0], mounts[0]}
+	got := mergeMounts(defaults, mounts)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+	// Stages may be set up more than once, e.g. when planning and running.
+	if again := mergeMounts(defaults, got); !reflect.DeepEqual(again, want) {
+		t.Errorf("want %+v merging twice, got %+v", want, again)
+	}
+}
+
+func TestPipelineVolumes(t *testing.T) {
+	p := Pipeline{
+		Mounts: []Mount{
+			{Type: VolumeMount, Source: "a", Target: "/a"},
+			{Type: BindMount, Source: "/b", Target: "/b"},
+			{Type: VolumeMount, Source: "a", Target: "/c"},
+			{Type: VolumeMount, Source: "d", Target: "/d"},
+		},
+		Stages:       []Stage{{Name: "coleta", Mounts: []Mount{{Type: VolumeMount, Source: "e", Target: "/e"}, {Type: VolumeMount, Source: "a", Target: "/a"}}}},
+		ErrorHandler: Stage{Name: "handler", Mounts: []Mount{{Type: VolumeMount, Source: "f", Target: "/f"}}},
+		Services:     []Service{{Name: "db", Mounts: []Mount{{Type: VolumeMount, Source: "g", Target: "/g"}}}},
+	}
+	if got, want := p.volumes(), []string{"a", "d", "e", "f", "g"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want volumes %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,23 @@
+package executor
+
+// Networks predefined by docker, which are never created by the pipeline.
+const (
+	// NoNetwork isolates the container from any network, e.g. for stages
+	// that must not access the internet.
+	NoNetwork = "none"
+	// BridgeNetwork is the docker default network.
+	BridgeNetwork = "bridge"
+	// HostNetwork shares the network of the host with the container.
+	HostNetwork = "host"
+)
+
+// predefinedNetworks lists the networks predefined by docker.
+var predefinedNetworks = []string{NoNetwork, BridgeNetwork, HostNetwork}
+
+// Network is an user-defined network created in the pipeline setup and
+// removed in its teardown. The containers in the same user-defined network
+// reach each other by name.
+type Network struct {
+	Name     string `json:"name" bson:"name,omitempty"`         // Name of the network.
+	Internal bool   `json:"internal" bson:"internal,omitempty"` // Whether the network is isolated from the outside, so its containers only reach each other.
+}
@@ -0,0 +1,309 @@
+package executor
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/dadosjusbr/executor/status"
+)
+
+// Observer is notified about the lifecycle of the pipeline execution, e.g. to
+// report its progress. Observers are registered in Pipeline.Observers and are
+// called synchronously, so they must not block. Stages running in parallel
+// may call them concurrently.
+type Observer interface {
+	// PipelineStarted is called before the pipeline is set up.
+	PipelineStarted(PipelineEvent)
+	// StageSetupStarted is called before the stage is set up, which is the
+	// beginning of its execution.
+	StageSetupStarted(StageEvent)
+	// StageSetupFinished is called after the stage is set up, with the error
+	// if it failed.
+	StageSetupFinished(StageEvent)
+	// StageBuildStarted is called before the stage image is built or pulled.
+	StageBuildStarted(StageEvent)
+	// StageBuildFinished is called after the stage image is built or pulled,
+	// with the error if it failed.
+	StageBuildFinished(StageEvent)
+	// StageRunStarted is called before the stage image is run.
+	StageRunStarted(StageEvent)
+	// StageRunFinished is called after the stage image is run, including its
+	// retries, with the error if it failed.
+	StageRunFinished(StageEvent)
+	// StageTeardownStarted is called before the stage is torn down. Failed
+	// stages are also torn down.
+	StageTeardownStarted(StageEvent)
+	// StageTeardownFinished is called after the stage is torn down, with the
+	// error if it failed. If the stage has not failed before, it is the end
+	// of its execution.
+	StageTeardownFinished(StageEvent)
+	// ErrorHandlerInvoked is called when a stage fails, before executing the
+	// error handler.
+	ErrorHandlerInvoked(ErrorHandlerEvent)
+	// PipelineFinished is called with the final pipeline result.
+	PipelineFinished(PipelineEvent)
+}
+
+// PipelineEvent describes the pipeline when it starts and finishes.
+type PipelineEvent struct {
+	Pipeline string         // Pipeline's name.
+	Time     time.Time      // Time of the event.
+	Result   PipelineResult // Pipeline result, which is final when the pipeline finishes.
+	Err      error          // Error setting up or tearing down the pipeline, if any.
+}
+
+// StageEvent describes a step of the stage execution.
+type StageEvent struct {
+	Pipeline string               // Pipeline's name.
+	Stage    string               // Stage's name.
+	Index    int                  // Stage position in the pipeline. It is -1 for the error handler.
+	Total    int                  // Number of stages in the pipeline.
+	Time     time.Time            // Time of the event.
+	Result   StageExecutionResult // Partial result of the stage, up to the event.
+	Err      error                // Error of the finished step, if any.
+	Duration time.Duration        // Duration of the finished step. Zero when the step starts.
+}
+
+// ErrorHandlerEvent describes the invocation of the error handler.
+type ErrorHandlerEvent struct {
+	Pipeline     string               // Pipeline's name.
+	Handler      string               // Error handler's name. Empty if the default error handling is used.
+	Time         time.Time            // Time of the event.
+	FailedResult StageExecutionResult // Result of the failed stage.
+}
+
+// NopObserver implements Observer ignoring all events. It can be embedded to
+// implement only some of the methods.
+type NopObserver struct{}
+
+func (NopObserver) PipelineStarted(PipelineEvent)         {}
+func (NopObserver) StageSetupStarted(StageEvent)          {}
+func (NopObserver) StageSetupFinished(StageEvent)         {}
+func (NopObserver) StageBuildStarted(StageEvent)          {}
+func (NopObserver) StageBuildFinished(StageEvent)         {}
+func (NopObserver) StageRunStarted(StageEvent)            {}
+func (NopObserver) StageRunFinished(StageEvent)           {}
+func (NopObserver) StageTeardownStarted(StageEvent)       {}
+func (NopObserver) StageTeardownFinished(StageEvent)      {}
+func (NopObserver) ErrorHandlerInvoked(ErrorHandlerEvent) {}
+func (NopObserver) PipelineFinished(PipelineEvent)        {}
+
+// LogObserver logs the pipeline progress using Logger. It is used when no
+// observer is registered. The records carry the pipeline, stage and step
+// attributes and, when the step finishes, its duration.
+type LogObserver struct {
+	Logger *slog.Logger // Defaults to slog.Default().
+}
+
+func (o LogObserver) PipelineStarted(e PipelineEvent) {
+	o.logger().Info("setting up pipeline", pipelineKey, e.Pipeline)
+}
+
+func (o LogObserver) StageSetupStarted(e StageEvent) {
+	if e.Index < 0 {
+		o.stageLogger(e).Info("executing error handler")
+	} else {
+		// 'Index+1' because the index starts from 0.
+		o.stageLogger(e).Info("executing stage", "position", e.Index+1, "total", e.Total)
+	}
+	o.started(e, setupStep)
+}
+
+func (o LogObserver) StageSetupFinished(e StageEvent) {
+	o.finished(e, setupStep)
+}
+
+func (o LogObserver) StageBuildStarted(e StageEvent) {
+	s := e.Result.Stage
+	o.started(e, buildStep, "dir", filepath.Join(s.BaseDir, s.Dir))
+}
+
+func (o LogObserver) StageBuildFinished(e StageEvent) {
+	o.finished(e, buildStep, exitStatusKey, e.Result.BuildResult.ExitStatus)
+}
+
+func (o LogObserver) StageRunStarted(e StageEvent) {
+	o.started(e, runStep)
+}
+
+func (o LogObserver) StageRunFinished(e StageEvent) {
+	o.finished(e, runStep, exitStatusKey, e.Result.RunResult.ExitStatus, "attempts", len(e.Result.Attempts))
+}
+
+func (o LogObserver) StageTeardownStarted(e StageEvent) {
+	o.started(e, teardownStep)
+}
+
+func (o LogObserver) StageTeardownFinished(e StageEvent) {
+	o.finished(e, teardownStep)
+	if e.Err == nil && e.Result.Status == status.OK {
+		o.stageLogger(e).Info("stage executed",
+			statusKey, status.Text(e.Result.Status),
+			durationKey, e.Time.Sub(e.Result.StartTime))
+	}
+}
+
+func (o LogObserver) ErrorHandlerInvoked(e ErrorHandlerEvent) {
+	handler := e.Handler
+	if handler == "" {
+		handler = "default"
+	}
+	o.logger().Warn("stage failed, calling error handler",
+		pipelineKey, e.Pipeline,
+		stageKey, e.FailedResult.Stage.Name,
+		statusKey, status.Text(e.FailedResult.Status),
+		"handler", handler)
+}
+
+func (o LogObserver) PipelineFinished(e PipelineEvent) {
+	logger := o.logger().With(
+		pipelineKey, e.Pipeline,
+		statusKey, status.Text(e.Result.Status),
+		durationKey, e.Time.Sub(e.Result.StartTime))
+	switch {
+	case e.Err != nil && e.Result.Status == status.SetupError:
+		logger.Error("error setting up pipeline", errAttr(e.Err))
+	case e.Err != nil:
+		logger.Error("error tearing down pipeline", errAttr(e.Err))
+	case e.Result.Status == status.OK:
+		logger.Info("pipeline executed")
+	default:
+		logger.Error("pipeline failed")
+	}
+}
+
+func (o LogObserver) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.Default()
+	}
+	return o.Logger
+}
+
+// stageLogger returns the logger with the attributes of the stage.
+func (o LogObserver) stageLogger(e StageEvent) *slog.Logger {
+	logger := o.logger().With(pipelineKey, e.Pipeline, stageKey, e.Stage)
+	if id := e.Result.Stage.ContainerID; id != "" {
+		logger = logger.With(containerIDKey, id)
+	}
+	if e.Result.CommitID != "" {
+		logger = logger.With(commitKey, e.Result.CommitID)
+	}
+	return logger
+}
+
+func (o LogObserver) started(e StageEvent, step string, args ...any) {
+	o.stageLogger(e).Info("step started", append([]any{stepKey, step}, args...)...)
+}
+
+func (o LogObserver) finished(e StageEvent, step string, args ...any) {
+	args = append([]any{stepKey, step, durationKey, e.Duration}, args...)
+	if e.Err != nil {
+		o.stageLogger(e).Error("step failed", append(args, errAttr(e.Err))...)
+		return
+	}
+	o.stageLogger(e).Info("step finished", args...)
+}
+
+// id returns the stage internal identification.
+func (e StageEvent) id() string {
+	return e.Pipeline + "/" + e.Stage
+}
+
+// multiObserver notifies every observer, in order.
+type multiObserver []Observer
+
+func (m multiObserver) PipelineStarted(e PipelineEvent) {
+	for _, o := range m {
+		o.PipelineStarted(e)
+	}
+}
+
+func (m multiObserver) StageSetupStarted(e StageEvent) {
+	for _, o := range m {
+		o.StageSetupStarted(e)
+	}
+}
+
+func (m multiObserver) StageSetupFinished(e StageEvent) {
+	for _, o := range m {
+		o.StageSetupFinished(e)
+	}
+}
+
+func (m multiObserver) StageBuildStarted(e StageEvent) {
+	for _, o := range m {
+		o.StageBuildStarted(e)
+	}
+}
+
+func (m multiObserver) StageBuildFinished(e StageEvent) {
+	for _, o := range m {
+		o.StageBuildFinished(e)
+	}
+}
+
+func (m multiObserver) StageRunStarted(e StageEvent) {
+	for _, o := range m {
+		o.StageRunStarted(e)
+	}
+}
+
+func (m multiObserver) StageRunFinished(e StageEvent) {
+	for _, o := range m {
+		o.StageRunFinished(e)
+	}
+}
+
+func (m multiObserver) StageTeardownStarted(e StageEvent) {
+	for _, o := range m {
+		o.StageTeardownStarted(e)
+	}
+}
+
+func (m multiObserver) StageTeardownFinished(e StageEvent) {
+	for _, o := range m {
+		o.StageTeardownFinished(e)
+	}
+}
+
+func (m multiObserver) ErrorHandlerInvoked(e ErrorHandlerEvent) {
+	for _, o := range m {
+		o.ErrorHandlerInvoked(e)
+	}
+}
+
+func (m multiObserver) PipelineFinished(e PipelineEvent) {
+	for _, o := range m {
+		o.PipelineFinished(e)
+	}
+}
+
+// observer returns the observer notifying all the pipeline observers, or a
+// LogObserver if none is registered.
+func (p *Pipeline) observer() Observer {
+	if len(p.Observers) == 0 {
+		return LogObserver{Logger: p.baseLogger()}
+	}
+	return multiObserver(p.Observers)
+}
+
+// event returns the event of the stage step with the partial result.
+func (stage *Stage) event(pipeline Pipeline, ser StageExecutionResult, err error) StageEvent {
+	return StageEvent{
+		Pipeline: pipeline.Name,
+		Stage:    stage.Name,
+		Index:    stage.index,
+		Total:    len(pipeline.Stages),
+		Time:     time.Now(),
+		Result:   ser,
+		Err:      err,
+	}
+}
+
+// finished returns the event of the stage step started at start.
+func (stage *Stage) finished(pipeline Pipeline, ser StageExecutionResult, start time.Time, err error) StageEvent {
+	e := stage.event(pipeline, ser, err)
+	e.Duration = e.Time.Sub(start)
+	return e
+}
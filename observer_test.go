@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dadosjusbr/executor/status"
+)
+
+// recorder records the events it observes.
+type recorder struct {
+	NopObserver
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(e string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) PipelineStarted(e PipelineEvent) { r.record("pipeline started") }
+func (r *recorder) StageSetupStarted(e StageEvent)  { r.record("setup " + e.Stage) }
+func (r *recorder) StageRunFinished(e StageEvent) {
+	if e.Err != nil {
+		r.record("run failed " + e.Stage)
+		return
+	}
+	r.record("run " + e.Stage)
+}
+func (r *recorder) StageTeardownFinished(e StageEvent) { r.record("teardown " + e.Stage) }
+func (r *recorder) ErrorHandlerInvoked(e ErrorHandlerEvent) {
+	r.record("error handler " + e.Handler + " for " + e.FailedResult.Stage.Name)
+}
+func (r *recorder) PipelineFinished(e PipelineEvent) {
+	r.record("pipeline finished " + status.Text(e.Result.Status))
+}
+
+func TestPipelineRun_Observers(t *testing.T) {
+	first, second := &recorder{}, &recorder{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Stages:         []Stage{{Name: "a"}, {Name: "b"}},
+		ErrorHandler:   Stage{Name: "handler"},
+		Observers:      []Observer{first, second},
+		Runtime:        &fakeRuntime{exitCodes: map[string]int{"b": 1}},
+	}
+	p.Run()
+	want := []string{
+		"pipeline started",
+		"setup a",
+		"run a",
+		"teardown a",
+		"setup b",
+		"run failed b",
+		"teardown b",
+		"error handler handler for b",
+		"setup handler",
+		"run handler",
+		"teardown handler",
+		"pipeline finished Run Error",
+	}
+	for _, r := range []*recorder{first, second} {
+		if got := strings.Join(r.events, "\n"); got != strings.Join(want, "\n") {
+			t.Errorf("want events:\n%s\ngot:\n%s", strings.Join(want, "\n"), got)
+		}
+	}
+}
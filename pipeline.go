@@ -1,10 +1,12 @@
 package executor
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"reflect"
@@ -12,7 +14,6 @@ import (
 
 	"github.com/dadosjusbr/executor/status"
 	"google.golang.org/protobuf/encoding/prototext"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -31,12 +32,34 @@ type Pipeline struct {
 	SkipVolumeDirCleanup bool              `json:"skip-volume-dir-cleanup" bson:"skip-volume-dir-cleanup,omitempt"` // Skip pipeline's volume setup. Useful for debugging long-running pipelines.
 	VolumeDir            string            `json:"volume-dir" bson:"volume-dir,omitempt"`                           // Pipeline's output directory. Shared accross all pipeline stages.
 	VolumeName           string            `json:"volume-name" bson:"volume-name,omitempt"`                         // Pipeline's name. Shared accross all pipeline stages.
+	DefaultTimeout       Duration          `json:"default-timeout" bson:"default-timeout,omitempty"`                // Default maximum duration of each stage, e.g. "1h". No timeout if not set.
+	MaxParallelism       int               `json:"max-parallelism" bson:"max-parallelism,omitempty"`                // Maximum number of stages executing at the same time. No limit if not set.
+	MaxOutputSize        int64             `json:"max-output-size" bson:"max-output-size,omitempty"`                // Maximum number of bytes of each stdout and stderr kept in memory. Larger outputs are written to files in OutputDir. Unbounded if not set.
+	OutputDir            string            `json:"output-dir" bson:"output-dir,omitempty"`                          // Directory of the files containing the outputs larger than MaxOutputSize. Defaults to the system temporary directory.
+	CheckpointDir        string            `json:"checkpoint-dir" bson:"checkpoint-dir,omitempty"`                  // Directory in which the execution state is saved after the successful stages, whenever no stage is running, allowing to resume the pipeline. Not saved if not set.
+	LogDir               string            `json:"log-dir" bson:"log-dir,omitempty"`                                // Directory in which the stdout and stderr of each stage are written while it is executed. Not written if not set.
+	DefaultGitAuth       GitAuth           `json:"default-git-auth" bson:"default-git-auth,omitempty"`              // Default credentials used to clone the stage repositories.
+	DefaultResources     Resources         `json:"default-resources" bson:"default-resources,omitempty"`            // Default limits of the host resources used by the stage containers.
+	Mounts               []Mount           `json:"mounts" bson:"mounts,omitempty"`                                  // Filesystems mounted in every stage container, besides the shared volume. The volumes of volume mounts are created in the setup and removed in the teardown.
+	Networks             []Network         `json:"networks" bson:"networks,omitempty"`                              // User-defined networks created in the setup and removed in the teardown, which stages can join by setting their network.
+	DefaultNetwork       string            `json:"default-network" bson:"default-network,omitempty"`                // Default network of the stage containers. The docker default bridge network if not set.
+	Services             []Service         `json:"services" bson:"services,omitempty"`                              // Long-running containers started in the setup, before any stage, and stopped in the teardown, e.g. a headless browser.
+	Secrets              []Secret          `json:"secrets" bson:"secrets,omitempty"`                                // Secrets the stages and services can receive as environment variables, read in the setup. Their values are redacted from the results and logs.
+	GitCacheDir          string            `json:"git-cache-dir" bson:"git-cache-dir,omitempty"`                    // Directory of the local git mirrors the stage repositories are cloned from, shared by the pipelines of the host. The stage clone-depth and single-branch do not apply to them. Cloned directly from the remote if not set.
+	Observers            []Observer        `json:"-" bson:"-"`                                                      // Observers notified about the pipeline execution. If none is registered, a LogObserver is used.
+	Runtime              ContainerRuntime  `json:"-" bson:"-"`                                                      // Container runtime used to build and run the stages. Defaults to DockerCLI.
+	OutputSinks          []OutputSink      `json:"-" bson:"-"`                                                      // Sinks receiving the stdout and stderr of the stages while they are executed.
+	Logger               *slog.Logger      `json:"-" bson:"-"`                                                      // Logger of the pipeline execution. Defaults to slog.Default().
+	SecretProvider       SecretProvider    `json:"-" bson:"-"`                                                      // Provider of the values of the secrets with provider set.
+
+	secrets *secretSet // Secrets of the running execution, redacted from its logs.
 }
 
 // PipelineResult represents the pipeline information and their results.
 type PipelineResult struct {
-	Name           string                 `json:"name" bson:"name,omitempty"`               // Name of pipeline.
-	StageResults   []StageExecutionResult `json:"stageResult" bson:"stageResult,omitempty"` // Results of stage execution.
+	Name           string                 `json:"name" bson:"name,omitempty"`                   // Name of pipeline.
+	StageResults   []StageExecutionResult `json:"stageResult" bson:"stageResult,omitempty"`     // Results of stage execution.
+	ServiceResults []ServiceResult        `json:"serviceResult" bson:"serviceResult,omitempty"` // Results of the services, in the order they were started.
 	SetupResult    string
 	TeardownResult string
 	StartTime      time.Time   `json:"start" bson:"start,omitempty"`   // Time at start of pipeline.
@@ -47,7 +70,16 @@ type PipelineResult struct {
 // Run executes the pipeline.
 // For each stage defined in the pipeline we execute the `docker build` and
 // `docker run`. If any of these two processes fail, we interrupt the flow
-// and the error handler is called. Here, we consider a failure when the
+// and the error handler is called. Stages already running are allowed to
+// finish, but no other stage is started.
+//
+// By default, stages run in the order they are defined and the stdout of a
+// stage is the stdin of the next one. If stages declare DependsOn, they form
+// a graph instead: a stage starts as soon as all its dependencies finish,
+// independent stages run concurrently (up to MaxParallelism) and stages with
+// more than one dependency receive a JSON array with the stdout of each
+// dependency, e.g. [{"stage": "a", "stdout": "..."}, {"stage": "b", "stdout": "..."}].
+// Stages without dependencies receive the data piped to the executor stdin. Here, we consider a failure when the
 // building or execution of the image returns a status other than 0 or
 // when an error is raised within the buildImage or runImage functions.
 //
@@ -71,218 +103,416 @@ type PipelineResult struct {
 // return the error message that occurred in the standard flow along with the
 // structure that describes all the pipeline execution information until that point.
 func (p *Pipeline) Run() PipelineResult {
-	result := PipelineResult{Name: p.Name, StartTime: time.Now()}
+	return p.RunContext(context.Background())
+}
+
+// RunContext executes the pipeline like Run, but stops the running stage,
+// killing its container, when ctx is done. The interrupted stage fails and
+// the stage teardown, the error handler and the pipeline teardown are
+// executed regardless of ctx.
+func (p *Pipeline) RunContext(ctx context.Context) PipelineResult {
+	return p.execute(ctx, nil, false)
+}
+
+// Resume executes the pipeline skipping the stages completed in the
+// checkpoint. The shared volume directory is restored from the checkpoint and
+// the saved stdout of the completed stages is used as input of the stages
+// depending on them.
+//
+// The pipeline is not resumed, failing with SetupError, if its definition or
+// the commit of a completed stage repo have changed since the checkpoint was
+// saved, unless force is true.
+func (p *Pipeline) Resume(cp *Checkpoint, force bool) PipelineResult {
+	return p.ResumeContext(context.Background(), cp, force)
+}
+
+// ResumeContext resumes the pipeline like Resume, handling ctx like RunContext.
+func (p *Pipeline) ResumeContext(ctx context.Context, cp *Checkpoint, force bool) PipelineResult {
+	return p.execute(ctx, cp, force)
+}
 
-	defer func(ser *PipelineResult) {
+// execute runs the pipeline, resuming it from cp if it is not nil.
+func (p *Pipeline) execute(ctx context.Context, cp *Checkpoint, force bool) (result PipelineResult) {
+	result = PipelineResult{Name: p.Name, StartTime: time.Now()}
+	// The secrets are read in the setup, but the logger, the observer and the
+	// outputs captured by the runtimes must already redact them.
+	p.secrets = &secretSet{}
+	defer func() { p.secrets = nil }()
+	logger := p.logger()
+	ctx = withSecrets(withLogger(ctx, logger), p.secrets)
+	// Tearing down and handling errors must happen even if ctx is done.
+	cleanupCtx := context.WithoutCancel(ctx)
+	obs := p.observer()
+	obs.PipelineStarted(PipelineEvent{Pipeline: p.Name, Time: result.StartTime, Result: result})
+
+	var err error
+	defer func() {
 		result.FinalTime = time.Now()
-	}(&result)
+		result.SetupResult = p.secrets.redact(result.SetupResult)
+		result.TeardownResult = p.secrets.redact(result.TeardownResult)
+		obs.PipelineFinished(PipelineEvent{Pipeline: p.Name, Time: result.FinalTime, Result: result, Err: err})
+	}()
 
-	log.Println()
-	log.Printf("# Setting up Pipeline %s\n", p.Name)
-	if err := p.setup(); err != nil {
+	// The hash must be computed before anything changes the definition.
+	hash, err := p.hash()
+	if err != nil {
 		result.SetupResult = fmt.Sprintf("Error in setup: %q", err)
 		result.Status = status.SetupError
-		log.Printf("# Error setting up pipeline %s:%v\n\n", p.Name, err)
 		return result
 	}
-	log.Printf("# Pipeline %s set up successfully!\n\n", p.Name)
+	graph, err := p.setup(ctx, cp, force, &result.ServiceResults)
+	if err != nil {
+		result.SetupResult = fmt.Sprintf("Error in setup: %q", err)
+		result.Status = status.SetupError
+		return result
+	}
+	logger.Info("pipeline set up")
+
+	// Completed stages are kept in the checkpoint in the order they finish.
+	checkpoint := &Checkpoint{PipelineHash: hash}
+	stdouts := make(map[int]stageInput)
+	completed := make(map[int]bool)
+	if cp != nil {
+		for _, sc := range cp.Stages {
+			if i := p.stageIndex(sc.Name); i >= 0 {
+				logger.Info("skipping stage completed in checkpoint", stageKey, sc.Name)
+				completed[i] = true
+				stdouts[i] = stageInput{data: sc.Stdout, file: sc.StdoutFile}
+				checkpoint.Stages = append(checkpoint.Stages, sc)
+			}
+		}
+	}
 
-	for index, stage := range p.Stages {
-		fmt.Printf("\n")
-		stdin := ""
-		if index == 0 {
-			// https://stackoverflow.com/a/38612652
-			// check if stdin has data and if it comes from a pipe.
-			fi, err := os.Stdin.Stat()
-			if err != nil {
-				log.Printf("Error verifying stdin: %q. Proceeding...\n", err)
+	input := readInput(logger)
+	limit := p.MaxParallelism
+	if limit <= 0 {
+		limit = len(p.Stages)
+	}
+	type stageOutcome struct {
+		index int
+		stage *Stage
+		ser   StageExecutionResult
+		err   error
+	}
+	outcomes := make(chan stageOutcome)
+	var ready []int
+	pending := make([]int, len(p.Stages)) // Number of dependencies not executed yet.
+	for i := range p.Stages {
+		if completed[i] {
+			continue
+		}
+		for _, parent := range graph.parents[i] {
+			if !completed[parent] {
+				pending[i]++
 			}
-			// only consumes data if it comes from a pipe.
-			if fi.Mode()&fs.ModeCharDevice == 0 {
-				in, err := io.ReadAll(os.Stdin)
-				if err != nil {
-					log.Printf("Error reading data from stdin: %q. Proceeding...\n", err)
-				} else {
-					stdin = string(in)
-				}
+		}
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	running := 0
+	failed := false
+	unsaved := false // Whether there are completed stages not saved in the checkpoint.
+	for {
+		// The checkpoint is only saved while no stage is running, so the
+		// snapshot of the shared volume is not torn by in-flight writes.
+		if unsaved && running == 0 {
+			unsaved = false
+			// Failing to save a checkpoint does not fail the pipeline.
+			if err := p.saveCheckpoint(checkpoint); err != nil {
+				logger.Warn("error saving checkpoint, proceeding", "dir", p.CheckpointDir, errAttr(err))
 			}
-		} else {
-			// 'index-1' is accessing the output from previous stage.
-			stdin = result.StageResults[index-1].RunResult.Stdout
 		}
-
-		// TODO: Move tearing down to the stage.
-		ser, err := stage.run(index, *p, stdin)
-		result.StageResults = append(result.StageResults, ser)
-		if err != nil {
-			// We don't want teardown the stage twice.
-			if ser.Status != status.TeardownError {
-				log.Printf("### Tearing down stage %s\n", stage.internalID)
-				if _, err := stage.teardown(); err != nil {
-					log.Printf("### Error tearing down stage %s:%v\n\n", stage.internalID, err)
-				} else {
-					log.Printf("### Stage %s tore down successfully!\n\n", stage.internalID)
-				}
+		// If there is an error, no other stage is started.
+		for !failed && len(ready) > 0 && running < limit {
+			index := ready[0]
+			ready = ready[1:]
+			stdin, err := graph.stdin(index, p.Stages, input, stdouts)
+			if err != nil {
+				logger.Warn("error building stage stdin, proceeding", stageKey, p.Stages[index].Name, errAttr(err))
 			}
-
-			// If the error handler stage fails, the pipeline simply logs and proceeed.
-			her, err := p.handleError(ser, result)
-			if err != nil && !reflect.ValueOf(p.ErrorHandler).IsZero() && her.Status != status.TeardownError {
-				log.Printf("### Tearing down stage %s\n", p.ErrorHandler.internalID)
-				if _, err := p.ErrorHandler.teardown(); err != nil {
-					log.Printf("### Error tearing down stage %s:%v\n\n", p.ErrorHandler.internalID, err)
-				} else {
-					log.Printf("### Stage %s tore down successfully!\n\n", p.ErrorHandler.internalID)
-				}
+			stage := p.Stages[index]
+			running++
+			go func() {
+				// TODO: Move tearing down to the stage.
+				ser, err := stage.run(ctx, index, *p, stdin)
+				outcomes <- stageOutcome{index, &stage, ser, err}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+		o := <-outcomes
+		running--
+		result.StageResults = append(result.StageResults, o.ser)
+		if o.err != nil {
+			p.stageFailed(cleanupCtx, o.stage, o.ser, &result)
+		}
+		if failed {
+			continue
+		}
+		result.Status = o.ser.Status
+		// If there is an error, stop the pipeline
+		if o.ser.Status != status.OK {
+			failed = true
+			continue
+		}
+		stdouts[o.index] = runOutput(o.ser.RunResult)
+		for _, c := range graph.children[o.index] {
+			if pending[c]--; pending[c] == 0 {
+				ready = insertSorted(ready, c)
 			}
-			result.StageResults = append(result.StageResults, her)
 		}
-		result.Status = ser.Status
+		if p.CheckpointDir != "" {
+			checkpoint.Stages = append(checkpoint.Stages, StageCheckpoint{
+				Name:       o.stage.Name,
+				Stdout:     o.ser.RunResult.Stdout,
+				StdoutFile: o.ser.RunResult.StdoutFile,
+				CommitID:   o.ser.CommitID,
+			})
+			unsaved = true
+		}
+	}
 
-		// If there is an error, stop the pipeline
-		if ser.Status != status.OK {
-			break
+	// The checkpoint is useless once the pipeline finished successfully.
+	if p.CheckpointDir != "" && result.Status == status.OK {
+		if err := p.removeCheckpoint(); err != nil {
+			logger.Warn("error removing checkpoint", "dir", p.CheckpointDir, errAttr(err))
 		}
 	}
 
-	log.Printf("# Tearing down pipeline %s\n", p.Name)
-	if err := p.teardown(); err != nil {
+	logger.Info("tearing down pipeline")
+	if err = p.teardown(cleanupCtx, result.ServiceResults); err != nil {
 		result.Status = status.TeardownError
 		result.TeardownResult = fmt.Sprintf("Error in teardown: %q", err)
-		log.Printf("# Error tearing down pipeline %s:%v\n\n", p.Name, err)
 		return result
 	}
-	log.Printf("# Pipeline %s tore down successfully!\n\n", p.Name)
+	logger.Info("pipeline torn down")
 	return result
 }
 
-func (p *Pipeline) setup() error {
-	log.Printf("Checking pipeline spec validation\n")
-	for _, s := range p.Stages {
-		if err := s.validateSpec(); err != nil {
-			return fmt.Errorf("Stage %s spec validation failed:%v", s.Name, err)
+// setup validates the pipeline spec, creates the shared volume, the volumes
+// of the mounts and the pipeline networks, starts the services, appending
+// their results to services, and returns the dependency graph of the stages.
+// When resuming from a checkpoint, it also checks whether the pipeline can be
+// resumed and restores the shared volume dir.
+func (p *Pipeline) setup(ctx context.Context, cp *Checkpoint, force bool, services *[]ServiceResult) (stageGraph, error) {
+	logger := loggerFrom(ctx)
+	logger.Info("validating pipeline spec")
+	if err := p.Validate(); err != nil {
+		return stageGraph{}, fmt.Errorf("invalid pipeline spec: %w", err)
+	}
+	graph, err := newStageGraph(p.Stages)
+	if err != nil {
+		return stageGraph{}, fmt.Errorf("invalid stage dependencies: %w", err)
+	}
+	if cp != nil {
+		logger.Info("checking whether the pipeline can be resumed from checkpoint")
+		if err := p.checkResume(ctx, cp, force); err != nil {
+			return stageGraph{}, err
+		}
+	}
+	if len(p.Secrets) > 0 {
+		logger.Info("reading secrets", "secrets", len(p.Secrets))
+		if err := p.secrets.resolve(ctx, p.Secrets, p.SecretProvider); err != nil {
+			return stageGraph{}, err
 		}
 	}
-	log.Printf("Spec validated successfully!\n")
 
+	// The pipeline is not torn down if the setup fails, so the volumes,
+	// networks and services created so far are removed, even if ctx is done.
+	cleanupCtx := context.WithoutCancel(ctx)
+	var undo []func()
+	fail := func(err error) (stageGraph, error) {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		return stageGraph{}, err
+	}
 	if p.VolumeDir == "" || p.VolumeName == "" {
-		log.Printf("volume-dir or volume-name not set, skipping shared volume setup.")
-		return nil
+		logger.Info("volume-dir or volume-name not set, skipping shared volume setup")
+	} else {
+		if err := p.setupVolume(ctx, cp); err != nil {
+			return stageGraph{}, err
+		}
+		undo = append(undo, func() {
+			if err := p.teardownVolume(cleanupCtx); err != nil {
+				logger.Warn("error removing shared volume", "volume", p.VolumeName, errAttr(err))
+			}
+		})
+	}
+	for _, v := range p.volumes() {
+		logger.Info("creating volume", "volume", v)
+		if err := p.runtime().CreateVolume(ctx, "", v); err != nil {
+			return fail(err)
+		}
+		undo = append(undo, func() {
+			if err := p.runtime().RemoveVolume(cleanupCtx, v); err != nil {
+				logger.Warn("error removing volume", "volume", v, errAttr(err))
+			}
+		})
+	}
+	for _, n := range p.Networks {
+		logger.Info("creating network", "network", n.Name, "internal", n.Internal)
+		if err := p.runtime().CreateNetwork(ctx, n.Name, n.Internal); err != nil {
+			return fail(err)
+		}
+		undo = append(undo, func() {
+			if err := p.runtime().RemoveNetwork(cleanupCtx, n.Name); err != nil {
+				logger.Warn("error removing network", "network", n.Name, errAttr(err))
+			}
+		})
 	}
+	undo = append(undo, func() {
+		for _, err := range p.stopServices(cleanupCtx, *services) {
+			logger.Warn("error stopping service", errAttr(err))
+		}
+	})
+	if err := p.startServices(ctx, services); err != nil {
+		return fail(err)
+	}
+	return graph, nil
+}
 
-	log.Printf("Setting up directory:%s\n", p.VolumeDir)
-	log.Printf("$ mkdir -m %d %s", dirPermission, p.VolumeDir)
+// setupVolume creates the shared volume and its dir, restoring it from the
+// checkpoint, if any.
+func (p *Pipeline) setupVolume(ctx context.Context, cp *Checkpoint) error {
+	logger := loggerFrom(ctx)
+	logger.Info("creating volume dir", cmdKey, fmt.Sprintf("mkdir -m %d %s", dirPermission, p.VolumeDir))
 	if err := os.MkdirAll(p.VolumeDir, dirPermission); err != nil {
 		return fmt.Errorf("error (re)creating shared dir(%s) with permissions(%d): %w", p.VolumeDir, dirPermission, err)
 	}
-	log.Printf("Directory %s created sucessfully!\n", p.VolumeDir)
+	if cp != nil {
+		logger.Info("restoring volume dir from checkpoint", "dir", p.VolumeDir)
+		if err := p.restoreVolume(cp); err != nil {
+			return err
+		}
+	}
 
-	log.Printf("Creating volume %s:%s\n", p.VolumeName, p.VolumeDir)
-	if err := createVolume(p.VolumeDir, p.VolumeName); err != nil {
-		return err
+	logger.Info("creating volume", "volume", p.VolumeName, "dir", p.VolumeDir)
+	return p.runtime().CreateVolume(ctx, p.VolumeDir, p.VolumeName)
+}
+
+// readInput returns the pipeline input, which is the data piped to the
+// executor stdin, if any.
+func readInput(logger *slog.Logger) string {
+	// https://stackoverflow.com/a/38612652
+	// check if stdin has data and if it comes from a pipe.
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		logger.Warn("error verifying stdin, proceeding", errAttr(err))
+		return ""
 	}
-	log.Printf("Volume %s:%s create sucessfully!\n", p.VolumeName, p.VolumeDir)
-	return nil
+	// only consumes data if it comes from a pipe.
+	if fi.Mode()&fs.ModeCharDevice != 0 {
+		return ""
+	}
+	in, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		logger.Warn("error reading data from stdin, proceeding", errAttr(err))
+		return ""
+	}
+	return string(in)
+}
+
+// stageFailed tears down the failed stage and calls the error handler,
+// appending its result to the pipeline result.
+func (p *Pipeline) stageFailed(ctx context.Context, stage *Stage, ser StageExecutionResult, result *PipelineResult) {
+	// We don't want teardown the stage twice.
+	if ser.Status != status.TeardownError {
+		p.teardownFailed(stage, ser)
+	}
+
+	// If the error handler stage fails, the pipeline simply logs and proceeed.
+	her, err := p.handleError(ctx, ser, *result)
+	if err != nil && !reflect.ValueOf(p.ErrorHandler).IsZero() && her.Status != status.TeardownError {
+		// The error handler is executed on a copy of p.ErrorHandler.
+		handler := her.Stage
+		p.teardownFailed(&handler, her)
+	}
+	result.StageResults = append(result.StageResults, her)
+}
+
+// teardownFailed tears down a failed stage. Errors are only reported.
+func (p *Pipeline) teardownFailed(stage *Stage, ser StageExecutionResult) {
+	obs := p.observer()
+	start := time.Now()
+	obs.StageTeardownStarted(stage.event(*p, ser, nil))
+	c, err := stage.teardown()
+	ser.TeardownResult = c
+	obs.StageTeardownFinished(stage.finished(*p, ser, start, err))
 }
 
-func (p *Pipeline) teardown() error {
+// teardown stops the services, collecting their results, and removes the
+// pipeline networks, the volumes of the mounts and the shared volume.
+// Everything is removed even if removing something else fails.
+func (p *Pipeline) teardown(ctx context.Context, services []ServiceResult) error {
+	logger := loggerFrom(ctx)
+	errs := p.stopServices(ctx, services)
+	for _, n := range p.Networks {
+		logger.Info("removing network", "network", n.Name)
+		if err := p.runtime().RemoveNetwork(ctx, n.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, v := range p.volumes() {
+		logger.Info("removing volume", "volume", v)
+		if err := p.runtime().RemoveVolume(ctx, v); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	if p.VolumeDir == "" || p.VolumeName == "" {
-		log.Printf("volume-dir or volume-name not set, skipping shared volume teardown.")
-		return nil
+		logger.Info("volume-dir or volume-name not set, skipping shared volume teardown")
+		return errors.Join(errs...)
 	}
+	return errors.Join(append(errs, p.teardownVolume(ctx))...)
+}
 
-	log.Printf("Removing volume %s:%s\n", p.VolumeName, p.VolumeDir)
-	if err := removeVolume(p.VolumeName); err != nil {
+// teardownVolume removes the shared volume and, unless SkipVolumeDirCleanup is
+// set, its dir.
+func (p *Pipeline) teardownVolume(ctx context.Context) error {
+	logger := loggerFrom(ctx)
+	logger.Info("removing volume", "volume", p.VolumeName, "dir", p.VolumeDir)
+	if err := p.runtime().RemoveVolume(ctx, p.VolumeName); err != nil {
 		return err
 	}
-	log.Printf("Volume %s:%s removed sucessfully!\n", p.VolumeName, p.VolumeDir)
 
 	if p.SkipVolumeDirCleanup {
-		log.Printf("Skipping removing volume directory")
+		logger.Info("skipping volume dir removal", "dir", p.VolumeDir)
 		return nil
 	}
-	log.Printf("Removing directory:%s\n", p.VolumeDir)
-	log.Printf("$ rm -rf %s", p.VolumeDir)
+	logger.Info("removing volume dir", cmdKey, "rm -rf "+p.VolumeDir)
 	if err := os.RemoveAll(p.VolumeDir); err != nil {
 		return fmt.Errorf("error removing volume dir(%s): %q", p.VolumeDir, err)
 	}
-	log.Printf("Directory %s removed sucessfully!\n", p.VolumeDir)
 	return nil
 }
 
-func (p *Pipeline) handleError(ser StageExecutionResult, result PipelineResult) (StageExecutionResult, error) {
+func (p *Pipeline) handleError(ctx context.Context, ser StageExecutionResult, result PipelineResult) (StageExecutionResult, error) {
 	handler := p.ErrorHandler
-	// TODO(danielfireman): make the whole pipeline use this proto
-	pDef := PipelineDef{
-		Name:                 p.Name,
-		DefaultBaseDir:       p.DefaultBaseDir,
-		DefaultBuildEnv:      p.DefaultBuildEnv,
-		DefaultRunEnv:        p.DefaultRunEnv,
-		VolumeDir:            p.VolumeDir,
-		SkipVolumeDirCleanup: p.SkipVolumeDirCleanup,
-		ErrorHander:          stage2stageDef(p.ErrorHandler),
-	}
-	for _, s := range p.Stages {
-		pDef.Stages = append(pDef.Stages, stage2stageDef(s))
-	}
-	pExec := PipelineExecution{
-		Pipeline:         &pDef,
-		SetupErrorMsg:    result.SetupResult,
-		TeardownErrorMsg: result.TeardownResult,
-	}
-	for _, s := range result.StageResults {
-		pExec.Results = append(pExec.Results, &StageExecution{
-			StartTime:   timestamppb.New(s.StartTime),
-			FinishTime:  timestamppb.New(s.FinalTime),
-			ContainerId: s.Stage.ContainerID,
-			CommitId:    s.CommitID,
-			Setup:       cmdResult2StepExec(s.BuildResult),
-			Build:       cmdResult2StepExec(s.BuildResult),
-			Run:         cmdResult2StepExec(s.RunResult),
-			Teardown:    cmdResult2StepExec(s.TeardownResult),
-			Status:      StageExecution_Status(s.Status),
-		})
-	}
-	stdin, err := prototext.Marshal(&pExec)
+	p.observer().ErrorHandlerInvoked(ErrorHandlerEvent{
+		Pipeline:     p.Name,
+		Handler:      handler.Name,
+		Time:         time.Now(),
+		FailedResult: ser,
+	})
+	logger := loggerFrom(ctx)
+	stdin, err := prototext.Marshal(NewPipelineExecution(*p, result))
 	if err != nil {
-		log.Printf("### Error marshaling execution result for default error handling:%s. Skipping default error handling.\n\n", string(stdin))
+		logger.Error("error marshaling execution result, skipping error handling", errAttr(err))
 		return StageExecutionResult{}, err
 	}
+	// The results are redacted where they are produced, but the error
+	// messages of the pipeline may still contain secrets.
+	stdin = []byte(p.secrets.redact(string(stdin)))
 
 	// NOTE: reflect about making the default error handler: should it become a normal stage?
+	// The default error handling logs the information about the last stage execution.
 	if reflect.ValueOf(handler).IsZero() {
-		log.Printf("### Executing default error handling. Printing information about last stage execution:\n\n")
-		log.Printf("%s\n", string(stdin))
-		log.Printf("### Default error handling stage executed successfully!\n\n")
+		logger.Error("stage failed", stageKey, ser.Stage.Name, statusKey, status.Text(ser.Status), "execution", string(stdin))
 		return StageExecutionResult{Status: status.OK}, nil
 	}
-	return handler.run(-1, *p, string(stdin))
-}
-
-func cmdResult2StepExec(r CmdResult) *StepExecution {
-	return &StepExecution{
-		Stdin:      r.Stdin,
-		Stdout:     r.Stdout,
-		Stderr:     r.Stderr,
-		Cmd:        r.Cmd,
-		CmdDir:     r.Cmd,
-		StatusCode: int32(r.ExitStatus),
-		Env:        r.Env,
-		StartTime:  timestamppb.New(r.StartTime),
-		FinishTime: timestamppb.New(r.FinishTime),
-	}
-}
-
-func stage2stageDef(s Stage) *StageDef {
-	return &StageDef{
-		Name:              s.Name,
-		Dir:               s.Dir,
-		BaseDir:           s.BaseDir,
-		Repo:              s.Repo,
-		RepoVersionEnvVar: s.RepoVersionEnvVar,
-		BuildEnv:          s.BuildEnv,
-		RunEnv:            s.RunEnv,
-	}
+	return handler.run(ctx, -1, *p, stageInput{data: string(stdin)})
 }
 
 // handleError is responsible for build and run the stage ErrorHandler
@@ -0,0 +1,978 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadosjusbr/executor/status"
+)
+
+func TestMain(m *testing.M) {
+	// Pipeline.Run consumes os.Stdin when it comes from a pipe.
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		panic(err)
+	}
+	os.Stdin = f
+	os.Exit(m.Run())
+}
+
+// fakeRuntime is an in-memory ContainerRuntime. Each run appends the image
+// name and the values of its secrets to its stdin, writing the result to
+// stdout, and exits with the code configured for the image. Flaky
+// images exit with ConnectionError the configured number of times before
+// succeeding. The image "hang" runs until the context is done and the image
+// "oom" is killed for exceeding its memory limit.
+type fakeRuntime struct {
+	exitCodes map[string]int
+	flaky     map[string]int
+	calls     []string
+	volumes   map[string]string
+	resources map[string]Resources         // Resources of the last run, by image.
+	mounts    map[string][]Mount           // Mounts of the last run, by image.
+	networks  map[string]string            // Network of the last run, by image.
+	secrets   map[string]map[string]string // Secrets of the last run or service, by image.
+	mu        sync.Mutex
+}
+
+func (f *fakeRuntime) call(c string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, c)
+}
+
+func (f *fakeRuntime) Build(_ context.Context, spec BuildSpec) (CmdResult, error) {
+	f.call("build " + spec.Image)
+	return CmdResult{CmdDir: spec.Dir}, nil
+}
+
+func (f *fakeRuntime) Pull(_ context.Context, image, dir string, _ OutputLimit) (CmdResult, error) {
+	f.call("pull " + image)
+	return CmdResult{CmdDir: dir}, nil
+}
+
+func (f *fakeRuntime) ImageID(_ context.Context, image string) (string, error) {
+	return "sha256:" + image, nil
+}
+
+func (f *fakeRuntime) Run(ctx context.Context, spec RunSpec) (CmdResult, error) {
+	f.call("run " + spec.Image)
+	if spec.Image == "hang" {
+		<-ctx.Done()
+		return CmdResult{ExitStatus: -1}, ctx.Err()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.resources == nil {
+		f.resources = make(map[string]Resources)
+		f.mounts = make(map[string][]Mount)
+		f.networks = make(map[string]string)
+	}
+	f.resources[spec.Image] = spec.Resources
+	f.mounts[spec.Image] = spec.Mounts
+	f.networks[spec.Image] = spec.Network
+	f.secret(spec.Image, spec.Secrets)
+	if spec.Image == "oom" {
+		return CmdResult{ExitStatus: 137, OOMKilled: true}, nil
+	}
+	if f.flaky[spec.Image] > 0 {
+		f.flaky[spec.Image]--
+		return CmdResult{ExitStatus: int(status.ConnectionError)}, nil
+	}
+	in, err := spec.openStdin()
+	if err != nil {
+		return CmdResult{ExitStatus: -1}, err
+	}
+	defer in.Close()
+	stdin, _ := io.ReadAll(in)
+	out := string(stdin) + spec.Image
+	for _, k := range sortedKeys(spec.Secrets) {
+		out += " " + spec.Secrets[k]
+	}
+	stdout := newCapture(ctx, spec.Limit, spec.Image)
+	io.WriteString(withSink(stdout, spec.Stdout), out)
+	return CmdResult{
+		Stdin:      spec.Stdin,
+		Stdout:     stdout.String(),
+		StdoutFile: stdout.File(),
+		CmdDir:     spec.Dir,
+		ExitStatus: f.exitCodes[spec.Image],
+	}, nil
+}
+
+func (f *fakeRuntime) CreateVolume(_ context.Context, dir, name string) error {
+	f.call("create-volume " + name)
+	if f.volumes == nil {
+		f.volumes = make(map[string]string)
+	}
+	f.volumes[name] = dir
+	return nil
+}
+
+func (f *fakeRuntime) RemoveVolume(ctx context.Context, name string) error {
+	// Like the docker commands, removals fail once ctx is done.
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	f.call("remove-volume " + name)
+	delete(f.volumes, name)
+	return nil
+}
+
+func (f *fakeRuntime) CreateNetwork(_ context.Context, name string, internal bool) error {
+	if name == "broken" {
+		return fmt.Errorf("error creating network %s", name)
+	}
+	f.call(fmt.Sprintf("create-network %s internal=%t", name, internal))
+	return nil
+}
+
+func (f *fakeRuntime) RemoveNetwork(ctx context.Context, name string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	f.call("remove-network " + name)
+	return nil
+}
+
+func (f *fakeRuntime) StartService(_ context.Context, spec ServiceSpec) error {
+	f.call(fmt.Sprintf("start-service %s network=%s", spec.Alias, spec.Network))
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.secret(spec.Image, spec.Secrets)
+	return nil
+}
+
+// secret records the secrets received by the image. f.mu must be held.
+func (f *fakeRuntime) secret(image string, secrets map[string]string) {
+	if f.secrets == nil {
+		f.secrets = make(map[string]map[string]string)
+	}
+	f.secrets[image] = secrets
+}
+
+// ServiceIP returns the loopback address, so tests reach services listening
+// on the host.
+func (f *fakeRuntime) ServiceIP(_ context.Context, name, network string) (string, error) {
+	return "127.0.0.1", nil
+}
+
+// ExecService returns the exit code of the command name in exitCodes.
+func (f *fakeRuntime) ExecService(_ context.Context, name string, cmd []string) (int, error) {
+	return f.exitCodes[cmd[0]], nil
+}
+
+func (f *fakeRuntime) StopService(ctx context.Context, name string, limit OutputLimit) (CmdResult, error) {
+	if ctx.Err() != nil {
+		return CmdResult{ExitStatus: -1}, ctx.Err()
+	}
+	// Container names end with a generated suffix.
+	alias := name[:strings.LastIndex(name, "-")]
+	f.call("stop-service " + alias)
+	return CmdResult{Stdout: alias + " logs", ExitStatus: 143}, nil
+}
+
+func TestPipelineRun(t *testing.T) {
+	rt := &fakeRuntime{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		VolumeName:     "vol",
+		VolumeDir:      filepath.Join(t.TempDir(), "output"),
+		Stages: []Stage{
+			{Name: "First Stage"},
+			{Name: "second", Image: "ghcr.io/dadosjusbr/second"},
+		},
+		Runtime: rt,
+	}
+	result := p.Run()
+	if result.Status != status.OK {
+		t.Fatalf("want status OK, got %v", result.Status)
+	}
+	if len(result.StageResults) != 2 {
+		t.Fatalf("want 2 stage results, got %d", len(result.StageResults))
+	}
+	if got, want := result.StageResults[1].RunResult.Stdout, "first-stageghcr.io/dadosjusbr/second"; got != want {
+		t.Errorf("want last stdout %q, got %q", want, got)
+	}
+	want := []string{
+		"create-volume vol",
+		"build first-stage",
+		"run first-stage",
+		"pull ghcr.io/dadosjusbr/second",
+		"run ghcr.io/dadosjusbr/second",
+		"remove-volume vol",
+	}
+	if len(rt.calls) != len(want) {
+		t.Fatalf("want calls %q, got %q", want, rt.calls)
+	}
+	for i := range want {
+		if rt.calls[i] != want[i] {
+			t.Errorf("want call %q at %d, got %q", want[i], i, rt.calls[i])
+		}
+	}
+	if len(rt.volumes) != 0 {
+		t.Errorf("want all volumes removed, got %v", rt.volumes)
+	}
+}
+
+func TestPipelineRun_StageFailure(t *testing.T) {
+	rt := &fakeRuntime{exitCodes: map[string]int{"first": 7}}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Stages: []Stage{
+			{Name: "first"},
+			{Name: "second"},
+		},
+		Runtime: rt,
+	}
+	result := p.Run()
+	if result.Status != status.RunError {
+		t.Fatalf("want status %v, got %v", status.RunError, result.Status)
+	}
+	for _, c := range rt.calls {
+		if c == "build second" || c == "run second" {
+			t.Errorf("stage second should not be executed after a failure, got call %q", c)
+		}
+	}
+	if got := result.StageResults[0].RunResult.ExitStatus; got != 7 {
+		t.Errorf("want exit status 7, got %d", got)
+	}
+}
+
+func TestPipelineRun_RunSuccessCodes(t *testing.T) {
+	rt := &fakeRuntime{exitCodes: map[string]int{"first": 4}}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Stages: []Stage{
+			{Name: "first", RunSuccessCodes: []int{0, 4}},
+		},
+		Runtime: rt,
+	}
+	if result := p.Run(); result.Status != status.OK {
+		t.Fatalf("want status OK, got %v", result.Status)
+	}
+}
+
+func TestPipelineRunContext_Timeout(t *testing.T) {
+	rt := &fakeRuntime{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		DefaultTimeout: Duration(time.Hour),
+		Stages: []Stage{
+			{Name: "hang", Timeout: Duration(50 * time.Millisecond)},
+			{Name: "second"},
+		},
+		ErrorHandler: Stage{Name: "handler"},
+		Runtime:      rt,
+	}
+	result := p.RunContext(context.Background())
+	if result.Status != status.TimeoutError {
+		t.Fatalf("want status %v, got %v", status.TimeoutError, result.Status)
+	}
+	if got := rt.calls[len(rt.calls)-1]; got != "run handler" {
+		t.Errorf("want error handler executed, got last call %q", got)
+	}
+}
+
+func TestPipelineRun_OOM(t *testing.T) {
+	rt := &fakeRuntime{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		DefaultResources: Resources{
+			Memory:  64 << 20,
+			CPUs:    1,
+			Ulimits: []Ulimit{{Name: "nofile", Soft: 1024}, {Name: "nproc", Soft: 64}},
+		},
+		Stages: []Stage{
+			{Name: "first", Resources: Resources{CPUs: 0.5, Ulimits: []Ulimit{{Name: "nofile", Soft: 512, Hard: 1024}}}},
+			{Name: "oom", Resources: Resources{Memory: 1 << 30}},
+		},
+		Runtime: rt,
+	}
+	result := p.Run()
+	if result.Status != status.OOMError {
+		t.Fatalf("want status %v, got %v", status.OOMError, result.Status)
+	}
+	if !result.StageResults[1].RunResult.OOMKilled {
+		t.Errorf("want run result of oom stage OOM killed")
+	}
+	want := Resources{
+		Memory:  64 << 20,
+		CPUs:    0.5,
+		Ulimits: []Ulimit{{Name: "nofile", Soft: 512, Hard: 1024}, {Name: "nproc", Soft: 64}},
+	}
+	if got := rt.resources["first"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("want resources %+v, got %+v", want, got)
+	}
+	if got := rt.resources["oom"].Memory; got != 1<<30 {
+		t.Errorf("want memory %v, got %v", ByteSize(1<<30), got)
+	}
+}
+
+func TestPipelineRun_Mounts(t *testing.T) {
+	rt := &fakeRuntime{}
+	pdfs := t.TempDir()
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		VolumeName:     "vol",
+		VolumeDir:      filepath.Join(t.TempDir(), "output"),
+		Mounts: []Mount{
+			{Type: VolumeMount, Source: "scratch", Target: "/scratch"},
+			{Type: BindMount, Source: pdfs, Target: "/pdfs", ReadOnly: true},
+		},
+		Stages: []Stage{
+			{Name: "first", Mounts: []Mount{{Type: TmpfsMount, Target: "/scratch", Size: 64 << 20}}},
+			{Name: "second"},
+		},
+		Runtime: rt,
+	}
+	result := p.Run()
+	if result.Status != status.OK {
+		t.Fatalf("want status OK, got %v: %s", result.Status, result.SetupResult)
+	}
+	want := []Mount{
+		{Type: BindMount, Source: pdfs, Target: "/pdfs", ReadOnly: true},
+		{Type: TmpfsMount, Target: "/scratch", Size: 64 << 20},
+	}
+	if got := rt.mounts["first"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("want mounts %+v, got %+v", want, got)
+	}
+	if got := rt.mounts["second"]; !reflect.DeepEqual(got, p.Mounts) {
+		t.Errorf("want mounts %+v, got %+v", p.Mounts, got)
+	}
+	wantCalls := []string{"create-volume vol", "create-volume scratch", "remove-volume scratch", "remove-volume vol"}
+	var got []string
+	for _, c := range rt.calls {
+		if strings.HasSuffix(c, "-volume vol") || strings.HasSuffix(c, "-volume scratch") {
+			got = append(got, c)
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(wantCalls, ",") {
+		t.Errorf("want volume calls %q, got %q", wantCalls, got)
+	}
+	if len(rt.volumes) != 0 {
+		t.Errorf("want volumes removed, got %v", rt.volumes)
+	}
+}
+
+func TestPipelineRun_Networks(t *testing.T) {
+	rt := &fakeRuntime{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Networks:       []Network{{Name: "coleta"}, {Name: "isolated", Internal: true}},
+		DefaultNetwork: "coleta",
+		Stages: []Stage{
+			{Name: "first"},
+			{Name: "validation", Network: NoNetwork},
+		},
+		Runtime: rt,
+	}
+	result := p.Run()
+	if result.Status != status.OK {
+		t.Fatalf("want status OK, got %v: %s", result.Status, result.SetupResult)
+	}
+	if got := rt.networks["first"]; got != "coleta" {
+		t.Errorf("want first stage in network coleta, got %q", got)
+	}
+	if got := rt.networks["validation"]; got != NoNetwork {
+		t.Errorf("want validation stage in network none, got %q", got)
+	}
+	want := []string{
+		"create-network coleta internal=false",
+		"create-network isolated internal=true",
+		"build first",
+		"run first",
+		"build validation",
+		"run validation",
+		"remove-network coleta",
+		"remove-network isolated",
+	}
+	if strings.Join(rt.calls, ",") != strings.Join(want, ",") {
+		t.Errorf("want calls %q, got %q", want, rt.calls)
+	}
+}
+
+func TestPipelineRun_SetupFailure(t *testing.T) {
+	rt := &fakeRuntime{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		VolumeName:     "shared",
+		VolumeDir:      filepath.Join(t.TempDir(), "output"),
+		Mounts:         []Mount{{Type: VolumeMount, Source: "scratch", Target: "/scratch"}},
+		Networks:       []Network{{Name: "coleta"}, {Name: "broken"}},
+		Stages:         []Stage{{Name: "first"}},
+		Runtime:        rt,
+	}
+	result := p.Run()
+	if result.Status != status.SetupError {
+		t.Fatalf("want status %v, got %v", status.SetupError, result.Status)
+	}
+	want := []string{
+		"create-volume shared", "create-volume scratch", "create-network coleta internal=false",
+		"remove-network coleta", "remove-volume scratch", "remove-volume shared",
+	}
+	if strings.Join(rt.calls, ",") != strings.Join(want, ",") {
+		t.Errorf("want calls %q, got %q", want, rt.calls)
+	}
+	if _, err := os.Stat(p.VolumeDir); !os.IsNotExist(err) {
+		t.Errorf("want volume dir removed, got %v", err)
+	}
+}
+
+func TestPipelineRun_Services(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("want no error listening, got %q", err)
+	}
+	defer l.Close()
+	rt := &fakeRuntime{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Networks:       []Network{{Name: "coleta"}},
+		DefaultNetwork: "coleta",
+		Services: []Service{
+			{Name: "browser", Image: "chromium", Ready: ReadinessCheck{Port: l.Addr().(*net.TCPAddr).Port}},
+			{Name: "db", Image: "postgres", Network: HostNetwork, Ready: ReadinessCheck{Command: []string{"pg_isready"}}},
+		},
+		Stages:  []Stage{{Name: "first"}},
+		Runtime: rt,
+	}
+	result := p.Run()
+	if result.Status != status.OK {
+		t.Fatalf("want status OK, got %v: %s", result.Status, result.SetupResult)
+	}
+	want := []string{
+		"create-network coleta internal=false",
+		"pull chromium",
+		"start-service browser network=coleta",
+		"pull postgres",
+		"start-service db network=host",
+		"build first",
+		"run first",
+		"stop-service db",
+		"stop-service browser",
+		"remove-network coleta",
+	}
+	if strings.Join(rt.calls, ",") != strings.Join(want, ",") {
+		t.Errorf("want calls %q, got %q", want, rt.calls)
+	}
+	if len(result.ServiceResults) != 2 {
+		t.Fatalf("want 2 service results, got %d", len(result.ServiceResults))
+	}
+	for _, sr := range result.ServiceResults {
+		if sr.ReadyTime.IsZero() {
+			t.Errorf("want service %s ready", sr.Service.Name)
+		}
+		if want := sr.Service.Name + " logs"; sr.RunResult.Stdout != want || sr.RunResult.ExitStatus != 143 {
+			t.Errorf("want service %s logs %q and exit status 143, got %q and %d", sr.Service.Name, want, sr.RunResult.Stdout, sr.RunResult.ExitStatus)
+		}
+	}
+	if got := result.ServiceResults[0].Service.Network; got != "coleta" {
+		t.Errorf("want default network resolved, got %q", got)
+	}
+}
+
+func TestPipelineRun_ServiceNotReady(t *testing.T) {
+	rt := &fakeRuntime{exitCodes: map[string]int{"pg_isready": 2}}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Networks:       []Network{{Name: "coleta"}},
+		Services: []Service{{
+			Name:    "db",
+			Image:   "postgres",
+			Network: "coleta",
+			Ready: ReadinessCheck{
+				Command:  []string{"pg_isready"},
+				Interval: Duration(time.Millisecond),
+				Timeout:  Duration(20 * time.Millisecond),
+			},
+		}},
+		Stages:  []Stage{{Name: "first"}},
+		Runtime: rt,
+	}
+	result := p.Run()
+	if result.Status != status.SetupError {
+		t.Fatalf("want status %v, got %v", status.SetupError, result.Status)
+	}
+	want := []string{"create-network coleta internal=false", "pull postgres", "start-service db network=coleta", "stop-service db", "remove-network coleta"}
+	if strings.Join(rt.calls, ",") != strings.Join(want, ",") {
+		t.Errorf("want calls %q, got %q", want, rt.calls)
+	}
+	if len(result.ServiceResults) != 1 || !result.ServiceResults[0].ReadyTime.IsZero() || result.ServiceResults[0].RunResult.Stdout != "db logs" {
+		t.Errorf("want result of the stopped service not ready, got %+v", result.ServiceResults)
+	}
+}
+
+func TestPipelineRun_ServicePortNotReachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Networks:       []Network{{Name: "coleta", Internal: true}},
+		Services: []Service{{
+			Name:    "db",
+			Image:   "postgres",
+			Network: "coleta",
+			Ready:   ReadinessCheck{Port: port, Interval: Duration(time.Millisecond), Timeout: Duration(20 * time.Millisecond)},
+		}},
+		Stages:  []Stage{{Name: "first"}},
+		Runtime: &fakeRuntime{},
+	}
+	result := p.Run()
+	if result.Status != status.SetupError {
+		t.Fatalf("want status %v, got %v", status.SetupError, result.Status)
+	}
+	if !strings.Contains(result.SetupResult, "use a readiness command instead") {
+		t.Errorf("want setup error explaining the port check, got %s", result.SetupResult)
+	}
+}
+
+func TestPipelineRunContext_CanceledWhileServiceNotReady(t *testing.T) {
+	rt := &fakeRuntime{exitCodes: map[string]int{"pg_isready": 2}}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Networks:       []Network{{Name: "coleta"}},
+		Services: []Service{{
+			Name:    "db",
+			Image:   "postgres",
+			Network: "coleta",
+			Ready:   ReadinessCheck{Command: []string{"pg_isready"}, Interval: Duration(time.Millisecond)},
+		}},
+		Stages:  []Stage{{Name: "first"}},
+		Runtime: rt,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result := p.RunContext(ctx)
+	if result.Status != status.SetupError {
+		t.Fatalf("want status %v, got %v", status.SetupError, result.Status)
+	}
+	want := []string{"create-network coleta internal=false", "pull postgres", "start-service db network=coleta", "stop-service db", "remove-network coleta"}
+	if strings.Join(rt.calls, ",") != strings.Join(want, ",") {
+		t.Errorf("want calls %q, got %q", want, rt.calls)
+	}
+}
+
+func TestPipelineRun_Secrets(t *testing.T) {
+	t.Setenv("COLETOR_TOKEN", "t0k3n")
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("p4ssw0rd\n"), 0o600); err != nil {
+		t.Fatalf("want no error writing secret file, got %q", err)
+	}
+	var logs bytes.Buffer
+	rt := &fakeRuntime{exitCodes: map[string]int{"second": 1}}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		LogDir:         t.TempDir(),
+		Networks:       []Network{{Name: "coleta"}},
+		DefaultNetwork: "coleta",
+		Secrets: []Secret{
+			{Name: "TOKEN", Env: "COLETOR_TOKEN"},
+			{Name: "PASSWORD", File: file},
+			{Name: "API_KEY", Provider: "coleta/api-key"},
+		},
+		SecretProvider: SecretProviderFunc(func(_ context.Context, key string) (string, error) {
+			return "k3y-" + key, nil
+		}),
+		Services:     []Service{{Name: "browser", Image: "chromium", Secrets: []string{"API_KEY"}}},
+		Stages:       []Stage{{Name: "first", Secrets: []string{"TOKEN", "PASSWORD"}}, {Name: "second"}},
+		ErrorHandler: Stage{Name: "handler"},
+		Runtime:      rt,
+		Logger:       slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+	result := p.Run()
+	if result.Status != status.RunError {
+		t.Fatalf("want status %v, got %v: %s", status.RunError, result.Status, result.SetupResult)
+	}
+	want := map[string]string{"TOKEN": "t0k3n", "PASSWORD": "p4ssw0rd"}
+	if !reflect.DeepEqual(rt.secrets["first"], want) || rt.secrets["second"] != nil {
+		t.Errorf("want secrets %v passed only to stage first, got %v", want, rt.secrets)
+	}
+	if got := rt.secrets["chromium"]["API_KEY"]; got != "k3y-coleta/api-key" {
+		t.Errorf("want secret API_KEY passed to service, got %q", got)
+	}
+	if got := result.StageResults[0].RunResult.Stdout; got != "first [REDACTED] [REDACTED]" {
+		t.Errorf("want secrets redacted from stdout, got %q", got)
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("want no error marshaling result, got %q", err)
+	}
+	logFiles, err := filepath.Glob(filepath.Join(p.LogDir, "*"))
+	if err != nil || len(logFiles) == 0 {
+		t.Fatalf("want log files, got %v (%v)", logFiles, err)
+	}
+	outputs := map[string]string{"result": string(b), "logs": logs.String()}
+	for _, f := range logFiles {
+		b, _ := os.ReadFile(f)
+		outputs[f] = string(b)
+	}
+	for name, out := range outputs {
+		for _, secret := range []string{"t0k3n", "p4ssw0rd", "k3y-coleta/api-key"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("want secret %q redacted from %s, got %s", secret, name, out)
+			}
+		}
+	}
+}
+
+func TestPipelineRun_SecretNotFound(t *testing.T) {
+	rt := &fakeRuntime{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Secrets:        []Secret{{Name: "TOKEN", Env: "COLETOR_MISSING_TOKEN"}},
+		Stages:         []Stage{{Name: "first", Secrets: []string{"TOKEN"}}},
+		Runtime:        rt,
+	}
+	result := p.Run()
+	if result.Status != status.SetupError || !strings.Contains(result.SetupResult, "COLETOR_MISSING_TOKEN not set") {
+		t.Errorf("want setup error for missing secret, got %v: %s", result.Status, result.SetupResult)
+	}
+	if len(rt.calls) != 0 {
+		t.Errorf("want nothing executed, got calls %q", rt.calls)
+	}
+}
+
+func TestPipelineRunContext_Canceled(t *testing.T) {
+	rt := &fakeRuntime{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Stages:         []Stage{{Name: "hang"}},
+		Runtime:        rt,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	if result := p.RunContext(ctx); result.Status != status.RunError {
+		t.Fatalf("want status %v, got %v", status.RunError, result.Status)
+	}
+}
+
+func TestPipelineRun_DependsOn(t *testing.T) {
+	rt := &fakeRuntime{}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		MaxParallelism: 2,
+		Stages: []Stage{
+			{Name: "join", DependsOn: []string{"b", "a"}},
+			{Name: "a"},
+			{Name: "b"},
+		},
+		Runtime: rt,
+	}
+	result := p.Run()
+	if result.Status != status.OK {
+		t.Fatalf("want status OK, got %v", result.Status)
+	}
+	if len(result.StageResults) != 3 {
+		t.Fatalf("want 3 stage results, got %d", len(result.StageResults))
+	}
+	last := result.StageResults[2]
+	if last.Stage.Name != "join" {
+		t.Fatalf("want stage join executed last, got %s", last.Stage.Name)
+	}
+	if want := `[{"stage":"b","stdout":"b"},{"stage":"a","stdout":"a"}]`; last.RunResult.Stdin != want {
+		t.Errorf("want stdin %s, got %s", want, last.RunResult.Stdin)
+	}
+}
+
+func TestPipelineRun_DependsOnFailure(t *testing.T) {
+	rt := &fakeRuntime{exitCodes: map[string]int{"a": 1}}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		MaxParallelism: 1,
+		Stages: []Stage{
+			{Name: "a"},
+			{Name: "b"},
+			{Name: "c", DependsOn: []string{"a"}},
+		},
+		Runtime: rt,
+	}
+	result := p.Run()
+	if result.Status != status.RunError {
+		t.Fatalf("want status %v, got %v", status.RunError, result.Status)
+	}
+	for _, c := range rt.calls {
+		if c == "run b" || c == "run c" {
+			t.Errorf("no stage should start after a failure, got call %q", c)
+		}
+	}
+}
+
+func TestPipelineRun_Retry(t *testing.T) {
+	rt := &fakeRuntime{flaky: map[string]int{"flaky": 2, "broken": 5}}
+	retry := RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: Duration(time.Millisecond),
+		ExitCodes:      []int{int(status.ConnectionError)},
+	}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		Stages: []Stage{
+			{Name: "flaky", Retry: retry},
+			{Name: "broken", Retry: retry},
+		},
+		Runtime: rt,
+	}
+	result := p.Run()
+	if result.Status != status.RunError {
+		t.Fatalf("want status %v, got %v", status.RunError, result.Status)
+	}
+	flaky := result.StageResults[0]
+	if flaky.Status != status.OK || len(flaky.Attempts) != 3 {
+		t.Errorf("want stage flaky to succeed after 3 attempts, got status %v after %d", flaky.Status, len(flaky.Attempts))
+	}
+	broken := result.StageResults[1]
+	if len(broken.Attempts) != 3 {
+		t.Errorf("want 3 attempts of stage broken, got %d", len(broken.Attempts))
+	}
+	if broken.RunResult.ExitStatus != int(status.ConnectionError) {
+		t.Errorf("want run result of last attempt, got exit status %d", broken.RunResult.ExitStatus)
+	}
+}
+
+func TestPipelineResume(t *testing.T) {
+	rt := &fakeRuntime{exitCodes: map[string]int{"third": 1}}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		VolumeName:     "vol",
+		VolumeDir:      filepath.Join(t.TempDir(), "output"),
+		CheckpointDir:  t.TempDir(),
+		Stages: []Stage{
+			{Name: "first"},
+			{Name: "second"},
+			{Name: "third"},
+		},
+		Runtime: rt,
+	}
+	if result := p.Run(); result.Status != status.RunError {
+		t.Fatalf("want status %v, got %v", status.RunError, result.Status)
+	}
+	cp, err := LoadCheckpoint(p.CheckpointDir)
+	if err != nil {
+		t.Fatalf("want no error loading checkpoint, got %v", err)
+	}
+	if len(cp.Stages) != 2 || cp.Stages[1].Stdout != "firstsecond" {
+		t.Fatalf("want first and second stages in checkpoint, got %+v", cp.Stages)
+	}
+
+	changed := p
+	changed.Stages = append([]Stage{}, p.Stages...)
+	changed.Stages[2].RunEnv = map[string]string{"A": "B"}
+	if result := changed.Resume(cp, false); result.Status != status.SetupError {
+		t.Errorf("want status %v resuming changed pipeline, got %v", status.SetupError, result.Status)
+	}
+
+	rt.exitCodes = nil
+	rt.calls = nil
+	result := changed.Resume(cp, true)
+	if result.Status != status.OK {
+		t.Fatalf("want status OK, got %v", result.Status)
+	}
+	if len(result.StageResults) != 1 || result.StageResults[0].RunResult.Stdin != "firstsecond" {
+		t.Fatalf("want only stage third executed with saved stdout, got %+v", result.StageResults)
+	}
+	for _, c := range rt.calls {
+		if c == "run first" || c == "run second" {
+			t.Errorf("completed stages should not be executed, got call %q", c)
+		}
+	}
+	if _, err := LoadCheckpoint(p.CheckpointDir); err == nil {
+		t.Errorf("want checkpoint removed after success")
+	}
+}
+
+// slowRuntime runs the image "slow" only after the image "first" finished
+// and reports whether a checkpoint was saved in dir meanwhile.
+type slowRuntime struct {
+	*fakeRuntime
+	dir        string
+	firstDone  chan struct{}
+	checkpoint bool
+}
+
+func (r *slowRuntime) Run(ctx context.Context, spec RunSpec) (CmdResult, error) {
+	switch spec.Image {
+	case "first":
+		defer close(r.firstDone)
+	case "slow":
+		<-r.firstDone
+		time.Sleep(50 * time.Millisecond)
+		_, err := os.Stat(filepath.Join(r.dir, checkpointFile))
+		r.checkpoint = err == nil
+	}
+	return r.fakeRuntime.Run(ctx, spec)
+}
+
+func TestPipelineRun_CheckpointParallelStages(t *testing.T) {
+	rt := &slowRuntime{fakeRuntime: &fakeRuntime{exitCodes: map[string]int{"third": 1}}, dir: t.TempDir(), firstDone: make(chan struct{})}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		CheckpointDir:  rt.dir,
+		Stages: []Stage{
+			{Name: "first"},
+			{Name: "slow"},
+			{Name: "third", DependsOn: []string{"first", "slow"}},
+		},
+		Runtime: rt,
+	}
+	if result := p.Run(); result.Status != status.RunError {
+		t.Fatalf("want status %v, got %v", status.RunError, result.Status)
+	}
+	if rt.checkpoint {
+		t.Errorf("want no checkpoint saved while stage slow was running")
+	}
+	cp, err := LoadCheckpoint(p.CheckpointDir)
+	if err != nil {
+		t.Fatalf("want no error loading checkpoint, got %v", err)
+	}
+	if len(cp.Stages) != 2 || cp.Stages[0].Name != "first" || cp.Stages[1].Name != "slow" {
+		t.Errorf("want stages first and slow in checkpoint, got %+v", cp.Stages)
+	}
+}
+
+func TestPipelineResume_StdoutFile(t *testing.T) {
+	rt := &fakeRuntime{exitCodes: map[string]int{"third": 1}}
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		MaxOutputSize:  8,
+		OutputDir:      t.TempDir(),
+		CheckpointDir:  t.TempDir(),
+		Stages:         []Stage{{Name: "first"}, {Name: "second"}, {Name: "third"}},
+		Runtime:        rt,
+	}
+	if result := p.Run(); result.Status != status.RunError {
+		t.Fatalf("want status %v, got %v", status.RunError, result.Status)
+	}
+	// The files in the output dir may be gone when the pipeline is resumed.
+	if err := os.RemoveAll(p.OutputDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(p.OutputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := LoadCheckpoint(p.CheckpointDir)
+	if err != nil {
+		t.Fatalf("want no error loading checkpoint, got %v", err)
+	}
+	second := cp.Stages[1].StdoutFile
+	if second == nil || filepath.Dir(second.Path) != filepath.Join(p.CheckpointDir, checkpointStdoutDir) {
+		t.Fatalf("want stdout file of stage second in checkpoint dir, got %+v", second)
+	}
+
+	corrupted := *cp
+	corrupted.Stages = append([]StageCheckpoint{}, cp.Stages...)
+	corrupted.Stages[1].StdoutFile = &OutputFile{Path: second.Path, Size: second.Size, SHA256: strings.Repeat("0", 64)}
+	if result := p.Resume(&corrupted, true); result.Status != status.SetupError || !strings.Contains(result.SetupResult, "stdout of stage second") {
+		t.Errorf("want status %v resuming with corrupted stdout file, got %v: %s", status.SetupError, result.Status, result.SetupResult)
+	}
+
+	rt.exitCodes = nil
+	result := p.Resume(cp, false)
+	if result.Status != status.OK {
+		t.Fatalf("want status OK, got %v: %s", result.Status, result.SetupResult)
+	}
+	third := result.StageResults[0].RunResult
+	b, err := os.ReadFile(third.StdoutFile.Path)
+	if err != nil || string(b) != "firstsecondthird" {
+		t.Errorf("want saved stdout passed to stage third, got %q (%v)", b, err)
+	}
+}
+
+func TestPipelineRun_OutputSinks(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[string]string)
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		LogDir:         t.TempDir(),
+		Stages:         []Stage{{Name: "first"}, {Name: "second"}},
+		Runtime:        &fakeRuntime{},
+		OutputSinks: []OutputSink{SinkFunc(func(stage, step, stream string, data []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+			got[stage+" "+step+" "+stream] += string(data)
+		})},
+	}
+	if result := p.Run(); result.Status != status.OK {
+		t.Fatalf("want status OK, got %v", result.Status)
+	}
+	if got["second run stdout"] != "firstsecond" {
+		t.Errorf("want stdout of stage second sent to sink, got %v", got)
+	}
+	b, err := os.ReadFile(filepath.Join(p.LogDir, "second.stdout.log"))
+	if err != nil || string(b) != "firstsecond" {
+		t.Errorf("want stdout of stage second in log file, got %q (%v)", b, err)
+	}
+}
+
+func TestPipelineRun_MaxOutputSize(t *testing.T) {
+	p := Pipeline{
+		Name:           "test",
+		DefaultBaseDir: t.TempDir(),
+		MaxOutputSize:  8,
+		OutputDir:      t.TempDir(),
+		Stages:         []Stage{{Name: "first"}, {Name: "second"}, {Name: "third"}},
+		ErrorHandler:   Stage{Name: "handler"},
+		Runtime:        &fakeRuntime{exitCodes: map[string]int{"third": 1}},
+	}
+	result := p.Run()
+	if result.Status != status.RunError {
+		t.Fatalf("want status %v, got %v", status.RunError, result.Status)
+	}
+	second := result.StageResults[1].RunResult
+	if second.Stdout != "firstsec" || second.StdoutFile == nil || second.StdoutFile.Size != 11 {
+		t.Fatalf("want stdout of stage second spilled to file, got %q and %+v", second.Stdout, second.StdoutFile)
+	}
+	third := result.StageResults[2].RunResult
+	if third.StdinFile == nil || third.StdinFile.Path != second.StdoutFile.Path {
+		t.Errorf("want stdin of stage third read from file %+v, got %+v", second.StdoutFile, third.StdinFile)
+	}
+	b, err := os.ReadFile(third.StdoutFile.Path)
+	if err != nil || string(b) != "firstsecondthird" {
+		t.Errorf("want whole stdin passed to stage third, got %q (%v)", b, err)
+	}
+	handler := result.StageResults[3].RunResult
+	if strings.Contains(handler.Stdin, "firstsecond") || !strings.Contains(handler.Stdin, second.StdoutFile.Path) {
+		t.Errorf("want previews and file references in error handler input, got %q", handler.Stdin)
+	}
+}
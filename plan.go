@@ -0,0 +1,329 @@
+package executor
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// commitPlaceholder replaces the commit of the stage repo, which is only known
+// after cloning it, in the plan.
+const commitPlaceholder = "<commit>"
+
+// PipelinePlan describes what executing the pipeline would do, with the
+// defaults resolved exactly as in the execution. Commands are described as
+// the equivalent docker command-line client calls, even if other runtime is used.
+type PipelinePlan struct {
+	Name         string      `json:"name"`                     // Pipeline's name.
+	VolumeName   string      `json:"volume-name"`              // Name of the shared volume.
+	VolumeDir    string      `json:"volume-dir"`               // Local directory of the shared volume.
+	Setup        []string    `json:"setup"`                    // Commands executed when setting up the pipeline.
+	Stages       []StagePlan `json:"stages"`                   // Stages in the order they would be started, considering MaxParallelism is 1.
+	ErrorHandler *StagePlan  `json:"error-handler,omitempty"`  // Stage executed in case of failure, if any.
+	Teardown     []string    `json:"teardown"`                 // Commands executed when tearing down the pipeline.
+	Checkpoint   string      `json:"checkpoint-dir,omitempty"` // Directory in which the execution state is saved.
+}
+
+// StagePlan describes the execution of a stage.
+type StagePlan struct {
+	Name            string            `json:"name"`              // Stage's name.
+	DependsOn       []string          `json:"depends-on"`        // Stages whose stdout is the stdin of this one. The pipeline input if empty.
+	Repo            string            `json:"repo,omitempty"`    // Repository URL from where to clone the stage.
+	Ref             string            `json:"ref,omitempty"`     // Branch or tag of the repository checked out.
+	Commit          string            `json:"commit,omitempty"`  // Commit of the repository checked out.
+	BaseDir         string            `json:"base-dir"`          // Resolved base directory of the stage.
+	Dir             string            `json:"dir"`               // Directory in which the image is built.
+	ContainerID     string            `json:"container-id"`      // Name of the built image and prefix of the container name.
+	Image           string            `json:"image"`             // Image executed.
+	BuildEnv        map[string]string `json:"build-env"`         // Resolved build variables.
+	RunEnv          map[string]string `json:"run-env"`           // Resolved run variables.
+	VolumeName      string            `json:"volume-name"`       // Name of the shared volume.
+	VolumeDir       string            `json:"volume-dir"`        // Directory of the shared volume inside the container.
+	Mounts          []Mount           `json:"mounts,omitempty"`  // Resolved mounts of the container.
+	Network         string            `json:"network,omitempty"` // Network of the container. The docker default if empty.
+	Secrets         []string          `json:"secrets,omitempty"` // Names of the secrets passed to the container.
+	RunSuccessCodes []int             `json:"run-success-codes"` // Exit codes meaning success.
+	Timeout         Duration          `json:"timeout,omitempty"` // Maximum duration of the stage.
+	Retry           *RetryPolicy      `json:"retry,omitempty"`   // Retry policy of the run, if any.
+	Commands        []string          `json:"commands"`          // Commands executed, in order.
+}
+
+// Plan resolves the pipeline and its stages defaults and returns what
+// executing it would do, without cloning, building or running anything.
+// An error is returned if the pipeline specification is invalid.
+func (p *Pipeline) Plan() (PipelinePlan, error) {
+	// The plan can be reviewed where the stage sources are not available.
+	if errs := p.validate(false); len(errs) > 0 {
+		return PipelinePlan{}, fmt.Errorf("invalid pipeline spec: %w", errs)
+	}
+	graph, err := newStageGraph(p.Stages)
+	if err != nil {
+		return PipelinePlan{}, fmt.Errorf("invalid stage dependencies: %w", err)
+	}
+	cli, ok := p.Runtime.(DockerCLI)
+	if !ok {
+		cli = DockerCLI{}
+	}
+	plan := PipelinePlan{
+		Name:       p.Name,
+		VolumeName: p.VolumeName,
+		VolumeDir:  p.VolumeDir,
+		Checkpoint: p.CheckpointDir,
+	}
+	if p.VolumeDir != "" && p.VolumeName != "" {
+		plan.Setup = []string{
+			fmt.Sprintf("mkdir -m %d %s", dirPermission, p.VolumeDir),
+			cli.createVolumeCmd(p.VolumeDir, p.VolumeName),
+		}
+		plan.Teardown = []string{cli.removeVolumeCmd(p.VolumeName)}
+		if !p.SkipVolumeDirCleanup {
+			plan.Teardown = append(plan.Teardown, fmt.Sprintf("rm -rf %s", p.VolumeDir))
+		}
+	}
+	for _, v := range p.volumes() {
+		plan.Setup = append(plan.Setup, cli.createVolumeCmd("", v))
+	}
+	var teardown []string
+	for _, n := range p.Networks {
+		plan.Setup = append(plan.Setup, cli.createNetworkCmd(n.Name, n.Internal))
+		teardown = append(teardown, cli.removeNetworkCmd(n.Name))
+	}
+	for _, v := range p.volumes() {
+		teardown = append(teardown, cli.removeVolumeCmd(v))
+	}
+	// Services are stopped in the reverse order they are started, before
+	// removing their networks.
+	var stops []string
+	for _, svc := range p.Services {
+		name := svc.Name + "-<id>"
+		plan.Setup = append(plan.Setup, cli.pullCmd(svc.Image), strings.Join(cli.startServiceArgs(ServiceSpec{
+			Name:      name,
+			Alias:     svc.Name,
+			Image:     svc.Image,
+			Command:   svc.Command,
+			Env:       svc.Env,
+			Secrets:   unreadSecrets(svc.Secrets),
+			Network:   svc.network(*p),
+			Mounts:    svc.Mounts,
+			Resources: svc.Resources,
+		}), " "))
+		stops = append([]string{cli.stopServiceCmd(name), cli.serviceLogsCmd(name), cli.removeContainerCmd(name)}, stops...)
+	}
+	plan.Teardown = append(append(stops, teardown...), plan.Teardown...)
+
+	// Stages are listed in the order they would start with no parallelism.
+	pending := make([]int, len(p.Stages))
+	var ready []int
+	for i := range p.Stages {
+		if pending[i] = len(graph.parents[i]); pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		sp, err := p.stagePlan(p.Stages[i], cli)
+		if err != nil {
+			return PipelinePlan{}, err
+		}
+		for _, parent := range graph.parents[i] {
+			sp.DependsOn = append(sp.DependsOn, p.Stages[parent].Name)
+		}
+		plan.Stages = append(plan.Stages, sp)
+		for _, c := range graph.children[i] {
+			if pending[c]--; pending[c] == 0 {
+				ready = insertSorted(ready, c)
+			}
+		}
+	}
+
+	if !reflect.ValueOf(p.ErrorHandler).IsZero() {
+		sp, err := p.stagePlan(p.ErrorHandler, cli)
+		if err != nil {
+			return PipelinePlan{}, err
+		}
+		plan.ErrorHandler = &sp
+	}
+	return plan, nil
+}
+
+// stagePlan resolves the stage like Stage.setup does and describes the
+// commands executed by it.
+func (p *Pipeline) stagePlan(stage Stage, cli DockerCLI) (StagePlan, error) {
+	stage.setDefaults(*p)
+	var commands []string
+	if stage.Repo != "" {
+		cloneURL, repoPath, err := cloneTarget(stage.Repo, stage.BaseDir, stage.Dir)
+		if err != nil {
+			return StagePlan{}, fmt.Errorf("error in planning repo(%s) for stage %s: %w", stage.Repo, stage.Name, err)
+		}
+		clone, source := "git clone", cloneURL
+		if p.GitCacheDir != "" {
+			// The mirror is updated and the working copy cloned from it.
+			mirror := filepath.Join(p.GitCacheDir, mirrorName(cloneURL))
+			var refSpecs []string
+			for _, rs := range mirrorRefSpecs {
+				refSpecs = append(refSpecs, "'"+rs.String()+"'")
+			}
+			commands = append(commands, fmt.Sprintf("git init -q --bare %s && git --git-dir %s fetch --prune %s %s", mirror, mirror, cloneURL, strings.Join(refSpecs, " ")))
+			clone, source = "git clone --local", mirror
+		} else {
+			if stage.CloneDepth > 0 {
+				clone += fmt.Sprintf(" --depth %d", stage.CloneDepth)
+			}
+			if stage.SingleBranch {
+				clone += " --single-branch"
+			}
+		}
+		if stage.Submodules {
+			clone += " --recurse-submodules"
+		}
+		if stage.SparseCheckout {
+			clone += " --sparse"
+		}
+		if stage.Ref != "" {
+			clone += " --branch " + stage.Ref
+		}
+		commands = append(commands, fmt.Sprintf("%s %s %s", clone, source, repoPath))
+		if stage.SparseCheckout {
+			commands = append(commands, fmt.Sprintf("cd %s && git sparse-checkout set %s", repoPath, stage.repoSpec(*p).sparseDirs[0]))
+		}
+		if stage.Commit != "" {
+			commands = append(commands, fmt.Sprintf("cd %s && git checkout %s", repoPath, stage.Commit))
+		}
+		stage.setRepoVersion(commitPlaceholder)
+		stage.BaseDir = repoPath
+	}
+	stage.mergeDefaultEnv(*p)
+
+	dir := filepath.Join(stage.BaseDir, stage.Dir)
+	if stage.Image != "" {
+		commands = append(commands, fmt.Sprintf("cd %s && %s", dir, cli.pullCmd(stage.Image)))
+	} else {
+		commands = append(commands, fmt.Sprintf("cd %s && %s", dir, cli.buildCmd(BuildSpec{
+			Image: stage.ContainerID,
+			Env:   stage.BuildEnv,
+		})))
+	}
+	name := stage.ContainerID + "-<id>"
+	commands = append(commands, fmt.Sprintf("cd %s && %s", dir, cli.runCmd(RunSpec{
+		Image:      stage.image(),
+		VolumeName: stage.VolumeName,
+		VolumeDir:  stage.VolumeDir,
+		Env:        stage.RunEnv,
+		Secrets:    unreadSecrets(stage.Secrets),
+		Mounts:     stage.Mounts,
+		Network:    stage.Network,
+		Resources:  stage.Resources,
+	}, name)), cli.removeContainerCmd(name))
+	if stage.Repo != "" {
+		commands = append(commands, fmt.Sprintf("rm -rf %s", stage.BaseDir))
+	}
+
+	sp := StagePlan{
+		Name:            stage.Name,
+		Repo:            stage.Repo,
+		Ref:             stage.Ref,
+		Commit:          stage.Commit,
+		BaseDir:         stage.BaseDir,
+		Dir:             dir,
+		ContainerID:     stage.ContainerID,
+		Image:           stage.image(),
+		BuildEnv:        stage.BuildEnv,
+		RunEnv:          stage.RunEnv,
+		VolumeName:      stage.VolumeName,
+		VolumeDir:       stage.VolumeDir,
+		Mounts:          stage.Mounts,
+		Network:         stage.Network,
+		Secrets:         stage.Secrets,
+		RunSuccessCodes: stage.RunSuccessCodes,
+		Timeout:         stage.Timeout,
+		Commands:        commands,
+	}
+	if stage.Retry.MaxAttempts > 1 {
+		retry := stage.Retry
+		sp.Retry = &retry
+	}
+	return sp, nil
+}
+
+// String returns the plan in human-readable text.
+func (pp PipelinePlan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Pipeline %s\n", pp.Name)
+	if pp.Checkpoint != "" {
+		fmt.Fprintf(&b, "Checkpoint dir: %s\n", pp.Checkpoint)
+	}
+	writeCommands(&b, "", "Setup", pp.Setup)
+	for i, s := range pp.Stages {
+		fmt.Fprintf(&b, "\nStage %s [%d/%d]\n", s.Name, i+1, len(pp.Stages))
+		input := "pipeline input"
+		if len(s.DependsOn) > 0 {
+			input = "stdout of " + strings.Join(s.DependsOn, ", ")
+		}
+		s.writeText(&b, input)
+	}
+	if pp.ErrorHandler != nil {
+		fmt.Fprintf(&b, "\nError handler %s\n", pp.ErrorHandler.Name)
+		pp.ErrorHandler.writeText(&b, "pipeline execution (prototext)")
+	}
+	b.WriteString("\n")
+	writeCommands(&b, "", "Teardown", pp.Teardown)
+	return b.String()
+}
+
+func (sp StagePlan) writeText(b *strings.Builder, input string) {
+	fmt.Fprintf(b, "  Stdin: %s\n", input)
+	if sp.Repo != "" {
+		fmt.Fprintf(b, "  Repo: %s\n", sp.Repo)
+		if sp.Ref != "" {
+			fmt.Fprintf(b, "  Ref: %s\n", sp.Ref)
+		}
+		if sp.Commit != "" {
+			fmt.Fprintf(b, "  Commit: %s\n", sp.Commit)
+		}
+	}
+	fmt.Fprintf(b, "  Dir: %s\n", sp.Dir)
+	fmt.Fprintf(b, "  Image: %s\n", sp.Image)
+	if sp.VolumeName != "" && sp.VolumeDir != "" {
+		fmt.Fprintf(b, "  Volume: %s:%s\n", sp.VolumeName, sp.VolumeDir)
+	}
+	for _, m := range sp.Mounts {
+		fmt.Fprintf(b, "  Mount: %s\n", m)
+	}
+	if sp.Network != "" {
+		fmt.Fprintf(b, "  Network: %s\n", sp.Network)
+	}
+	if len(sp.Secrets) > 0 {
+		fmt.Fprintf(b, "  Secrets: %s\n", strings.Join(sp.Secrets, ", "))
+	}
+	fmt.Fprintf(b, "  Success codes: %v\n", sp.RunSuccessCodes)
+	if sp.Timeout > 0 {
+		fmt.Fprintf(b, "  Timeout: %s\n", sp.Timeout)
+	}
+	if sp.Retry != nil {
+		fmt.Fprintf(b, "  Max attempts: %d\n", sp.Retry.MaxAttempts)
+	}
+	writeCommands(b, "  ", "Commands", sp.Commands)
+}
+
+func writeCommands(b *strings.Builder, indent, title string, commands []string) {
+	if len(commands) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s%s:\n", indent, title)
+	for _, c := range commands {
+		fmt.Fprintf(b, "%s  $ %s\n", indent, c)
+	}
+}
+
+// unreadSecrets returns the named secrets without values, as the plan does
+// not read them. The commands only name the secrets anyway.
+func unreadSecrets(names []string) map[string]string {
+	secrets := make(map[string]string)
+	for _, n := range names {
+		secrets[n] = ""
+	}
+	return secrets
+}
@@ -0,0 +1,100 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPipelinePlan(t *testing.T) {
+	p := Pipeline{
+		Name:             "test",
+		DefaultBaseDir:   "/base",
+		DefaultBuildEnv:  map[string]string{"B": "default"},
+		DefaultRunEnv:    map[string]string{"R": "default", "S": "default"},
+		DefaultTimeout:   Duration(time.Hour),
+		VolumeName:       "vol",
+		VolumeDir:        "/output",
+		DefaultResources: Resources{Memory: 512 << 20, Ulimits: []Ulimit{{Name: "nofile", Soft: 1024, Hard: 2048}}},
+		Mounts:           []Mount{{Type: VolumeMount, Source: "scratch", Target: "/scratch"}},
+		Networks:         []Network{{Name: "coleta", Internal: true}},
+		DefaultNetwork:   "coleta",
+		Services:         []Service{{Name: "browser", Image: "chromium", Command: []string{"--port", "3000"}, Env: map[string]string{"TOKEN": "a b"}, Secrets: []string{"API_KEY"}, Resources: Resources{Memory: 1 << 30}}},
+		Secrets:          []Secret{{Name: "API_KEY", Env: "COLETOR_API_KEY"}},
+		Stages: []Stage{
+			{Name: "Coleta", Repo: "github.com/dadosjusbr/coletor", Ref: "v1.2", Commit: "3f2a9c1d", CloneDepth: 10, SingleBranch: true, Submodules: true, RepoVersionEnvVar: "GIT_COMMIT", RunEnv: map[string]string{"S": "stage"}, Secrets: []string{"API_KEY"}, Resources: Resources{CPUs: 1.5, TmpfsSize: 64 << 20}, Mounts: []Mount{{Type: BindMount, Source: "/data/pdfs", Target: "/pdfs", ReadOnly: true}}},
+			{Name: "store", Image: "ghcr.io/dadosjusbr/store", DependsOn: []string{"Coleta"}, RunSuccessCodes: []int{0, 4}},
+		},
+		ErrorHandler: Stage{Name: "handler", Dir: "handler"},
+	}
+	plan, err := p.Plan()
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	if len(plan.Stages) != 2 {
+		t.Fatalf("want 2 stages, got %d", len(plan.Stages))
+	}
+	coleta := plan.Stages[0]
+	if coleta.BaseDir != "/base/coletor" || coleta.ContainerID != "coleta" || coleta.Timeout != Duration(time.Hour) {
+		t.Errorf("want defaults resolved, got %+v", coleta)
+	}
+	if coleta.RunEnv["S"] != "stage" || coleta.RunEnv["R"] != "default" || coleta.RunEnv["GIT_COMMIT"] != commitPlaceholder {
+		t.Errorf("want run env merged, got %v", coleta.RunEnv)
+	}
+	want := []string{
+		"git clone --depth 10 --single-branch --recurse-submodules --branch v1.2 https://github.com/dadosjusbr/coletor /base/coletor",
+		"cd /base/coletor && git checkout 3f2a9c1d",
+		`cd /base/coletor && docker build --build-arg B="default" --build-arg GIT_COMMIT="<commit>" -t coleta .`,
+		`cd /base/coletor && docker run -i --name coleta-<id> -v vol:/output --mount type=volume,source=scratch,target=/scratch --mount type=bind,source=/data/pdfs,target=/pdfs,readonly --network coleta --memory 512m --cpus 1.5 --ulimit nofile=1024:2048 --tmpfs /tmp:size=64m --env GIT_COMMIT="<commit>" --env R="default" --env S="stage" --env API_KEY coleta`,
+		"docker rm -f coleta-<id>",
+		"rm -rf /base/coletor",
+	}
+	if strings.Join(coleta.Commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want commands %q, got %q", want, coleta.Commands)
+	}
+	store := plan.Stages[1]
+	if len(store.DependsOn) != 1 || store.DependsOn[0] != "Coleta" || store.Image != "ghcr.io/dadosjusbr/store" {
+		t.Errorf("want stage store pulling image after Coleta, got %+v", store)
+	}
+	if plan.ErrorHandler == nil || plan.ErrorHandler.Dir != "/base/handler" {
+		t.Errorf("want error handler planned, got %+v", plan.ErrorHandler)
+	}
+	wantSetup := []string{
+		fmt.Sprintf("mkdir -m %d /output", dirPermission),
+		"docker volume create --driver local --opt type=none --opt device=/output --opt o=bind --name=vol",
+		"docker volume create --driver local --name=scratch",
+		"docker network create --internal coleta",
+		"docker pull chromium",
+		"docker run --detach --name browser-<id> --network coleta --network-alias browser --memory 1g --env TOKEN=a b --env API_KEY chromium --port 3000",
+	}
+	if strings.Join(plan.Setup, "\n") != strings.Join(wantSetup, "\n") {
+		t.Errorf("want setup %q, got %q", wantSetup, plan.Setup)
+	}
+	wantTeardown := []string{
+		"docker stop browser-<id>", "docker logs browser-<id>", "docker rm -f browser-<id>",
+		"docker network rm coleta", "docker volume rm -f scratch", "docker volume rm -f vol", "rm -rf /output"}
+	if strings.Join(plan.Teardown, "\n") != strings.Join(wantTeardown, "\n") {
+		t.Errorf("want teardown %q, got %q", wantTeardown, plan.Teardown)
+	}
+	p.GitCacheDir = "/cache"
+	if plan, err = p.Plan(); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	mirror := "/cache/" + mirrorName("https://github.com/dadosjusbr/coletor")
+	want = []string{
+		"git init -q --bare " + mirror + " && git --git-dir " + mirror + " fetch --prune https://github.com/dadosjusbr/coletor '+refs/heads/*:refs/heads/*' '+refs/tags/*:refs/tags/*'",
+		"git clone --local --recurse-submodules --branch v1.2 " + mirror + " /base/coletor",
+	}
+	if got := plan.Stages[0].Commands[:2]; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want commands %q, got %q", want, got)
+	}
+	if text := plan.String(); !strings.Contains(text, "Stage store [2/2]") || !strings.Contains(text, "Stdin: stdout of Coleta") || !strings.Contains(text, "Mount: type=bind,source=/data/pdfs,target=/pdfs,readonly") || !strings.Contains(text, "Secrets: API_KEY") {
+		t.Errorf("want stages in text plan, got %s", text)
+	}
+
+	p.Stages[1].DependsOn = []string{"unknown"}
+	if _, err := p.Plan(); err == nil {
+		t.Errorf("want error planning invalid pipeline")
+	}
+}
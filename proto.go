@@ -0,0 +1,482 @@
+package executor
+
+import (
+	"time"
+
+	"github.com/dadosjusbr/executor/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The messages of structs.proto describe every field of the pipeline
+// definition and of its execution result. The functions below convert them
+// from and to the Go types without losing information, except for the
+// location and monotonic clock reading of the times and for the fields that
+// are not part of the definition (Observers, Runtime, OutputSinks and Logger).
+// Adding a field to one side only breaks the round-trip tests.
+
+// NewPipelineExecution returns the proto description of the pipeline and of
+// its execution result.
+func NewPipelineExecution(p Pipeline, result PipelineResult) *PipelineExecution {
+	pExec := PipelineExecution{
+		Pipeline:         NewPipelineDef(p),
+		Name:             result.Name,
+		SetupErrorMsg:    result.SetupResult,
+		TeardownErrorMsg: result.TeardownResult,
+		Status:           int32(result.Status),
+		StartTime:        timestamppb.New(result.StartTime),
+		FinishTime:       timestamppb.New(result.FinalTime),
+	}
+	for _, s := range result.StageResults {
+		pExec.Results = append(pExec.Results, stageResult2StageExec(s))
+	}
+	for _, s := range result.ServiceResults {
+		pExec.Services = append(pExec.Services, &ServiceExecution{
+			Service:   service2Def(s.Service),
+			Container: s.Container,
+			Pull:      cmdResult2StepExec(s.PullResult),
+			Run:       cmdResult2StepExec(s.RunResult),
+			ReadyTime: timestamppb.New(s.ReadyTime),
+		})
+	}
+	return &pExec
+}
+
+// FromPipelineExecution returns the pipeline and the execution result
+// described by the proto. It is the inverse of NewPipelineExecution.
+func FromPipelineExecution(e *PipelineExecution) (Pipeline, PipelineResult) {
+	result := PipelineResult{
+		Name:           e.GetName(),
+		SetupResult:    e.GetSetupErrorMsg(),
+		TeardownResult: e.GetTeardownErrorMsg(),
+		Status:         status.Code(e.GetStatus()),
+		StartTime:      timestamp2Time(e.GetStartTime()),
+		FinalTime:      timestamp2Time(e.GetFinishTime()),
+	}
+	for _, s := range e.GetResults() {
+		result.StageResults = append(result.StageResults, stageExec2StageResult(s))
+	}
+	for _, s := range e.GetServices() {
+		result.ServiceResults = append(result.ServiceResults, ServiceResult{
+			Service:    def2Service(s.GetService()),
+			Container:  s.GetContainer(),
+			PullResult: stepExec2CmdResult(s.GetPull()),
+			RunResult:  stepExec2CmdResult(s.GetRun()),
+			ReadyTime:  timestamp2Time(s.GetReadyTime()),
+		})
+	}
+	return FromPipelineDef(e.GetPipeline()), result
+}
+
+// NewPipelineDef returns the proto description of the pipeline definition.
+func NewPipelineDef(p Pipeline) *PipelineDef {
+	pDef := PipelineDef{
+		Name:                 p.Name,
+		DefaultBaseDir:       p.DefaultBaseDir,
+		DefaultBuildEnv:      p.DefaultBuildEnv,
+		DefaultRunEnv:        p.DefaultRunEnv,
+		VolumeDir:            p.VolumeDir,
+		VolumeName:           p.VolumeName,
+		SkipVolumeDirCleanup: p.SkipVolumeDirCleanup,
+		ErrorHander:          stage2stageDef(p.ErrorHandler),
+		DefaultTimeout:       duration2Proto(p.DefaultTimeout),
+		MaxParallelism:       int32(p.MaxParallelism),
+		MaxOutputSize:        p.MaxOutputSize,
+		OutputDir:            p.OutputDir,
+		CheckpointDir:        p.CheckpointDir,
+		LogDir:               p.LogDir,
+		DefaultGitAuth:       gitAuth2Def(p.DefaultGitAuth),
+		GitCacheDir:          p.GitCacheDir,
+		DefaultResources:     resources2Def(p.DefaultResources),
+		Mounts:               mounts2Defs(p.Mounts),
+		DefaultNetwork:       p.DefaultNetwork,
+	}
+	for _, n := range p.Networks {
+		pDef.Networks = append(pDef.Networks, &NetworkDef{Name: n.Name, Internal: n.Internal})
+	}
+	for _, s := range p.Services {
+		pDef.Services = append(pDef.Services, service2Def(s))
+	}
+	for _, s := range p.Secrets {
+		pDef.Secrets = append(pDef.Secrets, &SecretDef{Name: s.Name, Env: s.Env, File: s.File, Provider: s.Provider})
+	}
+	for _, s := range p.Stages {
+		pDef.Stages = append(pDef.Stages, stage2stageDef(s))
+	}
+	return &pDef
+}
+
+// FromPipelineDef returns the pipeline described by the proto. It is the
+// inverse of NewPipelineDef.
+func FromPipelineDef(d *PipelineDef) Pipeline {
+	p := Pipeline{
+		Name:                 d.GetName(),
+		DefaultBaseDir:       d.GetDefaultBaseDir(),
+		DefaultBuildEnv:      d.GetDefaultBuildEnv(),
+		DefaultRunEnv:        d.GetDefaultRunEnv(),
+		VolumeDir:            d.GetVolumeDir(),
+		VolumeName:           d.GetVolumeName(),
+		SkipVolumeDirCleanup: d.GetSkipVolumeDirCleanup(),
+		ErrorHandler:         stageDef2stage(d.GetErrorHander()),
+		DefaultTimeout:       proto2Duration(d.GetDefaultTimeout()),
+		MaxParallelism:       int(d.GetMaxParallelism()),
+		MaxOutputSize:        d.GetMaxOutputSize(),
+		OutputDir:            d.GetOutputDir(),
+		CheckpointDir:        d.GetCheckpointDir(),
+		LogDir:               d.GetLogDir(),
+		DefaultGitAuth:       def2GitAuth(d.GetDefaultGitAuth()),
+		GitCacheDir:          d.GetGitCacheDir(),
+		DefaultResources:     def2Resources(d.GetDefaultResources()),
+		Mounts:               defs2Mounts(d.GetMounts()),
+		DefaultNetwork:       d.GetDefaultNetwork(),
+	}
+	for _, n := range d.GetNetworks() {
+		p.Networks = append(p.Networks, Network{Name: n.GetName(), Internal: n.GetInternal()})
+	}
+	for _, s := range d.GetServices() {
+		p.Services = append(p.Services, def2Service(s))
+	}
+	for _, s := range d.GetSecrets() {
+		p.Secrets = append(p.Secrets, Secret{Name: s.GetName(), Env: s.GetEnv(), File: s.GetFile(), Provider: s.GetProvider()})
+	}
+	for _, s := range d.GetStages() {
+		p.Stages = append(p.Stages, stageDef2stage(s))
+	}
+	return p
+}
+
+func stage2stageDef(s Stage) *StageDef {
+	return &StageDef{
+		Name:              s.Name,
+		Dir:               s.Dir,
+		Image:             s.Image,
+		BaseDir:           s.BaseDir,
+		Repo:              s.Repo,
+		Ref:               s.Ref,
+		Commit:            s.Commit,
+		GitAuth:           gitAuth2Def(s.GitAuth),
+		CloneDepth:        int32(s.CloneDepth),
+		SingleBranch:      s.SingleBranch,
+		Submodules:        s.Submodules,
+		SparseCheckout:    s.SparseCheckout,
+		RepoVersionEnvVar: s.RepoVersionEnvVar,
+		BuildEnv:          s.BuildEnv,
+		RunEnv:            s.RunEnv,
+		ContainerId:       s.ContainerID,
+		VolumeName:        s.VolumeName,
+		VolumeDir:         s.VolumeDir,
+		RunSuccessCodes:   convertInts[int32](s.RunSuccessCodes),
+		Timeout:           duration2Proto(s.Timeout),
+		DependsOn:         s.DependsOn,
+		Resources:         resources2Def(s.Resources),
+		Mounts:            mounts2Defs(s.Mounts),
+		Network:           s.Network,
+		Secrets:           s.Secrets,
+		Retry: &RetryPolicyDef{
+			MaxAttempts:    int32(s.Retry.MaxAttempts),
+			InitialBackoff: duration2Proto(s.Retry.InitialBackoff),
+			MaxBackoff:     duration2Proto(s.Retry.MaxBackoff),
+			Jitter:         s.Retry.Jitter,
+			AttemptTimeout: duration2Proto(s.Retry.AttemptTimeout),
+			ExitCodes:      convertInts[int32](s.Retry.ExitCodes),
+			Statuses:       convertInts[int32](s.Retry.Statuses),
+		},
+	}
+}
+
+func stageDef2stage(d *StageDef) Stage {
+	r := d.GetRetry()
+	return Stage{
+		Name:              d.GetName(),
+		Dir:               d.GetDir(),
+		Image:             d.GetImage(),
+		BaseDir:           d.GetBaseDir(),
+		Repo:              d.GetRepo(),
+		Ref:               d.GetRef(),
+		Commit:            d.GetCommit(),
+		GitAuth:           def2GitAuth(d.GetGitAuth()),
+		CloneDepth:        int(d.GetCloneDepth()),
+		SingleBranch:      d.GetSingleBranch(),
+		Submodules:        d.GetSubmodules(),
+		SparseCheckout:    d.GetSparseCheckout(),
+		RepoVersionEnvVar: d.GetRepoVersionEnvVar(),
+		BuildEnv:          d.GetBuildEnv(),
+		RunEnv:            d.GetRunEnv(),
+		ContainerID:       d.GetContainerId(),
+		VolumeName:        d.GetVolumeName(),
+		VolumeDir:         d.GetVolumeDir(),
+		RunSuccessCodes:   convertInts[int](d.GetRunSuccessCodes()),
+		Timeout:           proto2Duration(d.GetTimeout()),
+		DependsOn:         d.GetDependsOn(),
+		Resources:         def2Resources(d.GetResources()),
+		Mounts:            defs2Mounts(d.GetMounts()),
+		Network:           d.GetNetwork(),
+		Secrets:           d.GetSecrets(),
+		Retry: RetryPolicy{
+			MaxAttempts:    int(r.GetMaxAttempts()),
+			InitialBackoff: proto2Duration(r.GetInitialBackoff()),
+			MaxBackoff:     proto2Duration(r.GetMaxBackoff()),
+			Jitter:         r.GetJitter(),
+			AttemptTimeout: proto2Duration(r.GetAttemptTimeout()),
+			ExitCodes:      convertInts[int](r.GetExitCodes()),
+			Statuses:       convertInts[status.Code](r.GetStatuses()),
+		},
+	}
+}
+
+func gitAuth2Def(a GitAuth) *GitAuthDef {
+	return &GitAuthDef{
+		Username:            a.Username,
+		PasswordEnv:         a.PasswordEnv,
+		PasswordFile:        a.PasswordFile,
+		SshUser:             a.SSHUser,
+		SshKeyFile:          a.SSHKeyFile,
+		SshKeyEnv:           a.SSHKeyEnv,
+		SshKeyPassphraseEnv: a.SSHKeyPassphraseEnv,
+		SshAgent:            a.SSHAgent,
+		SshKnownHosts:       a.SSHKnownHosts,
+	}
+}
+
+func def2GitAuth(d *GitAuthDef) GitAuth {
+	return GitAuth{
+		Username:            d.GetUsername(),
+		PasswordEnv:         d.GetPasswordEnv(),
+		PasswordFile:        d.GetPasswordFile(),
+		SSHUser:             d.GetSshUser(),
+		SSHKeyFile:          d.GetSshKeyFile(),
+		SSHKeyEnv:           d.GetSshKeyEnv(),
+		SSHKeyPassphraseEnv: d.GetSshKeyPassphraseEnv(),
+		SSHAgent:            d.GetSshAgent(),
+		SSHKnownHosts:       d.GetSshKnownHosts(),
+	}
+}
+
+func resources2Def(r Resources) *ResourcesDef {
+	d := &ResourcesDef{
+		Memory:     int64(r.Memory),
+		MemorySwap: int64(r.MemorySwap),
+		Cpus:       r.CPUs,
+		CpuShares:  r.CPUShares,
+		PidsLimit:  r.PidsLimit,
+		TmpfsSize:  int64(r.TmpfsSize),
+	}
+	for _, u := range r.Ulimits {
+		d.Ulimits = append(d.Ulimits, &UlimitDef{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+	return d
+}
+
+func def2Resources(d *ResourcesDef) Resources {
+	r := Resources{
+		Memory:     ByteSize(d.GetMemory()),
+		MemorySwap: ByteSize(d.GetMemorySwap()),
+		CPUs:       d.GetCpus(),
+		CPUShares:  d.GetCpuShares(),
+		PidsLimit:  d.GetPidsLimit(),
+		TmpfsSize:  ByteSize(d.GetTmpfsSize()),
+	}
+	for _, u := range d.GetUlimits() {
+		r.Ulimits = append(r.Ulimits, Ulimit{Name: u.GetName(), Soft: u.GetSoft(), Hard: u.GetHard()})
+	}
+	return r
+}
+
+func mounts2Defs(mounts []Mount) []*MountDef {
+	var defs []*MountDef
+	for _, m := range mounts {
+		defs = append(defs, &MountDef{
+			Type:     string(m.Type),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+			Size:     int64(m.Size),
+		})
+	}
+	return defs
+}
+
+func defs2Mounts(defs []*MountDef) []Mount {
+	var mounts []Mount
+	for _, d := range defs {
+		mounts = append(mounts, Mount{
+			Type:     MountType(d.GetType()),
+			Source:   d.GetSource(),
+			Target:   d.GetTarget(),
+			ReadOnly: d.GetReadOnly(),
+			Size:     ByteSize(d.GetSize()),
+		})
+	}
+	return mounts
+}
+
+func service2Def(s Service) *ServiceDef {
+	return &ServiceDef{
+		Name:      s.Name,
+		Image:     s.Image,
+		Command:   s.Command,
+		Env:       s.Env,
+		Network:   s.Network,
+		Secrets:   s.Secrets,
+		Mounts:    mounts2Defs(s.Mounts),
+		Resources: resources2Def(s.Resources),
+		Ready: &ReadinessCheckDef{
+			Port:     int32(s.Ready.Port),
+			Command:  s.Ready.Command,
+			Interval: duration2Proto(s.Ready.Interval),
+			Timeout:  duration2Proto(s.Ready.Timeout),
+		},
+	}
+}
+
+func def2Service(d *ServiceDef) Service {
+	return Service{
+		Name:      d.GetName(),
+		Image:     d.GetImage(),
+		Command:   d.GetCommand(),
+		Env:       d.GetEnv(),
+		Network:   d.GetNetwork(),
+		Secrets:   d.GetSecrets(),
+		Mounts:    defs2Mounts(d.GetMounts()),
+		Resources: def2Resources(d.GetResources()),
+		Ready: ReadinessCheck{
+			Port:     int(d.GetReady().GetPort()),
+			Command:  d.GetReady().GetCommand(),
+			Interval: proto2Duration(d.GetReady().GetInterval()),
+			Timeout:  proto2Duration(d.GetReady().GetTimeout()),
+		},
+	}
+}
+
+func stageResult2StageExec(s StageExecutionResult) *StageExecution {
+	var attempts []*StepExecution
+	for _, a := range s.Attempts {
+		attempts = append(attempts, cmdResult2StepExec(a))
+	}
+	return &StageExecution{
+		StartTime:   timestamppb.New(s.StartTime),
+		FinishTime:  timestamppb.New(s.FinalTime),
+		ContainerId: s.Stage.ContainerID,
+		CommitId:    s.CommitID,
+		ImageId:     s.ImageID,
+		Stage:       stage2stageDef(s.Stage),
+		Setup:       cmdResult2StepExec(s.SetupResult),
+		Build:       cmdResult2StepExec(s.BuildResult),
+		Run:         cmdResult2StepExec(s.RunResult),
+		Teardown:    cmdResult2StepExec(s.TeardownResult),
+		Status:      StageExecution_Status(s.Status),
+		Attempts:    attempts,
+	}
+}
+
+func stageExec2StageResult(e *StageExecution) StageExecutionResult {
+	stage := stageDef2stage(e.GetStage())
+	// Executions recorded before the stage definition was added only have
+	// the container ID.
+	if stage.ContainerID == "" {
+		stage.ContainerID = e.GetContainerId()
+	}
+	var attempts []CmdResult
+	for _, a := range e.GetAttempts() {
+		attempts = append(attempts, stepExec2CmdResult(a))
+	}
+	return StageExecutionResult{
+		Stage:          stage,
+		CommitID:       e.GetCommitId(),
+		ImageID:        e.GetImageId(),
+		StartTime:      timestamp2Time(e.GetStartTime()),
+		FinalTime:      timestamp2Time(e.GetFinishTime()),
+		SetupResult:    stepExec2CmdResult(e.GetSetup()),
+		BuildResult:    stepExec2CmdResult(e.GetBuild()),
+		RunResult:      stepExec2CmdResult(e.GetRun()),
+		TeardownResult: stepExec2CmdResult(e.GetTeardown()),
+		Attempts:       attempts,
+		Status:         status.Code(e.GetStatus()),
+	}
+}
+
+func cmdResult2StepExec(r CmdResult) *StepExecution {
+	return &StepExecution{
+		Stdin:      r.Stdin,
+		Stdout:     r.Stdout,
+		Stderr:     r.Stderr,
+		Cmd:        r.Cmd,
+		CmdDir:     r.CmdDir,
+		StatusCode: int32(r.ExitStatus),
+		Env:        r.Env,
+		StartTime:  timestamppb.New(r.StartTime),
+		FinishTime: timestamppb.New(r.FinishTime),
+		StdinFile:  outputFile2StreamFile(r.StdinFile),
+		StdoutFile: outputFile2StreamFile(r.StdoutFile),
+		StderrFile: outputFile2StreamFile(r.StderrFile),
+		OomKilled:  r.OOMKilled,
+	}
+}
+
+func stepExec2CmdResult(e *StepExecution) CmdResult {
+	return CmdResult{
+		Stdin:      e.GetStdin(),
+		Stdout:     e.GetStdout(),
+		Stderr:     e.GetStderr(),
+		Cmd:        e.GetCmd(),
+		CmdDir:     e.GetCmdDir(),
+		ExitStatus: int(e.GetStatusCode()),
+		Env:        e.GetEnv(),
+		StartTime:  timestamp2Time(e.GetStartTime()),
+		FinishTime: timestamp2Time(e.GetFinishTime()),
+		StdinFile:  streamFile2OutputFile(e.GetStdinFile()),
+		StdoutFile: streamFile2OutputFile(e.GetStdoutFile()),
+		StderrFile: streamFile2OutputFile(e.GetStderrFile()),
+		OOMKilled:  e.GetOomKilled(),
+	}
+}
+
+func outputFile2StreamFile(f *OutputFile) *StreamFile {
+	if f == nil {
+		return nil
+	}
+	return &StreamFile{Path: f.Path, Size: f.Size, Sha256: f.SHA256}
+}
+
+func streamFile2OutputFile(f *StreamFile) *OutputFile {
+	if f == nil {
+		return nil
+	}
+	return &OutputFile{Path: f.Path, Size: f.Size, SHA256: f.Sha256}
+}
+
+// timestamp2Time returns the time of the timestamp, or the zero time if it is
+// not set.
+func timestamp2Time(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
+
+// duration2Proto returns the proto duration, or nil if it is not set.
+func duration2Proto(d Duration) *durationpb.Duration {
+	if d == 0 {
+		return nil
+	}
+	return durationpb.New(time.Duration(d))
+}
+
+func proto2Duration(d *durationpb.Duration) Duration {
+	if d == nil {
+		return 0
+	}
+	return Duration(d.AsDuration())
+}
+
+// convertInts converts the elements of the slice, keeping it nil if it is nil.
+func convertInts[U, T ~int | ~int32](s []T) []U {
+	if s == nil {
+		return nil
+	}
+	out := make([]U, len(s))
+	for i, v := range s {
+		out[i] = U(v)
+	}
+	return out
+}
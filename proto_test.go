@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// fill sets every exported field of v, recursively, to a non-zero value.
+// Fields not part of the definition (json:"-") are kept unset.
+func fill(v reflect.Value, name string) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(name)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		v.SetInt(7)
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Float64:
+		v.SetFloat(0.5)
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 2, 2)
+		for i := 0; i < s.Len(); i++ {
+			fill(s.Index(i), name)
+		}
+		v.Set(s)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		e := reflect.New(v.Type().Elem()).Elem()
+		fill(e, name)
+		m.SetMapIndex(reflect.ValueOf("KEY"), e)
+		v.Set(m)
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem(), name)
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(time.Date(2023, 4, 5, 6, 7, 8, 9, time.UTC)))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.IsExported() && f.Tag.Get("json") != "-" {
+				fill(v.Field(i), f.Name)
+			}
+		}
+	}
+}
+
+// fillMessage sets every field of m, recursively, to a non-zero value.
+func fillMessage(m protoreflect.Message) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		switch {
+		case fd.IsMap():
+			m.Mutable(fd).Map().Set(protoreflect.ValueOfString("KEY").MapKey(), scalarValue(fd.MapValue()))
+		case fd.IsList():
+			l := m.Mutable(fd).List()
+			for j := 0; j < 2; j++ {
+				if fd.Message() != nil {
+					e := l.NewElement()
+					fillMessage(e.Message())
+					l.Append(e)
+				} else {
+					l.Append(scalarValue(fd))
+				}
+			}
+		case fd.Message() != nil:
+			fillMessage(m.Mutable(fd).Message())
+		default:
+			m.Set(fd, scalarValue(fd))
+		}
+	}
+}
+
+func scalarValue(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(string(fd.Name()))
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true)
+	case protoreflect.Int32Kind:
+		return protoreflect.ValueOfInt32(7)
+	case protoreflect.Int64Kind:
+		return protoreflect.ValueOfInt64(7)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(0.5)
+	case protoreflect.EnumKind:
+		return protoreflect.ValueOfEnum(1)
+	}
+	panic("unexpected field kind: " + fd.Kind().String())
+}
+
+func TestPipelineExecution_RoundTrip(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		fill   bool
+		encode bool
+	}{
+		{"Zero", false, false},
+		{"AllFields", true, false},
+		{"AllFieldsEncoded", true, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var p Pipeline
+			var r PipelineResult
+			if tc.fill {
+				fill(reflect.ValueOf(&p).Elem(), "")
+				fill(reflect.ValueOf(&r).Elem(), "")
+			}
+			e := NewPipelineExecution(p, r)
+			if tc.encode {
+				b, err := proto.Marshal(e)
+				if err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+				e = &PipelineExecution{}
+				if err := proto.Unmarshal(b, e); err != nil {
+					t.Fatalf("want no error, got %q", err)
+				}
+			}
+			gotP, gotR := FromPipelineExecution(e)
+			if !reflect.DeepEqual(p, gotP) {
+				t.Errorf("want %+v, got %+v", p, gotP)
+			}
+			if !reflect.DeepEqual(r, gotR) {
+				t.Errorf("want %+v, got %+v", r, gotR)
+			}
+		})
+	}
+}
+
+func TestPipelineExecution_ProtoRoundTrip(t *testing.T) {
+	want := &PipelineExecution{}
+	fillMessage(want.ProtoReflect())
+	got := NewPipelineExecution(FromPipelineExecution(want))
+	if !proto.Equal(want, got) {
+		t.Errorf("want %s, got %s", prototext.Format(want), prototext.Format(got))
+	}
+}
+
+func TestFromPipelineExecution_ContainerID(t *testing.T) {
+	// Executions without the stage definition only have the container ID.
+	_, r := FromPipelineExecution(&PipelineExecution{Results: []*StageExecution{{ContainerId: "coleta"}}})
+	if got := r.StageResults[0].Stage.ContainerID; got != "coleta" {
+		t.Errorf("want coleta, got %s", got)
+	}
+}
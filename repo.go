@@ -1,28 +1,72 @@
 package executor
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
 )
 
+// repoSpec describes the repository of a stage and what to check out.
+type repoSpec struct {
+	url      string  // Repository URL.
+	ref      string  // Branch or tag to check out. The default branch if empty.
+	commit   string  // Commit to check out, possibly abbreviated. The ref head if empty.
+	auth     GitAuth // Credentials used to clone the repository.
+	cacheDir string  // Directory of the local git mirrors. Cloned directly from the URL if empty.
+
+	depth        int      // Number of commits fetched. Full history if zero.
+	singleBranch bool     // Fetch only the ref, or the default branch.
+	submodules   bool     // Clone the submodules recursively.
+	sparseDirs   []string // Directories checked out. The whole tree if empty.
+}
+
+// pinnedCommitRef is the reference of the commits fetched by their hash.
+const pinnedCommitRef = "refs/executor/commit"
+
+// gitModulesFile describes the submodules of a repository.
+const gitModulesFile = ".gitmodules"
+
 type repoSetupResult struct {
 	dir      string
 	commitID string
 }
 
-func setupRepo(repoURL, baseDir, dir string) (repoSetupResult, error) {
-	u, err := url.Parse(repoURL)
+func setupRepo(ctx context.Context, repo repoSpec, baseDir, dir string) (repoSetupResult, error) {
+	cloneURL, repoPath, err := cloneTarget(repo.url, baseDir, dir)
 	if err != nil {
-		return repoSetupResult{}, fmt.Errorf("error parsing repository URL: %w", err)
+		return repoSetupResult{}, err
 	}
-	if u.Scheme == "" {
-		u.Scheme = "https"
+
+	if err := os.MkdirAll(repoPath, 0775); err != nil {
+		return repoSetupResult{}, fmt.Errorf("error when creating temporary dir: %w", err)
+	}
+	repo.url = cloneURL
+	cid, err := cloneRepository(ctx, repoPath, repo)
+	if err != nil {
+		return repoSetupResult{}, fmt.Errorf("error when cloning repo(%s): %w", repo.url, err)
+	}
+	loggerFrom(ctx).Info("repo cloned", commitKey, cid, "dir", repoPath)
+	return repoSetupResult{repoPath, cid}, nil
+}
+
+// cloneTarget returns the URL from where the repository is cloned and the
+// local directory in which it is cloned.
+func cloneTarget(repoURL, baseDir, dir string) (string, string, error) {
+	cloneURL, err := normalizeRepoURL(repoURL)
+	if err != nil {
+		return "", "", err
 	}
 	// Considering baseDir and dir.
 	if baseDir == "" {
@@ -30,37 +74,81 @@ func setupRepo(repoURL, baseDir, dir string) (repoSetupResult, error) {
 	}
 	if dir == "" {
 		// spaces are super bad for paths in command-line
-		dir = path.Base(u.Path)
+		dir = path.Base(repoPath(cloneURL))
 	}
+	return cloneURL, filepath.Join(baseDir, dir), nil
+}
 
-	repoPath := filepath.Join(baseDir, dir)
-	log.Printf("Creating directory:%s\n", repoPath)
+// normalizeRepoURL returns the URL from where the repository is cloned.
+// URLs without scheme, like github.com/org/repo, are cloned through https.
+// scp-like URLs, e.g. git@github.com:org/repo.git, are kept as is.
+func normalizeRepoURL(repoURL string) (string, error) {
+	if scpLikeURL.MatchString(repoURL) {
+		return repoURL, nil
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", fmt.Errorf("error parsing repository URL: %w", err)
+	}
+	if u.Scheme == "" {
+		u.Scheme = "https"
+	}
+	return u.String(), nil
+}
 
-	if err := os.MkdirAll(repoPath, 0775); err != nil {
-		return repoSetupResult{}, fmt.Errorf("error when creating temporary dir: %w", err)
+// repoPath returns the path of the repository in the normalized URL.
+func repoPath(repoURL string) string {
+	if scpLikeURL.MatchString(repoURL) {
+		return repoURL[strings.Index(repoURL, ":")+1:]
 	}
-	cid, err := cloneRepository(repoPath, u.String())
+	u, err := url.Parse(repoURL)
 	if err != nil {
-		return repoSetupResult{}, fmt.Errorf("error when cloning repo(%s): %w", repoURL, err)
+		return repoURL
 	}
-	log.Printf("Repo cloned successfully! Commit:%s New dir:%s\n", cid, repoPath)
-	return repoSetupResult{repoPath, cid}, nil
+	return u.Path
 }
 
-// cloneRepository is responsible for get the latest code version of pipeline repository.
-// Creates and returns the DefaultBaseDir for the pipeline and the latest commit in the repository.
-func cloneRepository(dir, repoURL string) (string, error) {
+// cloneRepository is responsible for get the code version of pipeline repository
+// requested by the spec: the ref (or default branch) head or the commit.
+// It returns the commit checked out.
+func cloneRepository(ctx context.Context, dir string, repo repoSpec) (string, error) {
 	if err := os.RemoveAll(dir); err != nil {
 		return "", fmt.Errorf("error cloning the repository. error removing previous directory: %q", err)
 	}
 
-	log.Printf("Cloning the repository [%s] into [%s]\n", repoURL, dir)
-	r, err := git.PlainClone(dir, false, &git.CloneOptions{
-		URL:      repoURL,
-		Progress: os.Stdout,
-	})
+	auth, err := repo.auth.method(repo.url)
 	if err != nil {
-		return "", fmt.Errorf("error cloning the repository: %q", err)
+		return "", fmt.Errorf("error cloning the repository. error setting up git credentials: %w", err)
+	}
+	loggerFrom(ctx).Info("cloning repo", "repo", repo.url, "ref", repo.ref, commitKey, repo.commit, "dir", dir)
+	var r *git.Repository
+	if repo.cacheDir != "" {
+		// The working copy gets the whole history of the mirror, which is
+		// only fetched incrementally, so depth and single-branch do not apply.
+		if repo.depth > 0 || repo.singleBranch {
+			loggerFrom(ctx).Warn("clone-depth and single-branch are ignored when cloning from the git cache", "repo", repo.url, "clone_depth", repo.depth, "single_branch", repo.singleBranch)
+		}
+		if r, err = cloneFromCache(ctx, dir, repo); err != nil {
+			return "", fmt.Errorf("error cloning the repository from the git cache: %w", err)
+		}
+	} else {
+		// The files are checked out below, once the commit is known. The
+		// progress goes to stderr, as stdout may carry the pipeline result.
+		r, err = git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{
+			URL:           repo.url,
+			ReferenceName: plumbing.ReferenceName(repo.ref),
+			Auth:          auth,
+			Progress:      os.Stderr,
+			Depth:         repo.depth,
+			SingleBranch:  repo.singleBranch,
+			NoCheckout:    true,
+		})
+		if err != nil {
+			return "", fmt.Errorf("error cloning the repository: %q", err)
+		}
+	}
+	if err := checkoutRepository(ctx, r, repo, auth); err != nil {
+		return "", fmt.Errorf("error cloning the repository. %w", err)
 	}
 
 	ref, err := r.Head()
@@ -68,9 +156,162 @@ func cloneRepository(dir, repoURL string) (string, error) {
 		return "", fmt.Errorf("error cloning the repository. error getting the HEAD reference of the repository: %q", err)
 	}
 
+	// The HEAD commit is always fetched, even by shallow clones.
 	commit, err := r.CommitObject(ref.Hash())
 	if err != nil {
 		return "", fmt.Errorf("error cloning the repository. error getting the lattest commit of the repository: %q", err)
 	}
+	// Short commits must be a prefix of the one checked out.
+	if !strings.HasPrefix(commit.Hash.String(), strings.ToLower(repo.commit)) {
+		return "", fmt.Errorf("error cloning the repository. commit %s requested, %s checked out", repo.commit, commit.Hash)
+	}
 	return commit.Hash.String(), nil
 }
+
+// checkoutRepository checks out the commit of the spec or, if not set, the
+// HEAD of the clone. Only the sparse dirs are checked out, if set, and then
+// the submodules within them.
+func checkoutRepository(ctx context.Context, r *git.Repository, repo repoSpec, auth transport.AuthMethod) error {
+	opts := &git.CheckoutOptions{Force: true, SparseCheckoutDirectories: repo.sparseDirs}
+	if repo.submodules && len(repo.sparseDirs) > 0 {
+		// Needed to find the submodules.
+		opts.SparseCheckoutDirectories = append(slices.Clone(repo.sparseDirs), gitModulesFile)
+	}
+	if repo.commit != "" {
+		hash, err := resolveCommit(ctx, r, repo, auth)
+		if err != nil {
+			return err
+		}
+		opts.Hash = hash
+	} else {
+		head, err := r.Storer.Reference(plumbing.HEAD)
+		if err != nil {
+			return fmt.Errorf("error getting the HEAD reference of the repository: %q", err)
+		}
+		if head.Type() == plumbing.SymbolicReference {
+			opts.Branch = head.Target()
+		} else {
+			opts.Hash = head.Hash()
+		}
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return fmt.Errorf("error getting the worktree: %q", err)
+	}
+	if err := w.Checkout(opts); err != nil {
+		return fmt.Errorf("error checking out the repository: %q", err)
+	}
+	if !repo.submodules {
+		return nil
+	}
+
+	subs, err := w.Submodules()
+	if err != nil {
+		return fmt.Errorf("error reading submodules: %q", err)
+	}
+	var update git.Submodules
+	for _, sub := range subs {
+		if inSparseDirs(sub.Config().Path, repo.sparseDirs) {
+			update = append(update, sub)
+		}
+	}
+	// The submodules are cloned with the credentials of the repository.
+	if err := update.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Auth:              auth,
+	}); err != nil {
+		return fmt.Errorf("error updating submodules: %q", err)
+	}
+	return nil
+}
+
+// resolveCommit returns the full hash of the commit of the spec. Commits older
+// than the history of shallow clones are fetched, if the full hash is given
+// and the server allows it.
+func resolveCommit(ctx context.Context, r *git.Repository, repo repoSpec, auth transport.AuthMethod) (plumbing.Hash, error) {
+	hash, err := r.ResolveRevision(plumbing.Revision(repo.commit))
+	if err == nil {
+		return *hash, nil
+	}
+	if repo.depth == 0 {
+		return plumbing.ZeroHash, fmt.Errorf("error resolving commit %s: %q", repo.commit, err)
+	}
+	if !plumbing.IsHash(repo.commit) {
+		return plumbing.ZeroHash, fmt.Errorf("error resolving commit %s: not within the last %d commits cloned, increase clone-depth or use the full commit hash", repo.commit, repo.depth)
+	}
+	err = r.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec(strings.ToLower(repo.commit) + ":" + pinnedCommitRef)},
+		Auth:     auth,
+		Depth:    repo.depth,
+		Tags:     git.NoTags,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return plumbing.ZeroHash, fmt.Errorf("error fetching commit %s: %q, increase clone-depth", repo.commit, err)
+	}
+	return plumbing.NewHash(repo.commit), nil
+}
+
+// inSparseDirs returns whether the path is checked out by the sparse checkout
+// of the dirs. Everything is checked out if there are no dirs.
+func inSparseDirs(path string, dirs []string) bool {
+	for _, d := range dirs {
+		if path == d || strings.HasPrefix(path, d+"/") {
+			return true
+		}
+	}
+	return len(dirs) == 0
+}
+
+// remoteCommit returns the commit the ref (branch or tag) of the remote
+// repository points to, without cloning it, using the credentials. If ref is empty, the commit of
+// the remote HEAD is returned.
+func remoteCommit(ctx context.Context, repoURL, ref string, auth GitAuth) (string, error) {
+	listURL, err := normalizeRepoURL(repoURL)
+	if err != nil {
+		return "", err
+	}
+	method, err := auth.method(listURL)
+	if err != nil {
+		return "", fmt.Errorf("error setting up git credentials: %w", err)
+	}
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{listURL},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: method, PeelingOption: git.AppendPeeled})
+	if err != nil {
+		return "", fmt.Errorf("error listing references of repo(%s): %q", repoURL, err)
+	}
+	byName := make(map[plumbing.ReferenceName]*plumbing.Reference)
+	for _, r := range refs {
+		byName[r.Name()] = r
+	}
+	var found *plumbing.Reference
+	if ref == "" {
+		found = byName[plumbing.HEAD]
+	} else {
+		// Same rules as git uses to expand short names, e.g. main or v1.0.
+		for _, rule := range plumbing.RefRevParseRules {
+			if r, ok := byName[plumbing.ReferenceName(fmt.Sprintf(rule, ref))]; ok {
+				found = r
+				break
+			}
+		}
+	}
+	// HEAD may be a symbolic reference to the default branch.
+	for found != nil && found.Type() == plumbing.SymbolicReference {
+		found = byName[found.Target()]
+	}
+	if found == nil {
+		if ref == "" {
+			ref = string(plumbing.HEAD)
+		}
+		return "", fmt.Errorf("error resolving %s of repo(%s)", ref, repoURL)
+	}
+	// Annotated tags point to tag objects, peeled to the commit.
+	if peeled, ok := byName[found.Name()+"^{}"]; ok {
+		found = peeled
+	}
+	return found.Hash().String(), nil
+}
@@ -0,0 +1,357 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dadosjusbr/executor/status"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testRepo is a local repository with the history:
+//
+//	c1 (tag v1) -- c2 (master)
+//	  \
+//	   c3 (feature)
+type testRepo struct {
+	dir, url   string
+	c1, c2, c3 string
+}
+
+// commit commits a change to the checked out branch and returns its hash.
+func (tr testRepo) commit(t *testing.T, content string) string {
+	t.Helper()
+	r, err := git.PlainOpen(tr.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tr.dir, "README"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add("README"); err != nil {
+		t.Fatal(err)
+	}
+	h, err := w.Commit(content, &git.CommitOptions{Author: testSignature()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h.String()
+}
+
+func testSignature() *object.Signature {
+	return &object.Signature{Name: "dadosjusbr", Email: "dadosjusbr@gmail.com", When: time.Now()}
+}
+
+func newTestRepo(t *testing.T) testRepo {
+	t.Helper()
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := testRepo{dir: dir, url: "file://" + dir}
+	tr.c1 = tr.commit(t, "c1")
+	if _, err := r.CreateTag("v1", plumbing.NewHash(tr.c1), &git.CreateTagOptions{Tagger: testSignature(), Message: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	tr.c2 = tr.commit(t, "c2")
+	master, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(tr.c1), Branch: "refs/heads/feature", Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	tr.c3 = tr.commit(t, "c3")
+	if err := w.Checkout(&git.CheckoutOptions{Branch: master.Name()}); err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func TestCloneRepository(t *testing.T) {
+	tr := newTestRepo(t)
+	testCases := []struct {
+		desc    string
+		ref     string
+		commit  string
+		want    string
+		wantErr bool
+	}{
+		{"DefaultBranch", "", "", tr.c2, false},
+		{"Branch", "feature", "", tr.c3, false},
+		{"AnnotatedTag", "v1", "", tr.c1, false},
+		{"Commit", "", tr.c1, tr.c1, false},
+		{"ShortCommit", "", tr.c3[:8], tr.c3, false},
+		{"RefAndCommit", "feature", tr.c1, tr.c1, false},
+		{"UnknownRef", "missing", "", "", true},
+		{"UnknownCommit", "", "deadbeef", "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "repo")
+			got, err := cloneRepository(context.Background(), dir, repoSpec{url: tr.url, ref: tc.ref, commit: tc.commit})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error, got commit %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if got != tc.want {
+				t.Errorf("want commit %s, got %s", tc.want, got)
+			}
+			// The working copy must be at the commit returned.
+			r, err := git.PlainOpen(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			head, err := r.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if head.Hash().String() != tc.want {
+				t.Errorf("want HEAD at %s, got %s", tc.want, head.Hash())
+			}
+		})
+	}
+}
+
+func TestRemoteCommit(t *testing.T) {
+	tr := newTestRepo(t)
+	testCases := []struct {
+		desc string
+		ref  string
+		want string
+	}{
+		{"HEAD", "", tr.c2},
+		{"Branch", "feature", tr.c3},
+		{"FullBranchName", "refs/heads/feature", tr.c3},
+		{"AnnotatedTag", "v1", tr.c1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := remoteCommit(context.Background(), tr.url, tc.ref, GitAuth{})
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if got != tc.want {
+				t.Errorf("want %s, got %s", tc.want, got)
+			}
+		})
+	}
+	if _, err := remoteCommit(context.Background(), tr.url, "missing", GitAuth{}); err == nil {
+		t.Errorf("want error resolving missing ref, got nil")
+	}
+}
+
+func TestPipelineRun_PinnedRepo(t *testing.T) {
+	tr := newTestRepo(t)
+	testCases := []struct {
+		desc       string
+		ref        string
+		commit     string
+		wantStatus status.Code
+		wantCommit string
+	}{
+		{"Ref", "feature", "", status.OK, tr.c3},
+		{"Commit", "", tr.c1[:8], status.OK, tr.c1},
+		{"UnknownCommit", "", "deadbeef", status.SetupError, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			p := Pipeline{
+				Name:           "test",
+				DefaultBaseDir: t.TempDir(),
+				Stages:         []Stage{{Name: "coleta", Repo: tr.url, Ref: tc.ref, Commit: tc.commit}},
+				Runtime:        &fakeRuntime{},
+			}
+			result := p.Run()
+			if result.Status != tc.wantStatus {
+				t.Fatalf("want status %v, got %v", tc.wantStatus, result.Status)
+			}
+			ser := result.StageResults[0]
+			if ser.CommitID != tc.wantCommit {
+				t.Errorf("want commit %s, got %s", tc.wantCommit, ser.CommitID)
+			}
+			if tc.wantCommit != "" && ser.Stage.Commit != tc.wantCommit {
+				t.Errorf("want resolved commit %s in stage, got %s", tc.wantCommit, ser.Stage.Commit)
+			}
+		})
+	}
+}
+
+func TestCloneRepository_Shallow(t *testing.T) {
+	tr := newTestRepo(t)
+	// Allows fetching the commits older than the shallow history.
+	r, err := git.PlainOpen(tr.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Raw.Section("uploadpack").SetOption("allowReachableSHA1InWant", "true")
+	if err := r.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc         string
+		ref          string
+		commit       string
+		singleBranch bool
+		want         string
+		wantErr      bool
+	}{
+		{"DefaultBranch", "", "", false, tr.c2, false},
+		{"Branch", "feature", "", false, tr.c3, false},
+		{"SingleBranch", "feature", "", true, tr.c3, false},
+		{"HeadCommit", "", tr.c2[:8], false, tr.c2, false},
+		{"TaggedCommit", "", tr.c1[:8], false, tr.c1, false},
+		// c2 is only in master, not fetched.
+		{"CommitNotFetched", "feature", tr.c2, true, tr.c2, false},
+		{"ShortCommitNotFetched", "feature", tr.c2[:8], true, "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "repo")
+			got, err := cloneRepository(context.Background(), dir, repoSpec{url: tr.url, ref: tc.ref, commit: tc.commit, depth: 1, singleBranch: tc.singleBranch})
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error, got commit %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if got != tc.want {
+				t.Errorf("want commit %s, got %s", tc.want, got)
+			}
+			r, err := git.PlainOpen(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			shallow, err := r.Storer.Shallow()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(shallow) == 0 {
+				t.Errorf("want shallow clone, got full history")
+			}
+			_, err = r.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, "master"), false)
+			if tc.singleBranch == (err == nil) {
+				t.Errorf("want branch master fetched %v, got error %v", !tc.singleBranch, err)
+			}
+		})
+	}
+}
+
+// newTestRepoWithSubmodules returns the URL of a repository with the files
+// README, stage/Dockerfile and other/file and the submodules stage/lib and
+// other/lib, both the test repository at c2.
+func newTestRepoWithSubmodules(t *testing.T) string {
+	t.Helper()
+	sub := newTestRepo(t)
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"README":           "root",
+		"stage/Dockerfile": "FROM scratch",
+		"other/file":       "other",
+		gitModulesFile:     fmt.Sprintf("[submodule \"stage/lib\"]\n\tpath = stage/lib\n\turl = %s\n[submodule \"other/lib\"]\n\tpath = other/lib\n\turl = %s\n", sub.url, sub.url),
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0775); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Submodules are gitlinks in the index, pointing to their commit.
+	idx, err := r.Storer.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"other/lib", "stage/lib"} {
+		idx.Entries = append(idx.Entries, &index.Entry{Name: name, Mode: filemode.Submodule, Hash: plumbing.NewHash(sub.c2)})
+	}
+	if err := r.Storer.SetIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Commit("submodules", &git.CommitOptions{Author: testSignature()}); err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + dir
+}
+
+func TestCloneRepository_SparseSubmodules(t *testing.T) {
+	url := newTestRepoWithSubmodules(t)
+	testCases := []struct {
+		desc       string
+		submodules bool
+		sparseDirs []string
+		cache      bool
+		want       []string // Files checked out.
+		wantNot    []string // Files not checked out.
+	}{
+		{"Default", false, nil, false, []string{"README", "stage/Dockerfile", "other/file"}, []string{"stage/lib/README"}},
+		{"Submodules", true, nil, false, []string{"README", "stage/lib/README", "other/lib/README"}, nil},
+		{"Sparse", false, []string{"stage"}, false, []string{"stage/Dockerfile"}, []string{"README", "other/file", "stage/lib/README"}},
+		{"SparseSubmodules", true, []string{"stage"}, false, []string{"stage/Dockerfile", "stage/lib/README"}, []string{"README", "other/file", "other/lib/README"}},
+		{"SparseSubmodulesFromCache", true, []string{"stage"}, true, []string{"stage/Dockerfile", "stage/lib/README"}, []string{"README", "other/file", "other/lib/README"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "repo")
+			repo := repoSpec{url: url, submodules: tc.submodules, sparseDirs: tc.sparseDirs}
+			if tc.cache {
+				repo.cacheDir = t.TempDir()
+			}
+			if _, err := cloneRepository(context.Background(), dir, repo); err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			for _, name := range tc.want {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					t.Errorf("want %s checked out, got %v", name, err)
+				}
+			}
+			for _, name := range tc.wantNot {
+				if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+					t.Errorf("want %s not checked out, got it", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Resources limits the host resources used by the container of a stage, so
+// that a runaway stage can not take the host down. Zero values mean no
+// limit, or the pipeline default.
+type Resources struct {
+	Memory     ByteSize `json:"memory" bson:"memory,omitempty"`           // Memory limit of the container, e.g. "512m". If it is exceeded, the container is killed and the stage fails with OOMError.
+	MemorySwap ByteSize `json:"memory-swap" bson:"memory-swap,omitempty"` // Limit of memory plus swap, e.g. "1g", or -1 for unlimited swap. Defaults to twice Memory, like docker.
+	CPUs       float64  `json:"cpus" bson:"cpus,omitempty"`               // CPU quota, in number of CPUs, e.g. 1.5.
+	CPUShares  int64    `json:"cpu-shares" bson:"cpu-shares,omitempty"`   // CPU weight relative to the other containers, which have 1024 by default.
+	PidsLimit  int64    `json:"pids-limit" bson:"pids-limit,omitempty"`   // Maximum number of processes in the container, or -1 for unlimited.
+	Ulimits    []Ulimit `json:"ulimits" bson:"ulimits,omitempty"`         // Limits of the container processes, e.g. nofile.
+	TmpfsSize  ByteSize `json:"tmpfs-size" bson:"tmpfs-size,omitempty"`   // Size of the tmpfs mounted at /tmp, e.g. "1g". If not set, /tmp is written to the container layer, on the host disk.
+}
+
+// Ulimit is a limit of the container processes, like the ones set by ulimit.
+type Ulimit struct {
+	Name string `json:"name" bson:"name,omitempty"` // Name of the limit, e.g. nofile or nproc.
+	Soft int64  `json:"soft" bson:"soft,omitempty"` // Soft limit.
+	Hard int64  `json:"hard" bson:"hard,omitempty"` // Hard limit. Defaults to the soft limit.
+}
+
+// hard returns the hard limit, defaulting to the soft one.
+func (u Ulimit) hard() int64 {
+	if u.Hard == 0 {
+		return u.Soft
+	}
+	return u.Hard
+}
+
+// withDefaults returns the resources with the fields not set taken from the
+// defaults. Ulimits are merged by name.
+func (r Resources) withDefaults(defaults Resources) Resources {
+	if r.Memory == 0 {
+		r.Memory = defaults.Memory
+	}
+	if r.MemorySwap == 0 {
+		r.MemorySwap = defaults.MemorySwap
+	}
+	if r.CPUs == 0 {
+		r.CPUs = defaults.CPUs
+	}
+	if r.CPUShares == 0 {
+		r.CPUShares = defaults.CPUShares
+	}
+	if r.PidsLimit == 0 {
+		r.PidsLimit = defaults.PidsLimit
+	}
+	if r.TmpfsSize == 0 {
+		r.TmpfsSize = defaults.TmpfsSize
+	}
+	ulimits := append([]Ulimit(nil), r.Ulimits...)
+	for _, d := range defaults.Ulimits {
+		set := false
+		for _, u := range r.Ulimits {
+			set = set || u.Name == d.Name
+		}
+		if !set {
+			ulimits = append(ulimits, d)
+		}
+	}
+	if len(ulimits) > 0 {
+		r.Ulimits = ulimits
+	}
+	return r
+}
+
+// tmpDir is the directory in which the tmpfs of Resources.TmpfsSize is mounted.
+const tmpDir = "/tmp"
+
+// byteSizePattern matches the sizes accepted by ByteSize: a number of bytes
+// followed by an optional binary unit, e.g. 512m, 2GiB or 100, or -1.
+// It is also used in the JSON Schema, so it avoids flags.
+var byteSizePattern = regexp.MustCompile(`^(?:-1|([0-9]+)\s*(?:([kmgtKMGT])(?:[iI]?[bB])?|[bB])?)$`)
+
+// ByteSize is a number of bytes that is (un)marshaled as a string with binary
+// units, e.g. "512m" or "2g", like in the docker client. Numbers are also
+// accepted when unmarshaling and are read as bytes.
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"t", 1 << 40},
+	{"g", 1 << 30},
+	{"m", 1 << 20},
+	{"k", 1 << 10},
+}
+
+// ParseByteSize parses sizes like "512m", "2GiB" or "100".
+func ParseByteSize(s string) (ByteSize, error) {
+	m := byteSizePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q: it must be a number of bytes, optionally followed by k, m, g or t", s)
+	}
+	if m[1] == "" {
+		return -1, nil
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	size := ByteSize(n)
+	for _, u := range byteUnits {
+		if strings.EqualFold(m[2], u.suffix) {
+			if size > ByteSize(1<<63-1)/u.size {
+				return 0, fmt.Errorf("invalid size %q: too large", s)
+			}
+			size *= u.size
+		}
+	}
+	return size, nil
+}
+
+// String returns the size with the largest exact unit, e.g. 512m.
+func (b ByteSize) String() string {
+	for _, u := range byteUnits {
+		if b != 0 && b%u.size == 0 {
+			return fmt.Sprintf("%d%s", b/u.size, u.suffix)
+		}
+	}
+	return strconv.FormatInt(int64(b), 10)
+}
+
+// MarshalJSON encodes the size as a string.
+func (b ByteSize) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+// UnmarshalJSON decodes the size from a string or a number.
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case float64:
+		*b = ByteSize(value)
+	case string:
+		parsed, err := ParseByteSize(value)
+		if err != nil {
+			return err
+		}
+		*b = parsed
+	default:
+		return fmt.Errorf("invalid size: %s", string(data))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	testCases := []struct {
+		in      string
+		want    ByteSize
+		wantErr bool
+	}{
+		{"100", 100, false},
+		{"100b", 100, false},
+		{"512m", 512 << 20, false},
+		{"512MB", 512 << 20, false},
+		{"2GiB", 2 << 30, false},
+		{" 1 t ", 1 << 40, false},
+		{"4k", 4 << 10, false},
+		{"-1", -1, false},
+		{"", 0, true},
+		{"1.5g", 0, true},
+		{"-2", 0, true},
+		{"10x", 0, true},
+		{"9999999999t", 0, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseByteSize(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if got != tc.want {
+				t.Errorf("want %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestByteSize_JSON(t *testing.T) {
+	var r Resources
+	if err := json.Unmarshal([]byte(`{"memory": "1536m", "memory-swap": -1, "tmpfs-size": 1048576}`), &r); err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if r.Memory != 1536<<20 || r.MemorySwap != -1 || r.TmpfsSize != 1<<20 {
+		t.Errorf("want sizes 1536m, -1 and 1m, got %v, %v and %v", r.Memory, r.MemorySwap, r.TmpfsSize)
+	}
+	b, err := json.Marshal(r.Memory)
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	if string(b) != `"1536m"` {
+		t.Errorf("want %s, got %s", `"1536m"`, b)
+	}
+	if err := json.Unmarshal([]byte(`{"memory": true}`), &r); err == nil {
+		t.Errorf("want error unmarshaling a boolean size")
+	}
+}
+
+func TestResources_WithDefaults(t *testing.T) {
+	defaults := Resources{
+		Memory:    1 << 30,
+		PidsLimit: 100,
+		TmpfsSize: 64 << 20,
+		Ulimits:   []Ulimit{{Name: "nofile", Soft: 1024}, {Name: "nproc", Soft: 64}},
+	}
+	r := Resources{
+		Memory:  256 << 20,
+		CPUs:    2,
+		Ulimits: []Ulimit{{Name: "nproc", Soft: 32, Hard: 64}},
+	}
+	want := Resources{
+		Memory:    256 << 20,
+		CPUs:      2,
+		PidsLimit: 100,
+		TmpfsSize: 64 << 20,
+		Ulimits:   []Ulimit{{Name: "nproc", Soft: 32, Hard: 64}, {Name: "nofile", Soft: 1024}},
+	}
+	if got := r.withDefaults(defaults); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+	if got := (Resources{}).withDefaults(Resources{}); !reflect.DeepEqual(got, Resources{}) {
+		t.Errorf("want no resources, got %+v", got)
+	}
+}
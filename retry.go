@@ -0,0 +1,73 @@
+package executor
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/dadosjusbr/executor/status"
+)
+
+const (
+	defaultInitialBackoff = time.Second
+	maxBackoff            = time.Duration(math.MaxInt64 / 2) // Limit of the doubled backoff, so the jitter does not overflow it.
+)
+
+// RetryPolicy defines when the run of a stage is retried. Only the run step is
+// retried: the image is set up and built once. If the stage has a timeout, it
+// also bounds the retries.
+type RetryPolicy struct {
+	MaxAttempts    int           `json:"max-attempts" bson:"max-attempts,omitempty"`       // Maximum number of runs, including the first one. No retries if not greater than 1.
+	InitialBackoff Duration      `json:"initial-backoff" bson:"initial-backoff,omitempty"` // Wait before the first retry, e.g. "10s". It doubles at each retry. Defaults to 1s.
+	MaxBackoff     Duration      `json:"max-backoff" bson:"max-backoff,omitempty"`         // Maximum wait between two attempts. No limit if not set.
+	Jitter         float64       `json:"jitter" bson:"jitter,omitempty"`                   // Fraction of the backoff randomly added or subtracted, between 0 and 1.
+	AttemptTimeout Duration      `json:"attempt-timeout" bson:"attempt-timeout,omitempty"` // Maximum duration of each attempt. Attempts that take longer fail with TimeoutError.
+	ExitCodes      []int         `json:"exit-codes" bson:"exit-codes,omitempty"`           // Retryable exit codes of the container, e.g. 7.
+	Statuses       []status.Code `json:"statuses" bson:"statuses,omitempty"`               // Retryable statuses of the failed attempt: RunError, TimeoutError or OOMError. If neither these nor ExitCodes are set, every failure is retried.
+}
+
+// attemptStatuses are the statuses of a failed attempt.
+var attemptStatuses = []status.Code{status.RunError, status.TimeoutError, status.OOMError}
+
+// retryable returns whether a failed attempt should be retried.
+func (rp RetryPolicy) retryable(exitStatus int, code status.Code) bool {
+	if len(rp.ExitCodes) == 0 && len(rp.Statuses) == 0 {
+		return true
+	}
+	for _, c := range rp.ExitCodes {
+		if c == exitStatus {
+			return true
+		}
+	}
+	for _, c := range rp.Statuses {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns how long to wait after the given failed attempt, starting at 1.
+func (rp RetryPolicy) backoff(attempt int) time.Duration {
+	d := time.Duration(rp.InitialBackoff)
+	if d <= 0 {
+		d = defaultInitialBackoff
+	}
+	for i := 1; i < attempt; i++ {
+		if d >= maxBackoff/2 {
+			d = maxBackoff
+			break
+		}
+		d *= 2
+		if rp.MaxBackoff > 0 && d >= time.Duration(rp.MaxBackoff) {
+			break
+		}
+	}
+	if rp.MaxBackoff > 0 && d > time.Duration(rp.MaxBackoff) {
+		d = time.Duration(rp.MaxBackoff)
+	}
+	if rp.Jitter > 0 {
+		d += time.Duration(float64(d) * rp.Jitter * (2*rand.Float64() - 1))
+	}
+	return d
+}
@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dadosjusbr/executor/status"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	rp := RetryPolicy{InitialBackoff: Duration(time.Second), MaxBackoff: Duration(5 * time.Second)}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := rp.backoff(i + 1); got != w {
+			t.Errorf("want backoff %s after attempt %d, got %s", w, i+1, got)
+		}
+	}
+	if got := (RetryPolicy{}).backoff(1); got != defaultInitialBackoff {
+		t.Errorf("want default backoff %s, got %s", defaultInitialBackoff, got)
+	}
+	if got := (RetryPolicy{}).backoff(100); got != maxBackoff {
+		t.Errorf("want backoff clamped to %s after attempt 100, got %s", maxBackoff, got)
+	}
+	for i := 0; i < 100; i++ {
+		if got := (RetryPolicy{Jitter: 1}).backoff(100); got < 0 {
+			t.Fatalf("want non-negative backoff with jitter after attempt 100, got %s", got)
+		}
+	}
+	rp.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := rp.backoff(2); got < time.Second || got > 3*time.Second {
+			t.Fatalf("want backoff between 1s and 3s, got %s", got)
+		}
+	}
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	testCases := []struct {
+		name       string
+		policy     RetryPolicy
+		exitStatus int
+		code       status.Code
+		want       bool
+	}{
+		{"any failure", RetryPolicy{}, 1, status.RunError, true},
+		{"exit code", RetryPolicy{ExitCodes: []int{7}}, 7, status.RunError, true},
+		{"other exit code", RetryPolicy{ExitCodes: []int{7}}, 1, status.RunError, false},
+		{"status", RetryPolicy{Statuses: []status.Code{status.TimeoutError}}, -1, status.TimeoutError, true},
+		{"other status", RetryPolicy{Statuses: []status.Code{status.TimeoutError}}, 1, status.RunError, false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.retryable(tt.exitStatus, tt.code); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
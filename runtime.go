@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"context"
+	"io"
+)
+
+// ContainerRuntime is the container engine used to build, pull and run the
+// images of the pipeline stages. It also manages the pipeline volumes,
+// networks and services.
+//
+// Implementations must return a CmdResult describing each build, pull and run,
+// even when an error is returned, so the pipeline can report what happened.
+// The build, pull and run output must be captured respecting the output
+// limit, e.g. using newCapture.
+// When the context is done, implementations must stop the running process and
+// the container it started, not only the local client.
+// The default runtime, used when Pipeline.Runtime is nil, is DockerCLI.
+type ContainerRuntime interface {
+	// Build builds an image from the context directory described in spec.
+	Build(ctx context.Context, spec BuildSpec) (CmdResult, error)
+	// Pull downloads the image from its registry. The dir is the local
+	// directory of the stage and may be used as working directory. The
+	// output must be captured respecting limit.
+	Pull(ctx context.Context, image, dir string, limit OutputLimit) (CmdResult, error)
+	// ImageID returns the ID of a local image.
+	ImageID(ctx context.Context, image string) (string, error)
+	// Run executes a container and waits for it to finish. The resource
+	// limits of the spec must be applied and CmdResult.OOMKilled set if the
+	// container has been killed for exceeding its memory limit.
+	Run(ctx context.Context, spec RunSpec) (CmdResult, error)
+	// CreateVolume creates a named volume bound to the local directory dir,
+	// or kept by the runtime if dir is empty.
+	CreateVolume(ctx context.Context, dir, name string) error
+	// RemoveVolume removes the named volume.
+	RemoveVolume(ctx context.Context, name string) error
+	// CreateNetwork creates a user-defined network. Internal networks are
+	// isolated from the outside.
+	CreateNetwork(ctx context.Context, name string, internal bool) error
+	// RemoveNetwork removes the user-defined network.
+	RemoveNetwork(ctx context.Context, name string) error
+	// StartService starts a service container in background, returning as
+	// soon as it is started.
+	StartService(ctx context.Context, spec ServiceSpec) error
+	// ServiceIP returns the IP address of the service container in network.
+	ServiceIP(ctx context.Context, name, network string) (string, error)
+	// ExecService executes cmd inside the service container, returning its
+	// exit code.
+	ExecService(ctx context.Context, name string, cmd []string) (int, error)
+	// StopService stops and removes the service container, returning its
+	// output and exit status. The output must be captured respecting limit.
+	StopService(ctx context.Context, name string, limit OutputLimit) (CmdResult, error)
+}
+
+// BuildSpec describes an image build.
+type BuildSpec struct {
+	Image string            // Name (tag) of the image to be built.
+	Dir   string            // Local directory used as build context.
+	Env   map[string]string // Build arguments.
+
+	Stdout io.Writer   // Receives the build output as it is produced, if set. The output must be captured in the CmdResult regardless.
+	Stderr io.Writer   // Receives the build errors as they are produced, if set.
+	Limit  OutputLimit // Bounds the output kept in memory in the CmdResult.
+}
+
+// RunSpec describes a container execution.
+type RunSpec struct {
+	Name       string            // Name of the container. Runtimes may generate one when empty.
+	Image      string            // Image to be executed.
+	Dir        string            // Local directory of the stage.
+	VolumeName string            // Name of the volume to be mounted. Only mounted if VolumeDir is also set.
+	VolumeDir  string            // Path inside the container in which the volume is mounted.
+	Stdin      string            // Standard input of the container.
+	StdinFile  string            // Path of a file used as standard input instead of Stdin, if set.
+	Env        map[string]string // Environment variables of the container.
+	Secrets    map[string]string // Environment variables of the container holding secrets. Their values must not appear in command lines nor in the CmdResult.
+	Mounts     []Mount           // Filesystems mounted in the container, besides the volume.
+	Network    string            // Network the container is connected to. The runtime default if empty.
+	Resources  Resources         // Host resources the container may use.
+
+	Stdout io.Writer   // Receives the container stdout as it is produced, if set. The output must be captured in the CmdResult regardless.
+	Stderr io.Writer   // Receives the container stderr as it is produced, if set.
+	Limit  OutputLimit // Bounds the output kept in memory in the CmdResult.
+}
+
+// ServiceSpec describes a service container.
+type ServiceSpec struct {
+	Name      string            // Name of the container.
+	Alias     string            // Host name of the container in the network.
+	Image     string            // Image to be executed.
+	Command   []string          // Arguments of the container. The image command if empty.
+	Env       map[string]string // Environment variables of the container.
+	Secrets   map[string]string // Environment variables of the container holding secrets. Their values must not appear in command lines.
+	Network   string            // Network the container is connected to.
+	Mounts    []Mount           // Filesystems mounted in the container.
+	Resources Resources         // Host resources the container may use.
+}
+
+func (p *Pipeline) runtime() ContainerRuntime {
+	if p.Runtime == nil {
+		return DockerCLI{}
+	}
+	return p.Runtime
+}
+
+// withSink returns a writer that writes to w and to sink, if not nil. Errors
+// writing to the sink are ignored, so a broken sink never affects the capture.
+func withSink(w, sink io.Writer) io.Writer {
+	if sink == nil {
+		return w
+	}
+	return sinkWriter{w, sink}
+}
+
+type sinkWriter struct {
+	w    io.Writer
+	sink io.Writer
+}
+
+func (s sinkWriter) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	s.sink.Write(p[:n])
+	return n, err
+}
@@ -0,0 +1,162 @@
+package executor
+
+//go:generate go run gen_schema.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dadosjusbr/executor/status"
+)
+
+// durationPattern matches the durations accepted by time.ParseDuration.
+const durationPattern = `^[-+]?([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$|^0$`
+
+// schemaFields refines the schema of some fields, by type and JSON name, with
+// the constraints checked by Validate.
+var schemaFields = map[string]map[string]interface{}{
+	"Stage.name":                     {"minLength": 1},
+	"Stage.container-id":             {"pattern": imageName.String()},
+	"Stage.commit":                   {"pattern": commitHash.String()},
+	"Stage.repo_version_env_var":     {"pattern": envVarName.String()},
+	"Stage.clone-depth":              {"minimum": 0},
+	"Stage.run-success-codes":        {"items": map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 255}},
+	"RetryPolicy.jitter":             {"minimum": 0, "maximum": 1},
+	"RetryPolicy.exit-codes":         {"items": map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 255}},
+	"RetryPolicy.statuses":           {"items": attemptStatusSchema()},
+	"Resources.cpus":                 {"minimum": 0},
+	"Resources.cpu-shares":           {"minimum": 0},
+	"Resources.pids-limit":           {"minimum": -1},
+	"Ulimit.name":                    {"minLength": 1},
+	"Mount.type":                     {"enum": mountTypes},
+	"Mount.target":                   {"pattern": mountPath.String()},
+	"Network.name":                   {"pattern": objectName.String(), "not": map[string]interface{}{"enum": predefinedNetworks}},
+	"Pipeline.default-network":       {"pattern": objectName.String()},
+	"Stage.network":                  {"pattern": objectName.String()},
+	"Service.name":                   {"pattern": imageName.String()},
+	"Service.image":                  {"minLength": 1},
+	"Service.network":                {"pattern": objectName.String()},
+	"ReadinessCheck.port":            {"minimum": 0, "maximum": 65535},
+	"Secret.name":                    {"pattern": envVarName.String()},
+	"Secret.env":                     {"pattern": envVarName.String()},
+	"Stage.secrets":                  {"items": map[string]interface{}{"type": "string", "pattern": envVarName.String()}},
+	"Service.secrets":                {"items": map[string]interface{}{"type": "string", "pattern": envVarName.String()}},
+	"Ulimit.soft":                    {"minimum": 0},
+	"Ulimit.hard":                    {"minimum": 0},
+	"GitAuth.password-env":           {"pattern": envVarName.String()},
+	"GitAuth.ssh-key-env":            {"pattern": envVarName.String()},
+	"GitAuth.ssh-key-passphrase-env": {"pattern": envVarName.String()},
+}
+
+// JSONSchema returns the JSON Schema of the pipeline definition, which editors
+// can use to validate and complete pipeline files in JSON or YAML. It is
+// shipped as pipeline.schema.json, generated by go generate.
+func JSONSchema() ([]byte, error) {
+	defs := make(map[string]interface{})
+	schema := structSchema(reflect.TypeOf(Pipeline{}), defs)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "DadosJusBR executor pipeline"
+	schema["$defs"] = defs
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling JSON Schema: %w", err)
+	}
+	return append(b, '\n'), nil
+}
+
+// typeSchema returns the schema of the values of type t. Structs are added
+// to defs and referenced.
+func typeSchema(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	switch t {
+	case reflect.TypeOf(Duration(0)):
+		return map[string]interface{}{
+			"description": `Duration like "1h30m" or number of nanoseconds.`,
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "string", "pattern": durationPattern},
+				map[string]interface{}{"type": "integer"},
+			},
+		}
+	case reflect.TypeOf(ByteSize(0)):
+		return map[string]interface{}{
+			"description": `Size like "512m" or "2g", or number of bytes.`,
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "string", "pattern": byteSizePattern.String()},
+				map[string]interface{}{"type": "integer", "minimum": -1},
+			},
+		}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), defs)}
+	case reflect.Map:
+		// The only maps of the pipeline are environment variables.
+		return map[string]interface{}{
+			"type":                 "object",
+			"propertyNames":        map[string]interface{}{"pattern": envVarName.String()},
+			"additionalProperties": typeSchema(t.Elem(), defs),
+		}
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), defs)
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // Recursive types reference the definition being built.
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	}
+	return map[string]interface{}{}
+}
+
+// attemptStatusSchema returns the schema of the statuses of a failed attempt,
+// as number or name.
+func attemptStatusSchema() map[string]interface{} {
+	var names []string
+	for _, c := range attemptStatuses {
+		names = append(names, strings.ReplaceAll(status.Text(c), " ", ""))
+	}
+	return map[string]interface{}{
+		"description": `Status of a failed attempt, as number or name, e.g. 11 or "TimeoutError".`,
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "integer", "enum": attemptStatuses},
+			map[string]interface{}{"type": "string", "enum": names},
+		},
+	}
+}
+
+// structSchema returns the schema of the struct type t, which rejects unknown
+// fields.
+func structSchema(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	props := make(map[string]interface{})
+	fields := jsonFields(t)
+	for _, name := range sortedKeys(fields) {
+		s := typeSchema(fields[name].Type, defs)
+		for k, v := range schemaFields[t.Name()+"."+name] {
+			s[k] = v
+		}
+		props[name] = s
+	}
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	switch t {
+	case reflect.TypeOf(Stage{}):
+		schema["required"] = []string{"name"}
+	case reflect.TypeOf(Service{}):
+		schema["required"] = []string{"name", "image"}
+	case reflect.TypeOf(Secret{}):
+		schema["required"] = []string{"name"}
+	}
+	return schema
+}
@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	got, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("want no error, got %q", err)
+	}
+	shipped, err := os.ReadFile("pipeline.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, shipped) {
+		t.Errorf("pipeline.schema.json is outdated, run go generate")
+	}
+
+	var schema struct {
+		Properties           map[string]json.RawMessage `json:"properties"`
+		AdditionalProperties bool                       `json:"additionalProperties"`
+		Defs                 map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(got, &schema); err != nil {
+		t.Fatalf("want valid JSON, got %q", err)
+	}
+	if schema.AdditionalProperties {
+		t.Errorf("want unknown fields rejected")
+	}
+	for _, f := range []string{"default-run-env", "stages", "error-handler", "default-timeout"} {
+		if _, ok := schema.Properties[f]; !ok {
+			t.Errorf("want pipeline property %s", f)
+		}
+	}
+	for _, f := range []string{"run-success-codes", "depends-on", "retry"} {
+		if _, ok := schema.Defs["Stage"].Properties[f]; !ok {
+			t.Errorf("want stage property %s", f)
+		}
+	}
+	for _, f := range []string{"Observers", "Runtime", "Logger", "-"} {
+		if _, ok := schema.Properties[f]; ok {
+			t.Errorf("want no property %s", f)
+		}
+	}
+}
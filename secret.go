@@ -0,0 +1,292 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// redacted replaces the secret values in results, logs and outputs.
+const redacted = "[REDACTED]"
+
+// Secret is a sensitive value, like a password or an API token, received by
+// the stages and services that list it as an environment variable. Secrets
+// never appear in command lines and their values are redacted from the
+// results, logs, outputs and error handler input.
+type Secret struct {
+	Name     string `json:"name" bson:"name,omitempty"`         // Name of the secret, which is also the name of the environment variable it is injected as.
+	Env      string `json:"env" bson:"env,omitempty"`           // Environment variable of the executor containing the value.
+	File     string `json:"file" bson:"file,omitempty"`         // File containing the value. A trailing newline is removed.
+	Provider string `json:"provider" bson:"provider,omitempty"` // Key of the value in the SecretProvider of the pipeline.
+}
+
+// SecretProvider looks up secret values, e.g. in a vault.
+type SecretProvider interface {
+	// Secret returns the value of the secret identified by key.
+	Secret(ctx context.Context, key string) (string, error)
+}
+
+// SecretProviderFunc is a SecretProvider calling the function.
+type SecretProviderFunc func(ctx context.Context, key string) (string, error)
+
+// Secret implements SecretProvider.
+func (f SecretProviderFunc) Secret(ctx context.Context, key string) (string, error) {
+	return f(ctx, key)
+}
+
+// secretSet holds the secret values of a pipeline execution. It is created
+// empty when the execution starts, so loggers and captures can refer to it,
+// and filled in the pipeline setup. A nil set has no secrets.
+type secretSet struct {
+	values map[string]string // Values, by secret name.
+	sorted []string          // Distinct non-empty values, longest first.
+}
+
+// resolve reads the values of the secrets.
+func (s *secretSet) resolve(ctx context.Context, secrets []Secret, provider SecretProvider) error {
+	values := make(map[string]string)
+	for _, secret := range secrets {
+		var v string
+		switch {
+		case secret.Env != "":
+			var ok bool
+			if v, ok = os.LookupEnv(secret.Env); !ok {
+				return fmt.Errorf("error reading secret %s: environment variable %s not set", secret.Name, secret.Env)
+			}
+		case secret.File != "":
+			b, err := os.ReadFile(secret.File)
+			if err != nil {
+				return fmt.Errorf("error reading secret %s: %w", secret.Name, err)
+			}
+			v = strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r")
+		case secret.Provider != "":
+			if provider == nil {
+				return fmt.Errorf("error reading secret %s: no secret provider set", secret.Name)
+			}
+			var err error
+			if v, err = provider.Secret(ctx, secret.Provider); err != nil {
+				return fmt.Errorf("error reading secret %s: %w", secret.Name, err)
+			}
+		}
+		values[secret.Name] = v
+	}
+	s.values = values
+	s.sorted = nil
+	for _, v := range values {
+		if v != "" && !slices.Contains(s.sorted, v) {
+			s.sorted = append(s.sorted, v)
+		}
+	}
+	// Longer values are redacted first, so values containing others are not
+	// partially revealed.
+	slices.SortFunc(s.sorted, func(a, b string) int { return len(b) - len(a) })
+	return nil
+}
+
+// env returns the values of the named secrets, by name.
+func (s *secretSet) env(names []string) map[string]string {
+	if s == nil || len(names) == 0 {
+		return nil
+	}
+	env := make(map[string]string)
+	for _, n := range names {
+		env[n] = s.values[n]
+	}
+	return env
+}
+
+// maxLen returns the length of the longest secret value.
+func (s *secretSet) maxLen() int {
+	if s == nil || len(s.sorted) == 0 {
+		return 0
+	}
+	return len(s.sorted[0])
+}
+
+// next returns the position and length of the first secret value in b, or -1
+// if there is none. The longest value is returned if more than one starts at
+// the same position.
+func (s *secretSet) next(b []byte) (int, int) {
+	pos, n := -1, 0
+	for _, v := range s.sorted {
+		if i := bytes.Index(b, []byte(v)); i >= 0 && (pos < 0 || i < pos) {
+			pos, n = i, len(v)
+		}
+	}
+	return pos, n
+}
+
+// redact replaces the secret values in str.
+func (s *secretSet) redact(str string) string {
+	if s == nil {
+		return str
+	}
+	for _, v := range s.sorted {
+		str = strings.ReplaceAll(str, v, redacted)
+	}
+	return str
+}
+
+// redactResult replaces the secret values in the command result.
+func (s *secretSet) redactResult(r CmdResult) CmdResult {
+	if s.maxLen() == 0 {
+		return r
+	}
+	r.Stdin = s.redact(r.Stdin)
+	r.Stdout = s.redact(r.Stdout)
+	r.Stderr = s.redact(r.Stderr)
+	r.Cmd = s.redact(r.Cmd)
+	r.CmdDir = s.redact(r.CmdDir)
+	if r.Env != nil {
+		env := make([]string, len(r.Env))
+		for i, e := range r.Env {
+			env[i] = s.redact(e)
+		}
+		r.Env = env
+	}
+	return r
+}
+
+// redactWriter replaces the secret values in the stream written to w. As a
+// value may be split across writes, the end of the stream that may be the
+// beginning of a value is only written on the next write or when flushed.
+type redactWriter struct {
+	w       io.Writer
+	secrets *secretSet
+	buf     []byte
+}
+
+// newRedactWriter returns w if there can be no secrets to redact.
+func newRedactWriter(w io.Writer, secrets *secretSet) io.Writer {
+	if w == nil || secrets == nil {
+		return w
+	}
+	return &redactWriter{w: w, secrets: secrets}
+}
+
+func (r *redactWriter) Write(p []byte) (int, error) {
+	max := r.secrets.maxLen()
+	if max == 0 && len(r.buf) == 0 {
+		return r.w.Write(p)
+	}
+	r.buf = append(r.buf, p...)
+	var out []byte
+	for {
+		i, n := r.secrets.next(r.buf)
+		// A value close to the end may be part of a longer one.
+		if i < 0 || len(r.buf)-i < max {
+			break
+		}
+		out = append(append(out, r.buf[:i]...), redacted...)
+		r.buf = r.buf[i+n:]
+	}
+	keep := min(len(r.buf), max-1)
+	out = append(out, r.buf[:len(r.buf)-keep]...)
+	r.buf = append([]byte(nil), r.buf[len(r.buf)-keep:]...)
+	if _, err := r.w.Write(out); err != nil {
+		return len(p), err
+	}
+	return len(p), nil
+}
+
+// Flush writes the content kept, redacted.
+func (r *redactWriter) Flush() error {
+	if len(r.buf) == 0 {
+		return nil
+	}
+	defer func() { r.buf = nil }()
+	_, err := io.WriteString(r.w, r.secrets.redact(string(r.buf)))
+	return err
+}
+
+// flush flushes w if it is a redactWriter.
+func flush(w io.Writer) {
+	if r, ok := w.(*redactWriter); ok {
+		r.Flush()
+	}
+}
+
+// redactHandler is a slog.Handler replacing the secret values in the message
+// and attributes of the records.
+type redactHandler struct {
+	slog.Handler
+	secrets *secretSet
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.secrets.maxLen() == 0 {
+		return h.Handler.Handle(ctx, r)
+	}
+	nr := slog.NewRecord(r.Time, r.Level, h.secrets.redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		nr.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, nr)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redactedAttrs[i] = h.redactAttr(a)
+	}
+	return redactHandler{h.Handler.WithAttrs(redactedAttrs), h.secrets}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{h.Handler.WithGroup(name), h.secrets}
+}
+
+// redactAttr replaces the secret values in the attribute. Values other than
+// strings and groups are replaced by their redacted text if it contains a
+// secret.
+func (h redactHandler) redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.secrets.redact(v.String()))
+	case slog.KindGroup:
+		var attrs []any
+		for _, ga := range v.Group() {
+			attrs = append(attrs, h.redactAttr(ga))
+		}
+		return slog.Group(a.Key, attrs...)
+	case slog.KindAny:
+		s := fmt.Sprint(v.Any())
+		if err, ok := v.Any().(error); ok {
+			s = err.Error()
+		}
+		if r := h.secrets.redact(s); r != s {
+			return slog.String(a.Key, r)
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// redactLogger returns a logger redacting the secret values from the records
+// of l.
+func redactLogger(l *slog.Logger, secrets *secretSet) *slog.Logger {
+	if secrets == nil {
+		return l
+	}
+	return slog.New(redactHandler{l.Handler(), secrets})
+}
+
+type secretsKey struct{}
+
+// withSecrets returns a context carrying the secrets, which are redacted from
+// the outputs captured by the runtimes.
+func withSecrets(ctx context.Context, secrets *secretSet) context.Context {
+	return context.WithValue(ctx, secretsKey{}, secrets)
+}
+
+// secretsFrom returns the secrets carried by ctx, or nil.
+func secretsFrom(ctx context.Context) *secretSet {
+	s, _ := ctx.Value(secretsKey{}).(*secretSet)
+	return s
+}
@@ -0,0 +1,138 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestSecrets returns the set of the secret values.
+func newTestSecrets(t *testing.T, values ...string) *secretSet {
+	t.Helper()
+	var secrets []Secret
+	for _, v := range values {
+		secrets = append(secrets, Secret{Name: "S" + v, Provider: v})
+	}
+	s := &secretSet{}
+	provider := SecretProviderFunc(func(_ context.Context, key string) (string, error) { return key, nil })
+	if err := s.resolve(context.Background(), secrets, provider); err != nil {
+		t.Fatalf("want no error resolving secrets, got %q", err)
+	}
+	return s
+}
+
+func TestSecretSetResolve(t *testing.T) {
+	t.Setenv("COLETOR_TOKEN", "t0k3n")
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("p4ssw0rd\r\n"), 0o600); err != nil {
+		t.Fatalf("want no error writing secret file, got %q", err)
+	}
+	provider := SecretProviderFunc(func(_ context.Context, key string) (string, error) {
+		if key == "missing" {
+			return "", errors.New("not found")
+		}
+		return "k3y", nil
+	})
+	testCases := []struct {
+		desc     string
+		secrets  []Secret
+		provider SecretProvider
+		want     map[string]string
+		wantErr  string
+	}{
+		{"Env", []Secret{{Name: "TOKEN", Env: "COLETOR_TOKEN"}}, nil, map[string]string{"TOKEN": "t0k3n"}, ""},
+		{"File", []Secret{{Name: "PASSWORD", File: file}}, nil, map[string]string{"PASSWORD": "p4ssw0rd"}, ""},
+		{"Provider", []Secret{{Name: "KEY", Provider: "key"}}, provider, map[string]string{"KEY": "k3y"}, ""},
+		{"Env not set", []Secret{{Name: "TOKEN", Env: "COLETOR_MISSING"}}, nil, nil, "environment variable COLETOR_MISSING not set"},
+		{"File not found", []Secret{{Name: "PASSWORD", File: file + ".missing"}}, nil, nil, "error reading secret PASSWORD"},
+		{"No provider", []Secret{{Name: "KEY", Provider: "key"}}, nil, nil, "no secret provider set"},
+		{"Provider error", []Secret{{Name: "KEY", Provider: "missing"}}, provider, nil, "not found"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := &secretSet{}
+			err := s.resolve(context.Background(), tc.secrets, tc.provider)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("want error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want no error, got %q", err)
+			}
+			if !reflect.DeepEqual(s.values, tc.want) {
+				t.Errorf("want values %v, got %v", tc.want, s.values)
+			}
+		})
+	}
+}
+
+func TestRedactWriter(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		writes []string
+		want   string
+	}{
+		{"No secret", []string{"hello ", "world"}, "hello world"},
+		{"Whole secret", []string{"token s3cr3t!"}, "token [REDACTED]!"},
+		{"Split secret", []string{"token s3c", "r3t!"}, "token [REDACTED]!"},
+		{"Longer secret", []string{"s3cr3t-lo", "ng!"}, "[REDACTED]!"},
+		{"Secret at the end", []string{"token s3cr3t"}, "token [REDACTED]"},
+		{"Repeated secret", []string{"s3cr3ts3cr3", "t"}, "[REDACTED][REDACTED]"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := newRedactWriter(&buf, newTestSecrets(t, "s3cr3t", "s3cr3t-long"))
+			for _, s := range tc.writes {
+				if n, err := io.WriteString(w, s); err != nil || n != len(s) {
+					t.Fatalf("want %d bytes written, got %d (%v)", len(s), n, err)
+				}
+			}
+			flush(w)
+			if buf.String() != tc.want {
+				t.Errorf("want %q, got %q", tc.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestRedactLogger(t *testing.T) {
+	var buf bytes.Buffer
+	secrets := newTestSecrets(t, "s3cr3t")
+	l := redactLogger(slog.New(slog.NewJSONHandler(&buf, nil)), secrets)
+	l.With("cmd", "docker run --env TOKEN=s3cr3t").WithGroup("g").Info("token s3cr3t",
+		"error", errors.New("bad token s3cr3t"),
+		slog.Group("nested", "value", "s3cr3t"),
+		"count", 1)
+	out := buf.String()
+	if strings.Contains(out, "s3cr3t") {
+		t.Errorf("want secret redacted from logs, got %s", out)
+	}
+	if strings.Count(out, redacted) != 4 || !strings.Contains(out, `"count":1`) {
+		t.Errorf("want secrets replaced and other attributes kept, got %s", out)
+	}
+}
+
+func TestCapture_Secrets(t *testing.T) {
+	ctx := withSecrets(context.Background(), newTestSecrets(t, "s3cr3t"))
+	c := newCapture(ctx, OutputLimit{MaxSize: 4, Dir: t.TempDir()}, "stage")
+	for _, d := range []string{"token s3", "cr3t"} {
+		io.WriteString(c, d)
+	}
+	if c.String() != "toke" {
+		t.Errorf("want preview %q, got %q", "toke", c.String())
+	}
+	b, err := os.ReadFile(c.File().Path)
+	if err != nil || string(b) != "token [REDACTED]" {
+		t.Errorf("want secret redacted from file, got %q (%v)", b, err)
+	}
+}
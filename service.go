@@ -0,0 +1,192 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultReadyInterval = time.Second
+	defaultReadyTimeout  = time.Minute
+)
+
+// errPortNotReachable is returned by the port readiness checks, which dial
+// the service from the executor host.
+var errPortNotReachable = errors.New("port not reachable from the executor host")
+
+// Service is a long-running container used by the stages while they run, like
+// a headless browser or a database. Services are started in the pipeline
+// setup, before any stage, and stopped in its teardown. The stages reach a
+// service by its name in the service network.
+type Service struct {
+	Name      string            `json:"name" bson:"name,omitempty"`           // Service's name, which is its host name in the network.
+	Image     string            `json:"image" bson:"image,omitempty"`         // Image of the service container, e.g. ghcr.io/browserless/chromium.
+	Command   []string          `json:"command" bson:"command,omitempty"`     // Arguments of the container, replacing the command of the image, if set.
+	Env       map[string]string `json:"env" bson:"env,omitempty"`             // Environment variables of the container.
+	Secrets   []string          `json:"secrets" bson:"secrets,omitempty"`     // Names of the pipeline secrets passed to the container as environment variables.
+	Network   string            `json:"network" bson:"network,omitempty"`     // Network of the container, which must be a user-defined network or host. Defaults to the DefaultNetwork in pipeline's definition.
+	Mounts    []Mount           `json:"mounts" bson:"mounts,omitempty"`       // Filesystems mounted in the container. The Mounts in pipeline's definition are not mounted.
+	Resources Resources         `json:"resources" bson:"resources,omitempty"` // Limits of the host resources used by the container. The DefaultResources in pipeline's definition are not applied.
+	Ready     ReadinessCheck    `json:"ready" bson:"ready,omitempty"`         // How to check whether the service is ready. The stages start as soon as the container starts if not set.
+}
+
+// ReadinessCheck tells whether a service is ready to be used. If both Port and
+// Command are set, both must succeed.
+type ReadinessCheck struct {
+	Port     int      `json:"port" bson:"port,omitempty"`         // TCP port of the service that accepts connections once it is ready. It is dialed from the executor host, which can not reach services in internal networks, with Docker Desktop or when the executor runs in a container; use Command in those cases.
+	Command  []string `json:"command" bson:"command,omitempty"`   // Command executed in the service container that exits with 0 once it is ready.
+	Interval Duration `json:"interval" bson:"interval,omitempty"` // Wait between checks, e.g. "500ms". Defaults to 1s.
+	Timeout  Duration `json:"timeout" bson:"timeout,omitempty"`   // Maximum wait for the service to be ready, e.g. "2m". Defaults to 1m.
+}
+
+// ServiceResult describes the execution of a service.
+type ServiceResult struct {
+	Service    Service   `json:"service" bson:"service,omitempty"`       // Service executed, with its defaults resolved.
+	Container  string    `json:"container" bson:"container,omitempty"`   // Name of the service container.
+	PullResult CmdResult `json:"pullResult" bson:"pullResult,omitempty"` // Pull of the service image.
+	ReadyTime  time.Time `json:"ready" bson:"ready,omitempty"`           // Time the service has been found ready. Zero if it never was.
+	RunResult  CmdResult `json:"runResult" bson:"runResult,omitempty"`   // Output and exit status of the service container, collected when it is stopped.
+}
+
+// network returns the network of the service container.
+func (s Service) network(pipeline Pipeline) string {
+	if s.Network == "" {
+		return pipeline.DefaultNetwork
+	}
+	return s.Network
+}
+
+// startServices pulls the images of the services and starts them, waiting
+// for each one to be ready. The result of each service is appended to results
+// as soon as its image is pulled, even if it fails to start or to get ready,
+// so it can be reported and stopped.
+func (p *Pipeline) startServices(ctx context.Context, results *[]ServiceResult) error {
+	logger := loggerFrom(ctx)
+	for _, s := range p.Services {
+		s.Network = s.network(*p)
+		sr := ServiceResult{Service: s, Container: containerName(s.Name)}
+		logger.Info("pulling service image", "service", s.Name, "image", s.Image)
+		var err error
+		sr.PullResult, err = p.runtime().Pull(ctx, s.Image, "", OutputLimit{MaxSize: p.MaxOutputSize, Dir: p.OutputDir})
+		sr.PullResult = secretsFrom(ctx).redactResult(sr.PullResult)
+		if err != nil {
+			*results = append(*results, sr)
+			return fmt.Errorf("error pulling image of service %s: %w", s.Name, err)
+		}
+		logger.Info("starting service", "service", s.Name, "container", sr.Container, "network", s.Network)
+		sr.RunResult.StartTime = time.Now()
+		err = p.runtime().StartService(ctx, ServiceSpec{
+			Name:      sr.Container,
+			Alias:     s.Name,
+			Image:     s.Image,
+			Command:   s.Command,
+			Env:       s.Env,
+			Secrets:   secretsFrom(ctx).env(s.Secrets),
+			Network:   s.Network,
+			Mounts:    s.Mounts,
+			Resources: s.Resources,
+		})
+		if err != nil {
+			// The container may have been created anyway.
+			*results = append(*results, sr)
+			return fmt.Errorf("error starting service %s: %w", s.Name, err)
+		}
+		*results = append(*results, sr)
+		if err := p.waitReady(ctx, s, sr.Container); err != nil {
+			return fmt.Errorf("service %s not ready: %w", s.Name, err)
+		}
+		(*results)[len(*results)-1].ReadyTime = time.Now()
+		logger.Info("service ready", "service", s.Name)
+	}
+	return nil
+}
+
+// waitReady checks the service readiness until it succeeds or times out.
+func (p *Pipeline) waitReady(ctx context.Context, s Service, container string) error {
+	check := s.Ready
+	if check.Port == 0 && len(check.Command) == 0 {
+		return nil
+	}
+	interval, timeout := time.Duration(check.Interval), time.Duration(check.Timeout)
+	if interval <= 0 {
+		interval = defaultReadyInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		err := p.checkReady(ctx, s, container, interval)
+		if err == nil {
+			return nil
+		}
+		loggerFrom(ctx).Debug("service not ready yet", "service", s.Name, errAttr(err))
+		select {
+		case <-ctx.Done():
+			if errors.Is(err, errPortNotReachable) {
+				return fmt.Errorf("%w after %s: %w (the executor host may not reach the service network, e.g. if it is internal, with Docker Desktop or when the executor runs in a container; use a readiness command instead)", ctx.Err(), timeout, err)
+			}
+			return fmt.Errorf("%w after %s: %w", ctx.Err(), timeout, err)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkReady executes the readiness check of the service once.
+func (p *Pipeline) checkReady(ctx context.Context, s Service, container string, dialTimeout time.Duration) error {
+	if s.Ready.Port != 0 {
+		host := "127.0.0.1"
+		if s.Network != HostNetwork {
+			ip, err := p.runtime().ServiceIP(ctx, container, s.Network)
+			if err != nil {
+				return err
+			}
+			host = ip
+		}
+		d := net.Dialer{Timeout: dialTimeout}
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(s.Ready.Port)))
+		if err != nil {
+			return fmt.Errorf("%w: %w", errPortNotReachable, err)
+		}
+		conn.Close()
+	}
+	if len(s.Ready.Command) > 0 {
+		code, err := p.runtime().ExecService(ctx, container, s.Ready.Command)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return fmt.Errorf("readiness command exited with status %d", code)
+		}
+	}
+	return nil
+}
+
+// stopServices stops the service containers, in the reverse order they were
+// started, collecting their output and exit status. Every service is stopped
+// even if stopping another one fails.
+func (p *Pipeline) stopServices(ctx context.Context, results []ServiceResult) []error {
+	var errs []error
+	limit := OutputLimit{MaxSize: p.MaxOutputSize, Dir: p.OutputDir}
+	for i := len(results) - 1; i >= 0; i-- {
+		sr := &results[i]
+		// Services whose image could not be pulled were never started.
+		if sr.RunResult.StartTime.IsZero() {
+			continue
+		}
+		loggerFrom(ctx).Info("stopping service", "service", sr.Service.Name, "container", sr.Container)
+		start := sr.RunResult.StartTime
+		r, err := p.runtime().StopService(ctx, sr.Container, limit)
+		r.StartTime = start
+		sr.RunResult = secretsFrom(ctx).redactResult(r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error stopping service %s: %w", sr.Service.Name, err))
+		}
+	}
+	return errs
+}
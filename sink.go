@@ -0,0 +1,195 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Steps of the stage execution. Only the output of the build and run steps is
+// sent to the sinks.
+const (
+	setupStep    = "setup"
+	buildStep    = "build"
+	runStep      = "run"
+	teardownStep = "teardown"
+)
+
+// OutputSink receives the output of the stage builds and runs while they are
+// executed, e.g. to follow long-running stages. The output is captured in
+// the stage results regardless of the sinks.
+type OutputSink interface {
+	// Open returns the writers receiving the stdout and stderr of the step
+	// ("build" or "run") of the stage. Nil writers are ignored and writers
+	// implementing io.Closer are closed when the step finishes. Retried runs
+	// open the sink again.
+	Open(stage Stage, step string) (stdout, stderr io.Writer, err error)
+}
+
+// consoleMu prevents lines of stages running in parallel from being mixed.
+var consoleMu sync.Mutex
+
+// ConsoleSink writes the output to Writer, line by line, prefixed by the
+// stage name and step, e.g. "[coleta run] ".
+type ConsoleSink struct {
+	Writer io.Writer // Defaults to os.Stderr.
+}
+
+// Open implements OutputSink.
+func (c ConsoleSink) Open(stage Stage, step string) (io.Writer, io.Writer, error) {
+	w := c.Writer
+	if w == nil {
+		w = os.Stderr
+	}
+	stdout := &prefixWriter{w: w, prefix: fmt.Sprintf("[%s %s] ", stage.Name, step)}
+	stderr := &prefixWriter{w: w, prefix: fmt.Sprintf("[%s %s stderr] ", stage.Name, step)}
+	return stdout, stderr, nil
+}
+
+// prefixWriter writes complete lines prefixed. The incomplete last line is
+// written when it is closed.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return len(b), err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+func (p *prefixWriter) Close() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	defer func() { p.buf = nil }()
+	return p.writeLine(append(p.buf, '\n'))
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+	_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+	return err
+}
+
+// FileSink appends the output of each stage to files in Dir, named after the
+// stage container ID: <container-id>.stdout.log and <container-id>.stderr.log.
+// The build and run outputs, as well as the output of every run attempt, go
+// to the same files.
+type FileSink struct {
+	Dir string // Directory of the log files. Created if it does not exist.
+}
+
+// Open implements OutputSink.
+func (f FileSink) Open(stage Stage, _ string) (io.Writer, io.Writer, error) {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("error creating log dir(%s): %w", f.Dir, err)
+	}
+	stdout, err := os.OpenFile(filepath.Join(f.Dir, stage.ContainerID+".stdout.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening stdout log file: %w", err)
+	}
+	stderr, err := os.OpenFile(filepath.Join(f.Dir, stage.ContainerID+".stderr.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		stdout.Close()
+		return nil, nil, fmt.Errorf("error opening stderr log file: %w", err)
+	}
+	return stdout, stderr, nil
+}
+
+// SinkFunc is an OutputSink calling the function with each chunk of output
+// produced. The stream is "stdout" or "stderr". The data must not be retained.
+// Stages running in parallel may call it concurrently.
+type SinkFunc func(stage, step, stream string, data []byte)
+
+// Open implements OutputSink.
+func (f SinkFunc) Open(stage Stage, step string) (io.Writer, io.Writer, error) {
+	return funcWriter{f, stage.Name, step, "stdout"}, funcWriter{f, stage.Name, step, "stderr"}, nil
+}
+
+type funcWriter struct {
+	f                   SinkFunc
+	stage, step, stream string
+}
+
+func (w funcWriter) Write(b []byte) (int, error) {
+	w.f(w.stage, w.step, w.stream, b)
+	return len(b), nil
+}
+
+// sinkGroup writes to every sink, ignoring errors.
+type sinkGroup []io.Writer
+
+func (g sinkGroup) Write(b []byte) (int, error) {
+	for _, w := range g {
+		w.Write(b)
+	}
+	return len(b), nil
+}
+
+// openSinks opens the pipeline sinks for the step of the stage. It returns the
+// writers to be used in the BuildSpec or RunSpec, which are nil if there are
+// no sinks and redact the secrets carried by ctx, and a function closing them.
+// Sinks failing to open are only logged.
+func (stage *Stage) openSinks(ctx context.Context, step string) (io.Writer, io.Writer, func()) {
+	var stdouts, stderrs sinkGroup
+	var closers []io.Closer
+	for _, s := range stage.sinks {
+		stdout, stderr, err := s.Open(*stage, step)
+		if err != nil {
+			stage.log().Warn("error opening output sink, proceeding", stepKey, step, errAttr(err))
+			continue
+		}
+		for _, w := range []io.Writer{stdout, stderr} {
+			if c, ok := w.(io.Closer); ok {
+				closers = append(closers, c)
+			}
+		}
+		if stdout != nil {
+			stdouts = append(stdouts, stdout)
+		}
+		if stderr != nil {
+			stderrs = append(stderrs, stderr)
+		}
+	}
+	var stdout, stderr io.Writer
+	if len(stdouts) > 0 {
+		stdout = newRedactWriter(stdouts, secretsFrom(ctx))
+	}
+	if len(stderrs) > 0 {
+		stderr = newRedactWriter(stderrs, secretsFrom(ctx))
+	}
+	closeSinks := func() {
+		flush(stdout)
+		flush(stderr)
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+	return stdout, stderr, closeSinks
+}
+
+// outputSinks returns the sinks receiving the output of the pipeline stages.
+func (p *Pipeline) outputSinks() []OutputSink {
+	sinks := p.OutputSinks
+	if p.LogDir != "" {
+		sinks = append(sinks[:len(sinks):len(sinks)], FileSink{Dir: p.LogDir})
+	}
+	return sinks
+}
@@ -0,0 +1,23 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestConsoleSink(t *testing.T) {
+	var b bytes.Buffer
+	stdout, stderr, err := ConsoleSink{Writer: &b}.Open(Stage{Name: "coleta"}, runStep)
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	io.WriteString(stdout, "first line\nsec")
+	io.WriteString(stderr, "error\n")
+	io.WriteString(stdout, "ond line\nlast")
+	stdout.(io.Closer).Close()
+	want := "[coleta run] first line\n[coleta run stderr] error\n[coleta run] second line\n[coleta run] last\n"
+	if b.String() != want {
+		t.Errorf("want %q, got %q", want, b.String())
+	}
+}
@@ -1,10 +1,13 @@
 package executor
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"time"
 
@@ -19,10 +22,12 @@ const (
 type StageExecutionResult struct {
 	Stage          Stage       `json:"stage" bson:"stage,omitempty"`                   // Name of stage.
 	CommitID       string      `json:"commit" bson:"commit,omitempty"`                 // Commit of the stage repo when executing the stage.
+	ImageID        string      `json:"imageId" bson:"imageId,omitempty"`               // ID of the image executed.
 	StartTime      time.Time   `json:"start" bson:"start,omitempty"`                   // Time at start of stage.
 	FinalTime      time.Time   `json:"end" bson:"end,omitempty"`                       // Time at the end of stage.
 	BuildResult    CmdResult   `json:"buildResult" bson:"buildResult,omitempty"`       // Build result.
-	RunResult      CmdResult   `json:"runResult" bson:"runResult,omitempty"`           // Run result.
+	RunResult      CmdResult   `json:"runResult" bson:"runResult,omitempty"`           // Run result. If the run has been retried, the result of the last attempt.
+	Attempts       []CmdResult `json:"attempts" bson:"attempts,omitempty"`             // Results of every run attempt, in order.
 	SetupResult    CmdResult   `json:"setupResult" bson:"setupResult,omitempty"`       // Setup result.
 	TeardownResult CmdResult   `json:"teardownResult" bson:"teardownResult,omitempty"` // Teardown result.
 	Status         status.Code `json:"status" bson:"status,omitempty"`                 // Final execution status of the stage.
@@ -34,6 +39,13 @@ type Stage struct {
 	Dir               string            `json:"dir" bson:"dir,omitempt"`                                   // Directory to be concatenated with default base directory or with the base directory specified here in 'BaseDir'. This field is used to name the image built.
 	Image             string            `json:"image" bson:"image,omitempt"`                               // Docker image ID, e.g., ghcr.io/dadosjusbr/coletor-cnj:main
 	Repo              string            `json:"repo" bson:"repo,omitempt"`                                 // Repository URL from where to clone the pipeline stage.
+	Ref               string            `json:"ref" bson:"ref,omitempty"`                                  // Branch or tag of the repository to check out. Defaults to the default branch.
+	Commit            string            `json:"commit" bson:"commit,omitempty"`                            // Commit of the repository to check out, possibly abbreviated. Defaults to the Ref head. In the result, the commit checked out.
+	GitAuth           GitAuth           `json:"git-auth" bson:"git-auth,omitempty"`                        // Credentials used to clone the repository. This field overwrites the DefaultGitAuth in pipeline's definition.
+	CloneDepth        int               `json:"clone-depth" bson:"clone-depth,omitempty"`                  // Number of commits fetched when cloning the repository. Full history if not set. Ignored, with a warning, when cloning from the GitCacheDir.
+	SingleBranch      bool              `json:"single-branch" bson:"single-branch,omitempty"`              // Fetch only the Ref, or the default branch, when cloning the repository. Ignored, with a warning, when cloning from the GitCacheDir.
+	Submodules        bool              `json:"submodules" bson:"submodules,omitempty"`                    // Clone the submodules of the repository, recursively.
+	SparseCheckout    bool              `json:"sparse-checkout" bson:"sparse-checkout,omitempty"`          // Check out only Dir of the repository, and the submodules within it.
 	BaseDir           string            `json:"base-dir" bson:"base-dir,omitempt"`                         // Base directory for the stage. This field overwrites the DefaultBaseDir in pipeline's definition.
 	BuildEnv          map[string]string `json:"build-env" bson:"build-env,omitempt"`                       // Variables to be used in the stage build. They will be concatenated with the default variables defined in the pipeline, overwriting them if repeated.
 	RunEnv            map[string]string `json:"run-env" bson:"run-env,omitempt"`                           // Variables to be used in the stage run. They will be concatenated with the default variables defined in the pipeline, overwriting them if repeated.
@@ -42,104 +54,141 @@ type Stage struct {
 	VolumeName        string            `json:"volume-name" bson:"volume-name,omitempty"`                  // Name of the shared volume.
 	VolumeDir         string            `json:"volume-dir" bson:"volume-dir,omitempty"`                    // Directory of the shared volume.
 	RunSuccessCodes   []int             `json:"run-success-codes" bson:"run-success-codes,omitempty"`      // List of exit codes that mean the stage has been successfully excecuted.
+	Timeout           Duration          `json:"timeout" bson:"timeout,omitempty"`                          // Maximum duration of the stage setup, build and run, e.g. "2h". This field overwrites the DefaultTimeout in pipeline's definition.
+	DependsOn         []string          `json:"depends-on" bson:"depends-on,omitempty"`                    // Names of the stages that must finish before this one. If no stage in the pipeline sets it, each stage depends on the previous one.
+	Retry             RetryPolicy       `json:"retry" bson:"retry,omitempty"`                              // When to retry the stage run if it fails.
+	Resources         Resources         `json:"resources" bson:"resources,omitempty"`                      // Limits of the host resources used by the stage container. Fields not set are taken from the DefaultResources in pipeline's definition.
+	Mounts            []Mount           `json:"mounts" bson:"mounts,omitempty"`                            // Filesystems mounted in the stage container, besides the shared volume. They are added to the Mounts in pipeline's definition, replacing the ones with the same target. The volumes of volume mounts are created in the pipeline setup and removed in its teardown.
+	Network           string            `json:"network" bson:"network,omitempty"`                          // Network of the stage container: none, bridge, host or the name of a pipeline network or of an existing one. This field overwrites the DefaultNetwork in pipeline's definition.
+	Secrets           []string          `json:"secrets" bson:"secrets,omitempty"`                          // Names of the pipeline secrets passed to the stage run as environment variables.
 
-	internalID string // Stage internal identification.
-	index      int    // Stage position in the pipeline.
+	internalID string       // Stage internal identification.
+	index      int          // Stage position in the pipeline.
+	commitID   string       // Commit of the cloned repo, if any.
+	sinks      []OutputSink // Sinks receiving the build and run output.
+	limit      OutputLimit  // Bounds the build and run output kept in memory.
+	logger     *slog.Logger // Logger with the stage attributes.
 }
 
-func (stage *Stage) run(index int, pipeline Pipeline, stdin string) (StageExecutionResult, error) {
-	var ser StageExecutionResult
-
+// run sets up, builds, runs and tears down the stage. If the stage has a
+// timeout, the setup, build and run must finish before it expires.
+func (stage *Stage) run(ctx context.Context, index int, pipeline Pipeline, stdin stageInput) (ser StageExecutionResult, err error) {
 	stage.index = index
 	stage.internalID = fmt.Sprintf("%s/%s", pipeline.Name, stage.Name)
+	stage.sinks = pipeline.outputSinks()
+	stage.limit = OutputLimit{MaxSize: pipeline.MaxOutputSize, Dir: pipeline.OutputDir}
+	stage.logger = pipeline.logger().With(stageKey, stage.Name)
 
-	// 'index+1' because the index starts from 0.
-	log.Printf("## Executing Stage %s [%d/%d]\n\n", stage.internalID, stage.index+1, len(pipeline.Stages))
+	obs := pipeline.observer()
 
-	defer func(ser *StageExecutionResult) {
+	defer func() {
 		ser.FinalTime = time.Now()
-	}(&ser)
+	}()
 
 	// AQUI FIREMAN:
 	// Garantir que o setup e o tear down são corretamente referenciados dentro do SER, dessa forma, eles poderão
 	// Opções: campo SetupResult e TeardownResult string
 	// Precisa adicionar uma variável que permita identificar se houve sucesso ou não
 	ser.StartTime = time.Now()
+	ser.Stage = *stage
+	timeout := stage.Timeout
+	if timeout == 0 {
+		timeout = pipeline.DefaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout))
+		defer cancel()
+	}
+	// stepStatus returns the status of a failed step, taking the stage
+	// timeout into account.
+	stepStatus := func(code status.Code) status.Code {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return status.TimeoutError
+		}
+		return code
+	}
 	{
-		log.Printf("### [%s] Setting up ...\n", stage.internalID)
-		c, err := stage.setup(pipeline)
+		start := time.Now()
+		obs.StageSetupStarted(stage.event(pipeline, ser, nil))
+		c, err := stage.setup(stage.stepContext(ctx, setupStep), pipeline)
 		ser.SetupResult = c
 		if err != nil {
-			ser.Status = status.SetupError
-			log.Printf("### Error setting up stage %s:%v\n\n", stage.internalID, err)
+			ser.Status = stepStatus(status.SetupError)
+			obs.StageSetupFinished(stage.finished(pipeline, ser, start, err))
 			return ser, err
 		}
-		log.Printf("### [%s] Set up completed successfully!\n\n", stage.internalID)
+		ser.Stage = *stage
+		ser.CommitID = stage.commitID
+		stage.logger = stage.logger.With(containerIDKey, stage.ContainerID)
+		if stage.commitID != "" {
+			stage.logger = stage.logger.With(commitKey, stage.commitID)
+		}
+		obs.StageSetupFinished(stage.finished(pipeline, ser, start, nil))
 	}
-	ser.Stage = *stage
 	{
-		log.Printf("### [%s] Building/Pulling image %s from %s ...\n", stage.internalID, stage.ContainerID, filepath.Join(stage.BaseDir, stage.Dir))
-		c, err := stage.buildImage()
+		start := time.Now()
+		buildCtx := stage.stepContext(ctx, buildStep)
+		obs.StageBuildStarted(stage.event(pipeline, ser, nil))
+		c, err := stage.buildImage(buildCtx, pipeline.runtime())
 		ser.BuildResult = c
 		if err != nil {
-			ser.Status = status.BuildError
-			log.Printf("### Error building stage %s:%v\n\n", stage.internalID, err)
+			ser.Status = stepStatus(status.BuildError)
+			obs.StageBuildFinished(stage.finished(pipeline, ser, start, err))
 			return ser, err
 		}
-		log.Printf("### [%s] Image %s built/pulled sucessfully!\n\n", stage.internalID, stage.ContainerID)
+		// The image ID is informative, so failing to get it does not fail the stage.
+		if id, err := pipeline.runtime().ImageID(buildCtx, stage.image()); err != nil {
+			loggerFrom(buildCtx).Warn("error getting image ID, proceeding", errAttr(err))
+		} else {
+			ser.ImageID = id
+		}
+		obs.StageBuildFinished(stage.finished(pipeline, ser, start, nil))
 	}
 	{
-		log.Printf("### [%s] Running ...\n", stage.internalID)
-		c, err := stage.runImage(stdin)
-		ser.RunResult = c
+		start := time.Now()
+		obs.StageRunStarted(stage.event(pipeline, ser, nil))
+		attempts, code, err := stage.runAttempts(stage.stepContext(ctx, runStep), pipeline.runtime(), stdin)
+		ser.Attempts = attempts
+		ser.RunResult = attempts[len(attempts)-1]
 		if err != nil {
-			ser.Status = status.RunError
-			log.Printf("### Error running stage %s:%v\n\n", stage.internalID, err)
+			ser.Status = stepStatus(code)
+			obs.StageRunFinished(stage.finished(pipeline, ser, start, err))
 			return ser, err
 		}
-		log.Printf("### [%s] Run completed successfully!\n\n", stage.internalID)
+		obs.StageRunFinished(stage.finished(pipeline, ser, start, nil))
 	}
 	{
-		log.Printf("### [%s] Tearing down ...\n", stage.internalID)
+		start := time.Now()
+		obs.StageTeardownStarted(stage.event(pipeline, ser, nil))
 		c, err := stage.teardown()
 		ser.TeardownResult = c
 		if err != nil {
 			ser.Status = status.TeardownError
-			log.Printf("### Error tearing down stage %s:%v\n\n", stage.internalID, err)
+			obs.StageTeardownFinished(stage.finished(pipeline, ser, start, err))
 			return ser, err
 		}
-		log.Printf("### [%s] Tear down completed successfully!\n\n", stage.internalID)
+		ser.Status = status.OK
+		obs.StageTeardownFinished(stage.finished(pipeline, ser, start, nil))
 	}
-	// 'index+1' because the index starts from 0.
-	log.Printf("## Stage %s [%d/%d] executed successfully!\n\n", stage.internalID, stage.index, len(pipeline.Stages))
-
-	ser.Status = status.OK
 	return ser, nil
 }
 
-func (stage *Stage) setup(pipeline Pipeline) (CmdResult, error) {
+func (stage *Stage) setup(ctx context.Context, pipeline Pipeline) (CmdResult, error) {
+	if err := ctx.Err(); err != nil {
+		return CmdResult{
+			Stderr:     err.Error(),
+			ExitStatus: int(status.SystemError),
+		}, fmt.Errorf("error setting up stage %s: %w", stage.Name, err)
+	}
 	// Even though this stage uses libraries to execute its commands, we wrap
 	// those in a CmdResult to comply with the stage execution steps interface.
-	if stage.BaseDir == "" {
-		stage.BaseDir = pipeline.DefaultBaseDir
-	}
-	if stage.ContainerID == "" {
-		stage.ContainerID = strings.ReplaceAll(strings.ToLower(stage.Name), " ", "-")
-	}
-	if stage.VolumeName == "" {
-		stage.VolumeName = pipeline.VolumeName
-	}
-	if stage.VolumeDir == "" {
-		stage.VolumeDir = pipeline.VolumeDir
-	}
-	if len(stage.RunSuccessCodes) == 0 {
-		stage.RunSuccessCodes = []int{defaultRunSuccessCode}
-	}
+	stage.setDefaults(pipeline)
 
 	// if there the field "repo" is set for the stage, clone it and update
 	// its baseDir and commit id.
 	if stage.Repo != "" {
-		rr, err := setupRepo(stage.Repo, stage.BaseDir, stage.Dir)
+		rr, err := setupRepo(ctx, stage.repoSpec(pipeline), stage.BaseDir, stage.Dir)
 		if err != nil {
 			e := fmt.Errorf("error in setting up repo(%s) for stage %s setup: %w", stage.Repo, stage.Name, err)
 			return CmdResult{
@@ -147,37 +196,108 @@ func (stage *Stage) setup(pipeline Pipeline) (CmdResult, error) {
 				ExitStatus: int(status.SystemError),
 			}, e
 		}
-		// specifying commit id as environment variable.
-		if stage.RepoVersionEnvVar != "" {
-			if stage.BuildEnv == nil {
-				stage.BuildEnv = make(map[string]string)
-			}
-			stage.BuildEnv[stage.RepoVersionEnvVar] = rr.commitID
-			if stage.RunEnv == nil {
-				stage.RunEnv = make(map[string]string)
-			}
-			stage.RunEnv[stage.RepoVersionEnvVar] = rr.commitID
-		}
+		stage.commitID = rr.commitID
+		// The stage in the result records the commit actually executed.
+		stage.Commit = rr.commitID
+		stage.setRepoVersion(rr.commitID)
 		stage.BaseDir = rr.dir
 	}
 
-	// Fill up enviroment variable maps.
-	stage.BuildEnv = mergeEnv(pipeline.DefaultBuildEnv, stage.BuildEnv)
-	stage.RunEnv = mergeEnv(pipeline.DefaultRunEnv, stage.RunEnv)
+	stage.mergeDefaultEnv(pipeline)
 	return CmdResult{
 		ExitStatus: int(status.OK),
 	}, nil
 }
 
-func (stage *Stage) buildImage() (CmdResult, error) {
+// repoSpec describes how the stage repository is cloned.
+func (stage *Stage) repoSpec(pipeline Pipeline) repoSpec {
+	repo := repoSpec{
+		url:          stage.Repo,
+		ref:          stage.Ref,
+		commit:       stage.Commit,
+		auth:         stage.GitAuth,
+		cacheDir:     pipeline.GitCacheDir,
+		depth:        stage.CloneDepth,
+		singleBranch: stage.SingleBranch,
+		submodules:   stage.Submodules,
+	}
+	if stage.SparseCheckout {
+		repo.sparseDirs = []string{filepath.ToSlash(filepath.Clean(stage.Dir))}
+	}
+	return repo
+}
+
+// setDefaults fills the stage fields not set with the pipeline defaults.
+func (stage *Stage) setDefaults(pipeline Pipeline) {
+	if stage.BaseDir == "" {
+		stage.BaseDir = pipeline.DefaultBaseDir
+	}
+	stage.ContainerID = stage.containerID()
+	if stage.VolumeName == "" {
+		stage.VolumeName = pipeline.VolumeName
+	}
+	if stage.VolumeDir == "" {
+		stage.VolumeDir = pipeline.VolumeDir
+	}
+	if len(stage.RunSuccessCodes) == 0 {
+		stage.RunSuccessCodes = []int{defaultRunSuccessCode}
+	}
+	if stage.Timeout == 0 {
+		stage.Timeout = pipeline.DefaultTimeout
+	}
+	if reflect.ValueOf(stage.GitAuth).IsZero() {
+		stage.GitAuth = pipeline.DefaultGitAuth
+	}
+	stage.Resources = stage.Resources.withDefaults(pipeline.DefaultResources)
+	stage.Mounts = mergeMounts(pipeline.Mounts, stage.Mounts)
+	if stage.Network == "" {
+		stage.Network = pipeline.DefaultNetwork
+	}
+}
+
+// setRepoVersion specifies the commit id of the stage repo as environment
+// variable of the build and run, if RepoVersionEnvVar is set.
+func (stage *Stage) setRepoVersion(commitID string) {
+	if stage.RepoVersionEnvVar == "" {
+		return
+	}
+	if stage.BuildEnv == nil {
+		stage.BuildEnv = make(map[string]string)
+	}
+	stage.BuildEnv[stage.RepoVersionEnvVar] = commitID
+	if stage.RunEnv == nil {
+		stage.RunEnv = make(map[string]string)
+	}
+	stage.RunEnv[stage.RepoVersionEnvVar] = commitID
+}
+
+// mergeDefaultEnv fills up the environment variable maps with the pipeline
+// defaults.
+func (stage *Stage) mergeDefaultEnv(pipeline Pipeline) {
+	stage.BuildEnv = mergeEnv(pipeline.DefaultBuildEnv, stage.BuildEnv)
+	stage.RunEnv = mergeEnv(pipeline.DefaultRunEnv, stage.RunEnv)
+}
+
+func (stage *Stage) buildImage(ctx context.Context, rt ContainerRuntime) (CmdResult, error) {
 	var err error
 	var r CmdResult
+	dir := filepath.Join(stage.BaseDir, stage.Dir)
 	switch {
 	case stage.Image != "":
-		r, err = pullImage(stage.Image, stage.BaseDir, stage.Dir)
+		r, err = rt.Pull(ctx, stage.Image, dir, stage.limit)
 	default:
-		r, err = buildImage(stage.ContainerID, stage.BaseDir, stage.Dir, stage.BuildEnv)
+		stdout, stderr, closeSinks := stage.openSinks(ctx, buildStep)
+		defer closeSinks()
+		r, err = rt.Build(ctx, BuildSpec{
+			Image:  stage.ContainerID,
+			Dir:    dir,
+			Env:    stage.BuildEnv,
+			Stdout: stdout,
+			Stderr: stderr,
+			Limit:  stage.limit,
+		})
 	}
+	r = secretsFrom(ctx).redactResult(r)
 	if err != nil {
 		return r, fmt.Errorf("error when building image: %w", err)
 	}
@@ -187,24 +307,107 @@ func (stage *Stage) buildImage() (CmdResult, error) {
 	return r, nil
 }
 
-func (stage *Stage) runImage(stdin string) (CmdResult, error) {
-	image := stage.Image
-	if image == "" {
-		image = stage.ContainerID
+// runAttempts runs the stage image, retrying according to the stage retry
+// policy. It returns the result of every attempt and, if the last attempt
+// failed, its status and error.
+func (stage *Stage) runAttempts(ctx context.Context, rt ContainerRuntime, stdin stageInput) ([]CmdResult, status.Code, error) {
+	var attempts []CmdResult
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if stage.Retry.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, time.Duration(stage.Retry.AttemptTimeout))
+		}
+		r, err := stage.runImage(attemptCtx, rt, stdin)
+		cancel()
+		attempts = append(attempts, r)
+		if err == nil {
+			return attempts, status.OK, nil
+		}
+		code := status.RunError
+		switch {
+		case errors.Is(attemptCtx.Err(), context.DeadlineExceeded):
+			code = status.TimeoutError
+		case r.OOMKilled:
+			code = status.OOMError
+		}
+		if ctx.Err() != nil || attempt >= stage.Retry.MaxAttempts || !stage.Retry.retryable(r.ExitStatus, code) {
+			return attempts, code, err
+		}
+		wait := stage.Retry.backoff(attempt)
+		loggerFrom(ctx).Warn("run attempt failed, retrying",
+			"attempt", attempt,
+			"max_attempts", stage.Retry.MaxAttempts,
+			exitStatusKey, r.ExitStatus,
+			"wait", wait,
+			errAttr(err))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return attempts, code, err
+		}
+	}
+}
+
+// image returns the image executed by the stage: the pulled image, if set, or
+// the built one.
+func (stage *Stage) image() string {
+	if stage.Image != "" {
+		return stage.Image
+	}
+	return stage.ContainerID
+}
+
+func (stage *Stage) runImage(ctx context.Context, rt ContainerRuntime, stdin stageInput) (CmdResult, error) {
+	stdout, stderr, closeSinks := stage.openSinks(ctx, runStep)
+	defer closeSinks()
+	secrets := secretsFrom(ctx)
+	r, err := rt.Run(ctx, RunSpec{
+		Name:       containerName(stage.ContainerID),
+		Image:      stage.image(),
+		Dir:        filepath.Join(stage.BaseDir, stage.Dir),
+		VolumeName: stage.VolumeName,
+		VolumeDir:  stage.VolumeDir,
+		Stdin:      stdin.data,
+		StdinFile:  stdinPath(stdin),
+		Env:        stage.RunEnv,
+		Secrets:    secrets.env(stage.Secrets),
+		Mounts:     stage.Mounts,
+		Network:    stage.Network,
+		Resources:  stage.Resources,
+		Stdout:     stdout,
+		Stderr:     stderr,
+		Limit:      stage.limit,
+	})
+	// The runtime only knows the path of the stdin file.
+	r.Stdin, r.StdinFile = stdin.data, stdin.file
+	r = secrets.redactResult(r)
+	if ctx.Err() != nil {
+		return r, fmt.Errorf("error when running image for %s: %w", stage.internalID, errors.Join(ctx.Err(), err))
+	}
+	if !contains(stage.RunSuccessCodes, r.ExitStatus) && r.OOMKilled {
+		return r, fmt.Errorf("error when running image: container killed for running out of memory (status code %d) when running image for %s", r.ExitStatus, stage.internalID)
 	}
-	r, _ := runImage(image, stage.BaseDir, stage.Dir, stage.VolumeName, stage.VolumeDir, stdin, stage.RunEnv)
 	if !contains(stage.RunSuccessCodes, r.ExitStatus) {
 		return r, fmt.Errorf("error when running image: Status code %d(%s) when running image for %s", r.ExitStatus, status.Text(status.Code(r.ExitStatus)), stage.internalID)
 	}
 	return r, nil
 }
 
+// stdinPath returns the path of the file containing the stdin, if any.
+func stdinPath(in stageInput) string {
+	if in.file == nil {
+		return ""
+	}
+	return in.file.Path
+}
+
 // Removing temporary directories created from cloned repositories.
 func (stage *Stage) teardown() (CmdResult, error) {
 	// Even though this stage uses libraries to execute its commands, we wrap
 	// those in a CmdResult to comply with the stage execution steps interface.
 	if stage.Repo != "" {
-		log.Printf("Removing cloned repo %s\n", stage.BaseDir)
+		logger := stage.log().With(stepKey, teardownStep)
+		logger.Info("removing cloned repo", "dir", stage.BaseDir)
 		if err := os.RemoveAll(stage.BaseDir); err != nil {
 			e := fmt.Errorf("error removing temp dir(%s): %w", stage.BaseDir, err)
 			return CmdResult{
@@ -212,19 +415,20 @@ func (stage *Stage) teardown() (CmdResult, error) {
 				ExitStatus: int(status.SystemError),
 			}, e
 		}
-		log.Printf("Cloned repo temp dir removed successfully!\n")
+		logger.Info("cloned repo removed", "dir", stage.BaseDir)
 	}
 	return CmdResult{
 		ExitStatus: int(status.OK),
 	}, nil
 }
 
-// validate checks whether the stage specification is valid.
-func (stage *Stage) validateSpec() error {
-	if stage.Repo != "" && stage.Image != "" {
-		return fmt.Errorf("invalid stage configuration: repo and image can not be set at the same time")
+// containerID returns the stage ContainerID or, if not set, the one derived
+// from its name.
+func (stage *Stage) containerID() string {
+	if stage.ContainerID != "" {
+		return stage.ContainerID
 	}
-	return nil
+	return strings.ReplaceAll(strings.ToLower(stage.Name), " ", "-")
 }
 
 func mergeEnv(defaultEnv, stageEnv map[string]string) map[string]string {
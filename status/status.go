@@ -1,10 +1,13 @@
 package status
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"sort"
+	"strings"
 )
 
 // Code is a custom type to represent ints
@@ -43,6 +46,12 @@ const (
 
 	// Unknown means that something unexpected has happend.
 	Unknown Code = 10
+
+	// TimeoutError should be used for scenarios where a stage took longer than its timeout.
+	TimeoutError Code = 11
+
+	// OOMError should be used for scenarios where a stage container was killed for exceeding its memory limit.
+	OOMError Code = 12
 )
 
 var (
@@ -58,6 +67,8 @@ var (
 		BuildError:        "Build Error",
 		RunError:          "Run Error",
 		TeardownError:     "Teardown Error",
+		TimeoutError:      "Timeout Error",
+		OOMError:          "OOM Error",
 	}
 )
 
@@ -67,10 +78,42 @@ func Text(code Code) string {
 	return statusText[code]
 }
 
+// Codes returns the known codes in ascending order.
+func Codes() []Code {
+	codes := make([]Code, 0, len(statusText))
+	for c := range statusText {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// UnmarshalJSON decodes a code from its number or from its name, with or
+// without spaces, e.g. 7, "ConnectionError" or "Connection Error".
+func (c *Code) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		*c = Code(n)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return fmt.Errorf("invalid status code: %s", string(b))
+	}
+	name = strings.ReplaceAll(name, " ", "")
+	for code, text := range statusText {
+		if strings.EqualFold(strings.ReplaceAll(text, " ", ""), name) {
+			*c = code
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status code: %q", name)
+}
+
 // ExitFromError logs the error message and call os.Exit
 // passing the code if err is of type StatusError.
 func ExitFromError(err error) {
-	log.Println(fmt.Errorf("%q", err))
+	slog.Error("exiting", "error", err.Error())
 	var se *Error
 	if errors.As(err, &se) {
 		os.Exit(int(se.Code))
@@ -1,6 +1,7 @@
 package status
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -39,6 +40,33 @@ func TestText(t *testing.T) {
 	}
 }
 
+func TestCodeUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		in  string
+		out Code
+		err bool
+	}{
+		{`7`, ConnectionError, false},
+		{`"ConnectionError"`, ConnectionError, false},
+		{`"Connection Error"`, ConnectionError, false},
+		{`"timeouterror"`, TimeoutError, false},
+		{`"Nonexistent"`, OK, true},
+		{`true`, OK, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.in, func(t *testing.T) {
+			var c Code
+			err := json.Unmarshal([]byte(tt.in), &c)
+			if (err != nil) != tt.err {
+				t.Fatalf("want error %v, got %v", tt.err, err)
+			}
+			if c != tt.out {
+				t.Errorf("got %v, want %v", c, tt.out)
+			}
+		})
+	}
+}
+
 func TestExitFromError(t *testing.T) {
 	testCode := int(InvalidFile)
 	if os.Getenv("FLAG") == "1" {
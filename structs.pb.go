@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.25.0-devel
+// 	protoc-gen-go v1.34.1
 // 	protoc        v3.14.0
 // source: structs.proto
 
@@ -9,6 +9,7 @@ package executor
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
@@ -29,16 +30,20 @@ const (
 	StageExecution_BUILD_ERROR    StageExecution_Status = 2
 	StageExecution_RUN_ERROR      StageExecution_Status = 3
 	StageExecution_TEARDOWN_ERROR StageExecution_Status = 4
+	StageExecution_TIMEOUT_ERROR  StageExecution_Status = 11
+	StageExecution_OOM_ERROR      StageExecution_Status = 12
 )
 
 // Enum value maps for StageExecution_Status.
 var (
 	StageExecution_Status_name = map[int32]string{
-		0: "OK",
-		1: "SETUP_ERROR",
-		2: "BUILD_ERROR",
-		3: "RUN_ERROR",
-		4: "TEARDOWN_ERROR",
+		0:  "OK",
+		1:  "SETUP_ERROR",
+		2:  "BUILD_ERROR",
+		3:  "RUN_ERROR",
+		4:  "TEARDOWN_ERROR",
+		11: "TIMEOUT_ERROR",
+		12: "OOM_ERROR",
 	}
 	StageExecution_Status_value = map[string]int32{
 		"OK":             0,
@@ -46,6 +51,8 @@ var (
 		"BUILD_ERROR":    2,
 		"RUN_ERROR":      3,
 		"TEARDOWN_ERROR": 4,
+		"TIMEOUT_ERROR":  11,
+		"OOM_ERROR":      12,
 	}
 )
 
@@ -81,10 +88,15 @@ type PipelineExecution struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Pipeline         *PipelineDef      `protobuf:"bytes,1,opt,name=pipeline,proto3" json:"pipeline,omitempty"`
-	SetupErrorMsg    string            `protobuf:"bytes,2,opt,name=setup_error_msg,json=setupErrorMsg,proto3" json:"setup_error_msg,omitempty"`
-	Results          []*StageExecution `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
-	TeardownErrorMsg string            `protobuf:"bytes,4,opt,name=teardown_error_msg,json=teardownErrorMsg,proto3" json:"teardown_error_msg,omitempty"`
+	Pipeline         *PipelineDef           `protobuf:"bytes,1,opt,name=pipeline,proto3" json:"pipeline,omitempty"`
+	SetupErrorMsg    string                 `protobuf:"bytes,2,opt,name=setup_error_msg,json=setupErrorMsg,proto3" json:"setup_error_msg,omitempty"`
+	Results          []*StageExecution      `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+	TeardownErrorMsg string                 `protobuf:"bytes,4,opt,name=teardown_error_msg,json=teardownErrorMsg,proto3" json:"teardown_error_msg,omitempty"`
+	Status           int32                  `protobuf:"varint,5,opt,name=status,proto3" json:"status,omitempty"`                          // Summary status of the pipeline execution.
+	StartTime        *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`    // Beginning of the pipeline execution.
+	FinishTime       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=finish_time,json=finishTime,proto3" json:"finish_time,omitempty"` // End of the pipeline execution.
+	Name             string                 `protobuf:"bytes,8,opt,name=name,proto3" json:"name,omitempty"`                               // Name of the pipeline executed.
+	Services         []*ServiceExecution    `protobuf:"bytes,9,rep,name=services,proto3" json:"services,omitempty"`                       // Details of the services, in the order they were started.
 }
 
 func (x *PipelineExecution) Reset() {
@@ -147,19 +159,69 @@ func (x *PipelineExecution) GetTeardownErrorMsg() string {
 	return ""
 }
 
+func (x *PipelineExecution) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *PipelineExecution) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *PipelineExecution) GetFinishTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishTime
+	}
+	return nil
+}
+
+func (x *PipelineExecution) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PipelineExecution) GetServices() []*ServiceExecution {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
 type PipelineDef struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Name                 string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	DefaultBaseDir       string            `protobuf:"bytes,2,opt,name=default_base_dir,json=defaultBaseDir,proto3" json:"default_base_dir,omitempty"`
-	DefaultBuildEnv      map[string]string `protobuf:"bytes,3,rep,name=default_build_env,json=defaultBuildEnv,proto3" json:"default_build_env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	DefaultRunEnv        map[string]string `protobuf:"bytes,4,rep,name=default_run_env,json=defaultRunEnv,proto3" json:"default_run_env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	VolumeDir            string            `protobuf:"bytes,5,opt,name=volume_dir,json=volumeDir,proto3" json:"volume_dir,omitempty"`
-	SkipVolumeDirCleanup bool              `protobuf:"varint,6,opt,name=skip_volume_dir_cleanup,json=skipVolumeDirCleanup,proto3" json:"skip_volume_dir_cleanup,omitempty"`
-	Stages               []*StageDef       `protobuf:"bytes,7,rep,name=stages,proto3" json:"stages,omitempty"`
-	ErrorHander          *StageDef         `protobuf:"bytes,8,opt,name=error_hander,json=errorHander,proto3" json:"error_hander,omitempty"`
+	Name                 string               `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	DefaultBaseDir       string               `protobuf:"bytes,2,opt,name=default_base_dir,json=defaultBaseDir,proto3" json:"default_base_dir,omitempty"`
+	DefaultBuildEnv      map[string]string    `protobuf:"bytes,3,rep,name=default_build_env,json=defaultBuildEnv,proto3" json:"default_build_env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	DefaultRunEnv        map[string]string    `protobuf:"bytes,4,rep,name=default_run_env,json=defaultRunEnv,proto3" json:"default_run_env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	VolumeDir            string               `protobuf:"bytes,5,opt,name=volume_dir,json=volumeDir,proto3" json:"volume_dir,omitempty"`
+	SkipVolumeDirCleanup bool                 `protobuf:"varint,6,opt,name=skip_volume_dir_cleanup,json=skipVolumeDirCleanup,proto3" json:"skip_volume_dir_cleanup,omitempty"`
+	Stages               []*StageDef          `protobuf:"bytes,7,rep,name=stages,proto3" json:"stages,omitempty"`
+	ErrorHander          *StageDef            `protobuf:"bytes,8,opt,name=error_hander,json=errorHander,proto3" json:"error_hander,omitempty"`
+	VolumeName           string               `protobuf:"bytes,9,opt,name=volume_name,json=volumeName,proto3" json:"volume_name,omitempty"`                    // Name of the volume shared across all pipeline stages.
+	DefaultTimeout       *durationpb.Duration `protobuf:"bytes,10,opt,name=default_timeout,json=defaultTimeout,proto3" json:"default_timeout,omitempty"`       // Default maximum duration of each stage. No timeout if not set.
+	MaxParallelism       int32                `protobuf:"varint,11,opt,name=max_parallelism,json=maxParallelism,proto3" json:"max_parallelism,omitempty"`      // Maximum number of stages executing at the same time. No limit if not set.
+	MaxOutputSize        int64                `protobuf:"varint,12,opt,name=max_output_size,json=maxOutputSize,proto3" json:"max_output_size,omitempty"`       // Maximum number of bytes of each stdout and stderr kept in memory.
+	OutputDir            string               `protobuf:"bytes,13,opt,name=output_dir,json=outputDir,proto3" json:"output_dir,omitempty"`                      // Directory of the files containing the outputs larger than max_output_size.
+	CheckpointDir        string               `protobuf:"bytes,14,opt,name=checkpoint_dir,json=checkpointDir,proto3" json:"checkpoint_dir,omitempty"`          // Directory in which the execution state is saved after each successful stage.
+	LogDir               string               `protobuf:"bytes,15,opt,name=log_dir,json=logDir,proto3" json:"log_dir,omitempty"`                               // Directory in which the stdout and stderr of each stage are written while it is executed.
+	DefaultGitAuth       *GitAuthDef          `protobuf:"bytes,16,opt,name=default_git_auth,json=defaultGitAuth,proto3" json:"default_git_auth,omitempty"`     // Default credentials used to clone the stage repositories.
+	GitCacheDir          string               `protobuf:"bytes,17,opt,name=git_cache_dir,json=gitCacheDir,proto3" json:"git_cache_dir,omitempty"`              // Directory of the local git mirrors the stage repositories are cloned from.
+	DefaultResources     *ResourcesDef        `protobuf:"bytes,18,opt,name=default_resources,json=defaultResources,proto3" json:"default_resources,omitempty"` // Default resource limits of the stage containers.
+	Mounts               []*MountDef          `protobuf:"bytes,19,rep,name=mounts,proto3" json:"mounts,omitempty"`                                             // Filesystems mounted in every stage container.
+	Networks             []*NetworkDef        `protobuf:"bytes,20,rep,name=networks,proto3" json:"networks,omitempty"`                                         // User-defined networks created by the pipeline.
+	DefaultNetwork       string               `protobuf:"bytes,21,opt,name=default_network,json=defaultNetwork,proto3" json:"default_network,omitempty"`       // Default network of the stage containers.
+	Services             []*ServiceDef        `protobuf:"bytes,22,rep,name=services,proto3" json:"services,omitempty"`                                         // Long-running containers used by the stages.
+	Secrets              []*SecretDef         `protobuf:"bytes,23,rep,name=secrets,proto3" json:"secrets,omitempty"`                                           // Secrets passed to the stages and services.
 }
 
 func (x *PipelineDef) Reset() {
@@ -250,6 +312,111 @@ func (x *PipelineDef) GetErrorHander() *StageDef {
 	return nil
 }
 
+func (x *PipelineDef) GetVolumeName() string {
+	if x != nil {
+		return x.VolumeName
+	}
+	return ""
+}
+
+func (x *PipelineDef) GetDefaultTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.DefaultTimeout
+	}
+	return nil
+}
+
+func (x *PipelineDef) GetMaxParallelism() int32 {
+	if x != nil {
+		return x.MaxParallelism
+	}
+	return 0
+}
+
+func (x *PipelineDef) GetMaxOutputSize() int64 {
+	if x != nil {
+		return x.MaxOutputSize
+	}
+	return 0
+}
+
+func (x *PipelineDef) GetOutputDir() string {
+	if x != nil {
+		return x.OutputDir
+	}
+	return ""
+}
+
+func (x *PipelineDef) GetCheckpointDir() string {
+	if x != nil {
+		return x.CheckpointDir
+	}
+	return ""
+}
+
+func (x *PipelineDef) GetLogDir() string {
+	if x != nil {
+		return x.LogDir
+	}
+	return ""
+}
+
+func (x *PipelineDef) GetDefaultGitAuth() *GitAuthDef {
+	if x != nil {
+		return x.DefaultGitAuth
+	}
+	return nil
+}
+
+func (x *PipelineDef) GetGitCacheDir() string {
+	if x != nil {
+		return x.GitCacheDir
+	}
+	return ""
+}
+
+func (x *PipelineDef) GetDefaultResources() *ResourcesDef {
+	if x != nil {
+		return x.DefaultResources
+	}
+	return nil
+}
+
+func (x *PipelineDef) GetMounts() []*MountDef {
+	if x != nil {
+		return x.Mounts
+	}
+	return nil
+}
+
+func (x *PipelineDef) GetNetworks() []*NetworkDef {
+	if x != nil {
+		return x.Networks
+	}
+	return nil
+}
+
+func (x *PipelineDef) GetDefaultNetwork() string {
+	if x != nil {
+		return x.DefaultNetwork
+	}
+	return ""
+}
+
+func (x *PipelineDef) GetServices() []*ServiceDef {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+func (x *PipelineDef) GetSecrets() []*SecretDef {
+	if x != nil {
+		return x.Secrets
+	}
+	return nil
+}
+
 type StageExecution struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -264,6 +431,9 @@ type StageExecution struct {
 	Run         *StepExecution         `protobuf:"bytes,7,opt,name=run,proto3" json:"run,omitempty"`                                    // Details of the stage run.
 	Teardown    *StepExecution         `protobuf:"bytes,8,opt,name=teardown,proto3" json:"teardown,omitempty"`                          // Details of the stage teardown.
 	Status      StageExecution_Status  `protobuf:"varint,9,opt,name=status,proto3,enum=StageExecution_Status" json:"status,omitempty"`  // Summary status of the stage execution.
+	Attempts    []*StepExecution       `protobuf:"bytes,10,rep,name=attempts,proto3" json:"attempts,omitempty"`                         // Details of every run attempt, in order. The last one is also in run.
+	ImageId     string                 `protobuf:"bytes,11,opt,name=image_id,json=imageId,proto3" json:"image_id,omitempty"`            // ID of the image executed.
+	Stage       *StageDef              `protobuf:"bytes,12,opt,name=stage,proto3" json:"stage,omitempty"`                               // Definition of the stage executed. Its container_id is the same as the one above.
 }
 
 func (x *StageExecution) Reset() {
@@ -361,6 +531,27 @@ func (x *StageExecution) GetStatus() StageExecution_Status {
 	return StageExecution_OK
 }
 
+func (x *StageExecution) GetAttempts() []*StepExecution {
+	if x != nil {
+		return x.Attempts
+	}
+	return nil
+}
+
+func (x *StageExecution) GetImageId() string {
+	if x != nil {
+		return x.ImageId
+	}
+	return ""
+}
+
+func (x *StageExecution) GetStage() *StageDef {
+	if x != nil {
+		return x.Stage
+	}
+	return nil
+}
+
 type StepExecution struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -375,6 +566,10 @@ type StepExecution struct {
 	Env        []string               `protobuf:"bytes,7,rep,name=env,proto3" json:"env,omitempty"`                                  // Copy of strings representing the environment variables in the form ke=value
 	StartTime  *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`     // Beginning of the process execution.
 	FinishTime *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=finish_time,json=finishTime,proto3" json:"finish_time,omitempty"`  // End of the process execution.
+	StdinFile  *StreamFile            `protobuf:"bytes,10,opt,name=stdin_file,json=stdinFile,proto3" json:"stdin_file,omitempty"`    // File containing the whole standard input, if it exceeded the in-memory limit. In that case, stdin is a preview.
+	StdoutFile *StreamFile            `protobuf:"bytes,11,opt,name=stdout_file,json=stdoutFile,proto3" json:"stdout_file,omitempty"` // File containing the whole standard output, if it exceeded the in-memory limit. In that case, stdout is a preview.
+	StderrFile *StreamFile            `protobuf:"bytes,12,opt,name=stderr_file,json=stderrFile,proto3" json:"stderr_file,omitempty"` // File containing the whole standard error, if it exceeded the in-memory limit. In that case, stderr is a preview.
+	OomKilled  bool                   `protobuf:"varint,13,opt,name=oom_killed,json=oomKilled,proto3" json:"oom_killed,omitempty"`   // Whether the container has been killed for exceeding its memory limit.
 }
 
 func (x *StepExecution) Reset() {
@@ -472,24 +667,134 @@ func (x *StepExecution) GetFinishTime() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *StepExecution) GetStdinFile() *StreamFile {
+	if x != nil {
+		return x.StdinFile
+	}
+	return nil
+}
+
+func (x *StepExecution) GetStdoutFile() *StreamFile {
+	if x != nil {
+		return x.StdoutFile
+	}
+	return nil
+}
+
+func (x *StepExecution) GetStderrFile() *StreamFile {
+	if x != nil {
+		return x.StderrFile
+	}
+	return nil
+}
+
+func (x *StepExecution) GetOomKilled() bool {
+	if x != nil {
+		return x.OomKilled
+	}
+	return false
+}
+
+type StreamFile struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path   string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`     // Path of the file.
+	Size   int64  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`    // Size of the stream, in bytes.
+	Sha256 string `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"` // Hex-encoded SHA-256 of the stream.
+}
+
+func (x *StreamFile) Reset() {
+	*x = StreamFile{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamFile) ProtoMessage() {}
+
+func (x *StreamFile) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamFile.ProtoReflect.Descriptor instead.
+func (*StreamFile) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamFile) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *StreamFile) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *StreamFile) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
 type StageDef struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Name              string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`                                                                                                                 // Stage's name.
-	Dir               string            `protobuf:"bytes,2,opt,name=dir,proto3" json:"dir,omitempty"`                                                                                                                   // Directory to be concatenated with default base directory or with the base directory specified here in 'BaseDir'. This field is used to name the image built.
-	BaseDir           string            `protobuf:"bytes,3,opt,name=base_dir,json=baseDir,proto3" json:"base_dir,omitempty"`                                                                                            // Base directory for the stage. This field overwrites the DefaultBaseDir in pipeline's definition.
-	BuildEnv          map[string]string `protobuf:"bytes,4,rep,name=build_env,json=buildEnv,proto3" json:"build_env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"` // Variables to be used in the stage build. They will be concatenated with the default variables defined in the pipeline, overwriting them if repeated.
-	RunEnv            map[string]string `protobuf:"bytes,5,rep,name=run_env,json=runEnv,proto3" json:"run_env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`       // Variables to be used in the stage run. They will be concatenated with the default variables defined in the pipeline, overwriting them if repeated.
-	Repo              string            `protobuf:"bytes,6,opt,name=repo,proto3" json:"repo,omitempty"`                                                                                                                 // Repository URL from where to clone the pipeline stage.
-	RepoVersionEnvVar string            `protobuf:"bytes,7,opt,name=repo_version_env_var,json=repoVersionEnvVar,proto3" json:"repo_version_env_var,omitempty"`                                                          // Name of the environment variable passed to build and run that represents the stage commit id (only when Repo is set).
+	Name              string               `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`                                                                                                                 // Stage's name.
+	Dir               string               `protobuf:"bytes,2,opt,name=dir,proto3" json:"dir,omitempty"`                                                                                                                   // Directory to be concatenated with default base directory or with the base directory specified here in 'BaseDir'. This field is used to name the image built.
+	BaseDir           string               `protobuf:"bytes,3,opt,name=base_dir,json=baseDir,proto3" json:"base_dir,omitempty"`                                                                                            // Base directory for the stage. This field overwrites the DefaultBaseDir in pipeline's definition.
+	BuildEnv          map[string]string    `protobuf:"bytes,4,rep,name=build_env,json=buildEnv,proto3" json:"build_env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"` // Variables to be used in the stage build. They will be concatenated with the default variables defined in the pipeline, overwriting them if repeated.
+	RunEnv            map[string]string    `protobuf:"bytes,5,rep,name=run_env,json=runEnv,proto3" json:"run_env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`       // Variables to be used in the stage run. They will be concatenated with the default variables defined in the pipeline, overwriting them if repeated.
+	Repo              string               `protobuf:"bytes,6,opt,name=repo,proto3" json:"repo,omitempty"`                                                                                                                 // Repository URL from where to clone the pipeline stage.
+	RepoVersionEnvVar string               `protobuf:"bytes,7,opt,name=repo_version_env_var,json=repoVersionEnvVar,proto3" json:"repo_version_env_var,omitempty"`                                                          // Name of the environment variable passed to build and run that represents the stage commit id (only when Repo is set).
+	Image             string               `protobuf:"bytes,8,opt,name=image,proto3" json:"image,omitempty"`                                                                                                               // Docker image ID, e.g., ghcr.io/dadosjusbr/coletor-cnj:main
+	ContainerId       string               `protobuf:"bytes,9,opt,name=container_id,json=containerId,proto3" json:"container_id,omitempty"`                                                                                // ID of the container used to run the stage.
+	VolumeName        string               `protobuf:"bytes,10,opt,name=volume_name,json=volumeName,proto3" json:"volume_name,omitempty"`                                                                                  // Name of the shared volume.
+	VolumeDir         string               `protobuf:"bytes,11,opt,name=volume_dir,json=volumeDir,proto3" json:"volume_dir,omitempty"`                                                                                     // Directory of the shared volume.
+	RunSuccessCodes   []int32              `protobuf:"varint,12,rep,packed,name=run_success_codes,json=runSuccessCodes,proto3" json:"run_success_codes,omitempty"`                                                         // Exit codes that mean the stage has been successfully executed.
+	Timeout           *durationpb.Duration `protobuf:"bytes,13,opt,name=timeout,proto3" json:"timeout,omitempty"`                                                                                                          // Maximum duration of the stage setup, build and run.
+	DependsOn         []string             `protobuf:"bytes,14,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`                                                                                     // Names of the stages that must finish before this one.
+	Retry             *RetryPolicyDef      `protobuf:"bytes,15,opt,name=retry,proto3" json:"retry,omitempty"`                                                                                                              // When to retry the stage run if it fails.
+	Ref               string               `protobuf:"bytes,16,opt,name=ref,proto3" json:"ref,omitempty"`                                                                                                                  // Branch or tag of the repository to check out.
+	Commit            string               `protobuf:"bytes,17,opt,name=commit,proto3" json:"commit,omitempty"`                                                                                                            // Commit of the repository to check out. In a stage execution, the commit checked out.
+	GitAuth           *GitAuthDef          `protobuf:"bytes,18,opt,name=git_auth,json=gitAuth,proto3" json:"git_auth,omitempty"`                                                                                           // Credentials used to clone the repository.
+	CloneDepth        int32                `protobuf:"varint,19,opt,name=clone_depth,json=cloneDepth,proto3" json:"clone_depth,omitempty"`                                                                                 // Number of commits fetched when cloning the repository. Full history if zero.
+	SingleBranch      bool                 `protobuf:"varint,20,opt,name=single_branch,json=singleBranch,proto3" json:"single_branch,omitempty"`                                                                           // Fetch only the ref, or the default branch, when cloning the repository.
+	Submodules        bool                 `protobuf:"varint,21,opt,name=submodules,proto3" json:"submodules,omitempty"`                                                                                                   // Clone the submodules of the repository, recursively.
+	SparseCheckout    bool                 `protobuf:"varint,22,opt,name=sparse_checkout,json=sparseCheckout,proto3" json:"sparse_checkout,omitempty"`                                                                     // Check out only the dir of the repository.
+	Resources         *ResourcesDef        `protobuf:"bytes,23,opt,name=resources,proto3" json:"resources,omitempty"`                                                                                                      // Resource limits of the container.
+	Mounts            []*MountDef          `protobuf:"bytes,24,rep,name=mounts,proto3" json:"mounts,omitempty"`                                                                                                            // Filesystems mounted in the container, besides the shared volume.
+	Network           string               `protobuf:"bytes,25,opt,name=network,proto3" json:"network,omitempty"`                                                                                                          // Network of the container.
+	Secrets           []string             `protobuf:"bytes,26,rep,name=secrets,proto3" json:"secrets,omitempty"`                                                                                                          // Names of the secrets passed to the run.
 }
 
 func (x *StageDef) Reset() {
 	*x = StageDef{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_structs_proto_msgTypes[4]
+		mi := &file_structs_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -502,7 +807,7 @@ func (x *StageDef) String() string {
 func (*StageDef) ProtoMessage() {}
 
 func (x *StageDef) ProtoReflect() protoreflect.Message {
-	mi := &file_structs_proto_msgTypes[4]
+	mi := &file_structs_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -515,7 +820,7 @@ func (x *StageDef) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StageDef.ProtoReflect.Descriptor instead.
 func (*StageDef) Descriptor() ([]byte, []int) {
-	return file_structs_proto_rawDescGZIP(), []int{4}
+	return file_structs_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *StageDef) GetName() string {
@@ -567,191 +872,1454 @@ func (x *StageDef) GetRepoVersionEnvVar() string {
 	return ""
 }
 
-var File_structs_proto protoreflect.FileDescriptor
-
-var file_structs_proto_rawDesc = []byte{
-	0x0a, 0x0d, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
-	0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
-	0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x22, 0xbe, 0x01, 0x0a, 0x11, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x45, 0x78, 0x65,
-	0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x28, 0x0a, 0x08, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69,
-	0x6e, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x6c,
-	0x69, 0x6e, 0x65, 0x44, 0x65, 0x66, 0x52, 0x08, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65,
-	0x12, 0x26, 0x0a, 0x0f, 0x73, 0x65, 0x74, 0x75, 0x70, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f,
-	0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x65, 0x74, 0x75, 0x70,
-	0x45, 0x72, 0x72, 0x6f, 0x72, 0x4d, 0x73, 0x67, 0x12, 0x29, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75,
-	0x6c, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x53, 0x74, 0x61, 0x67,
-	0x65, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75,
-	0x6c, 0x74, 0x73, 0x12, 0x2c, 0x0a, 0x12, 0x74, 0x65, 0x61, 0x72, 0x64, 0x6f, 0x77, 0x6e, 0x5f,
-	0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x6d, 0x73, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x10, 0x74, 0x65, 0x61, 0x72, 0x64, 0x6f, 0x77, 0x6e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x4d, 0x73,
-	0x67, 0x22, 0x90, 0x04, 0x0a, 0x0b, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x44, 0x65,
-	0x66, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x28, 0x0a, 0x10, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74,
-	0x5f, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x0e, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x42, 0x61, 0x73, 0x65, 0x44, 0x69, 0x72, 0x12,
-	0x4d, 0x0a, 0x11, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64,
-	0x5f, 0x65, 0x6e, 0x76, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x50, 0x69, 0x70,
-	0x65, 0x6c, 0x69, 0x6e, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x44, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74,
-	0x42, 0x75, 0x69, 0x6c, 0x64, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0f, 0x64,
-	0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x45, 0x6e, 0x76, 0x12, 0x47,
-	0x0a, 0x0f, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x72, 0x75, 0x6e, 0x5f, 0x65, 0x6e,
-	0x76, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69,
-	0x6e, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x44, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x52, 0x75, 0x6e,
-	0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0d, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c,
-	0x74, 0x52, 0x75, 0x6e, 0x45, 0x6e, 0x76, 0x12, 0x1d, 0x0a, 0x0a, 0x76, 0x6f, 0x6c, 0x75, 0x6d,
-	0x65, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x76, 0x6f, 0x6c,
-	0x75, 0x6d, 0x65, 0x44, 0x69, 0x72, 0x12, 0x35, 0x0a, 0x17, 0x73, 0x6b, 0x69, 0x70, 0x5f, 0x76,
-	0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x5f, 0x63, 0x6c, 0x65, 0x61, 0x6e, 0x75,
-	0x70, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x14, 0x73, 0x6b, 0x69, 0x70, 0x56, 0x6f, 0x6c,
-	0x75, 0x6d, 0x65, 0x44, 0x69, 0x72, 0x43, 0x6c, 0x65, 0x61, 0x6e, 0x75, 0x70, 0x12, 0x21, 0x0a,
-	0x06, 0x73, 0x74, 0x61, 0x67, 0x65, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e,
-	0x53, 0x74, 0x61, 0x67, 0x65, 0x44, 0x65, 0x66, 0x52, 0x06, 0x73, 0x74, 0x61, 0x67, 0x65, 0x73,
-	0x12, 0x2c, 0x0a, 0x0c, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x68, 0x61, 0x6e, 0x64, 0x65, 0x72,
-	0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x53, 0x74, 0x61, 0x67, 0x65, 0x44, 0x65,
-	0x66, 0x52, 0x0b, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x48, 0x61, 0x6e, 0x64, 0x65, 0x72, 0x1a, 0x42,
-	0x0a, 0x14, 0x44, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x45, 0x6e,
-	0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02,
-	0x38, 0x01, 0x1a, 0x40, 0x0a, 0x12, 0x44, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x52, 0x75, 0x6e,
-	0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
-	0x3a, 0x02, 0x38, 0x01, 0x22, 0xe9, 0x03, 0x0a, 0x0e, 0x53, 0x74, 0x61, 0x67, 0x65, 0x45, 0x78,
-	0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x39, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74,
-	0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f,
-	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69,
-	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69,
-	0x6d, 0x65, 0x12, 0x3b, 0x0a, 0x0b, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x5f, 0x74, 0x69, 0x6d,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74,
-	0x61, 0x6d, 0x70, 0x52, 0x0a, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x54, 0x69, 0x6d, 0x65, 0x12,
-	0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72,
-	0x49, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x69, 0x64, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x49, 0x64, 0x12,
-	0x24, 0x0a, 0x05, 0x73, 0x65, 0x74, 0x75, 0x70, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e,
-	0x2e, 0x53, 0x74, 0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x05,
-	0x73, 0x65, 0x74, 0x75, 0x70, 0x12, 0x24, 0x0a, 0x05, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x18, 0x06,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x53, 0x74, 0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75,
-	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x05, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x12, 0x20, 0x0a, 0x03, 0x72,
-	0x75, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x53, 0x74, 0x65, 0x70, 0x45,
-	0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x03, 0x72, 0x75, 0x6e, 0x12, 0x2a, 0x0a,
-	0x08, 0x74, 0x65, 0x61, 0x72, 0x64, 0x6f, 0x77, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x0e, 0x2e, 0x53, 0x74, 0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52,
-	0x08, 0x74, 0x65, 0x61, 0x72, 0x64, 0x6f, 0x77, 0x6e, 0x12, 0x2e, 0x0a, 0x06, 0x73, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x16, 0x2e, 0x53, 0x74, 0x61, 0x67,
-	0x65, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x22, 0x55, 0x0a, 0x06, 0x53, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x12, 0x06, 0x0a, 0x02, 0x4f, 0x4b, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x53,
-	0x45, 0x54, 0x55, 0x50, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b,
-	0x42, 0x55, 0x49, 0x4c, 0x44, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x02, 0x12, 0x0d, 0x0a,
-	0x09, 0x52, 0x55, 0x4e, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x03, 0x12, 0x12, 0x0a, 0x0e,
-	0x54, 0x45, 0x41, 0x52, 0x44, 0x4f, 0x57, 0x4e, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x04,
-	0x22, 0xab, 0x02, 0x0a, 0x0d, 0x53, 0x74, 0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69,
-	0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x64, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x05, 0x73, 0x74, 0x64, 0x69, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x64, 0x6f,
-	0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x64, 0x6f, 0x75, 0x74,
-	0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x64, 0x65, 0x72, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x06, 0x73, 0x74, 0x64, 0x65, 0x72, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x63, 0x6d, 0x64, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x63, 0x6d, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x63, 0x6d,
-	0x64, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6d, 0x64,
-	0x44, 0x69, 0x72, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x5f, 0x63, 0x6f,
-	0x64, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73,
-	0x43, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x76, 0x18, 0x07, 0x20, 0x03, 0x28,
-	0x09, 0x52, 0x03, 0x65, 0x6e, 0x76, 0x12, 0x39, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f,
-	0x74, 0x69, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d,
-	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d,
-	0x65, 0x12, 0x3b, 0x0a, 0x0b, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x5f, 0x74, 0x69, 0x6d, 0x65,
-	0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
-	0x6d, 0x70, 0x52, 0x0a, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x54, 0x69, 0x6d, 0x65, 0x22, 0xee,
-	0x02, 0x0a, 0x08, 0x53, 0x74, 0x61, 0x67, 0x65, 0x44, 0x65, 0x66, 0x12, 0x12, 0x0a, 0x04, 0x6e,
-	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
-	0x10, 0x0a, 0x03, 0x64, 0x69, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x69,
-	0x72, 0x12, 0x19, 0x0a, 0x08, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x03, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x07, 0x62, 0x61, 0x73, 0x65, 0x44, 0x69, 0x72, 0x12, 0x34, 0x0a, 0x09,
-	0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x65, 0x6e, 0x76, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x17, 0x2e, 0x53, 0x74, 0x61, 0x67, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x42, 0x75, 0x69, 0x6c, 0x64,
-	0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x45,
-	0x6e, 0x76, 0x12, 0x2e, 0x0a, 0x07, 0x72, 0x75, 0x6e, 0x5f, 0x65, 0x6e, 0x76, 0x18, 0x05, 0x20,
-	0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x53, 0x74, 0x61, 0x67, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x52,
-	0x75, 0x6e, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x72, 0x75, 0x6e, 0x45,
-	0x6e, 0x76, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x65, 0x70, 0x6f, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x04, 0x72, 0x65, 0x70, 0x6f, 0x12, 0x2f, 0x0a, 0x14, 0x72, 0x65, 0x70, 0x6f, 0x5f, 0x76,
-	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x65, 0x6e, 0x76, 0x5f, 0x76, 0x61, 0x72, 0x18, 0x07,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x11, 0x72, 0x65, 0x70, 0x6f, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
-	0x6e, 0x45, 0x6e, 0x76, 0x56, 0x61, 0x72, 0x1a, 0x3b, 0x0a, 0x0d, 0x42, 0x75, 0x69, 0x6c, 0x64,
-	0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
-	0x3a, 0x02, 0x38, 0x01, 0x1a, 0x39, 0x0a, 0x0b, 0x52, 0x75, 0x6e, 0x45, 0x6e, 0x76, 0x45, 0x6e,
-	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x42,
-	0x20, 0x5a, 0x1e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x64, 0x61,
-	0x64, 0x6f, 0x73, 0x6a, 0x75, 0x73, 0x62, 0x72, 0x2f, 0x65, 0x78, 0x65, 0x63, 0x75, 0x74, 0x6f,
-	0x72, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *StageDef) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
 }
 
-var (
-	file_structs_proto_rawDescOnce sync.Once
-	file_structs_proto_rawDescData = file_structs_proto_rawDesc
-)
-
-func file_structs_proto_rawDescGZIP() []byte {
-	file_structs_proto_rawDescOnce.Do(func() {
-		file_structs_proto_rawDescData = protoimpl.X.CompressGZIP(file_structs_proto_rawDescData)
-	})
-	return file_structs_proto_rawDescData
+func (x *StageDef) GetContainerId() string {
+	if x != nil {
+		return x.ContainerId
+	}
+	return ""
 }
 
-var file_structs_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_structs_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
-var file_structs_proto_goTypes = []interface{}{
-	(StageExecution_Status)(0),    // 0: StageExecution.Status
-	(*PipelineExecution)(nil),     // 1: PipelineExecution
-	(*PipelineDef)(nil),           // 2: PipelineDef
-	(*StageExecution)(nil),        // 3: StageExecution
-	(*StepExecution)(nil),         // 4: StepExecution
-	(*StageDef)(nil),              // 5: StageDef
-	nil,                           // 6: PipelineDef.DefaultBuildEnvEntry
-	nil,                           // 7: PipelineDef.DefaultRunEnvEntry
-	nil,                           // 8: StageDef.BuildEnvEntry
-	nil,                           // 9: StageDef.RunEnvEntry
-	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+func (x *StageDef) GetVolumeName() string {
+	if x != nil {
+		return x.VolumeName
+	}
+	return ""
 }
-var file_structs_proto_depIdxs = []int32{
-	2,  // 0: PipelineExecution.pipeline:type_name -> PipelineDef
-	3,  // 1: PipelineExecution.results:type_name -> StageExecution
-	6,  // 2: PipelineDef.default_build_env:type_name -> PipelineDef.DefaultBuildEnvEntry
-	7,  // 3: PipelineDef.default_run_env:type_name -> PipelineDef.DefaultRunEnvEntry
-	5,  // 4: PipelineDef.stages:type_name -> StageDef
-	5,  // 5: PipelineDef.error_hander:type_name -> StageDef
-	10, // 6: StageExecution.start_time:type_name -> google.protobuf.Timestamp
-	10, // 7: StageExecution.finish_time:type_name -> google.protobuf.Timestamp
-	4,  // 8: StageExecution.setup:type_name -> StepExecution
-	4,  // 9: StageExecution.build:type_name -> StepExecution
-	4,  // 10: StageExecution.run:type_name -> StepExecution
-	4,  // 11: StageExecution.teardown:type_name -> StepExecution
-	0,  // 12: StageExecution.status:type_name -> StageExecution.Status
-	10, // 13: StepExecution.start_time:type_name -> google.protobuf.Timestamp
-	10, // 14: StepExecution.finish_time:type_name -> google.protobuf.Timestamp
-	8,  // 15: StageDef.build_env:type_name -> StageDef.BuildEnvEntry
-	9,  // 16: StageDef.run_env:type_name -> StageDef.RunEnvEntry
-	17, // [17:17] is the sub-list for method output_type
-	17, // [17:17] is the sub-list for method input_type
-	17, // [17:17] is the sub-list for extension type_name
-	17, // [17:17] is the sub-list for extension extendee
-	0,  // [0:17] is the sub-list for field type_name
+
+func (x *StageDef) GetVolumeDir() string {
+	if x != nil {
+		return x.VolumeDir
+	}
+	return ""
 }
 
-func init() { file_structs_proto_init() }
-func file_structs_proto_init() {
-	if File_structs_proto != nil {
-		return
+func (x *StageDef) GetRunSuccessCodes() []int32 {
+	if x != nil {
+		return x.RunSuccessCodes
+	}
+	return nil
+}
+
+func (x *StageDef) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+func (x *StageDef) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *StageDef) GetRetry() *RetryPolicyDef {
+	if x != nil {
+		return x.Retry
+	}
+	return nil
+}
+
+func (x *StageDef) GetRef() string {
+	if x != nil {
+		return x.Ref
+	}
+	return ""
+}
+
+func (x *StageDef) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *StageDef) GetGitAuth() *GitAuthDef {
+	if x != nil {
+		return x.GitAuth
+	}
+	return nil
+}
+
+func (x *StageDef) GetCloneDepth() int32 {
+	if x != nil {
+		return x.CloneDepth
+	}
+	return 0
+}
+
+func (x *StageDef) GetSingleBranch() bool {
+	if x != nil {
+		return x.SingleBranch
+	}
+	return false
+}
+
+func (x *StageDef) GetSubmodules() bool {
+	if x != nil {
+		return x.Submodules
+	}
+	return false
+}
+
+func (x *StageDef) GetSparseCheckout() bool {
+	if x != nil {
+		return x.SparseCheckout
+	}
+	return false
+}
+
+func (x *StageDef) GetResources() *ResourcesDef {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+func (x *StageDef) GetMounts() []*MountDef {
+	if x != nil {
+		return x.Mounts
+	}
+	return nil
+}
+
+func (x *StageDef) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *StageDef) GetSecrets() []string {
+	if x != nil {
+		return x.Secrets
+	}
+	return nil
+}
+
+type RetryPolicyDef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MaxAttempts    int32                `protobuf:"varint,1,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"`         // Maximum number of runs, including the first one.
+	InitialBackoff *durationpb.Duration `protobuf:"bytes,2,opt,name=initial_backoff,json=initialBackoff,proto3" json:"initial_backoff,omitempty"` // Wait before the first retry. It doubles at each retry.
+	MaxBackoff     *durationpb.Duration `protobuf:"bytes,3,opt,name=max_backoff,json=maxBackoff,proto3" json:"max_backoff,omitempty"`             // Maximum wait between two attempts.
+	Jitter         float64              `protobuf:"fixed64,4,opt,name=jitter,proto3" json:"jitter,omitempty"`                                     // Fraction of the backoff randomly added or subtracted, between 0 and 1.
+	AttemptTimeout *durationpb.Duration `protobuf:"bytes,5,opt,name=attempt_timeout,json=attemptTimeout,proto3" json:"attempt_timeout,omitempty"` // Maximum duration of each attempt.
+	ExitCodes      []int32              `protobuf:"varint,6,rep,packed,name=exit_codes,json=exitCodes,proto3" json:"exit_codes,omitempty"`        // Retryable exit codes of the container.
+	Statuses       []int32              `protobuf:"varint,7,rep,packed,name=statuses,proto3" json:"statuses,omitempty"`                           // Retryable statuses of the failed attempt.
+}
+
+func (x *RetryPolicyDef) Reset() {
+	*x = RetryPolicyDef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RetryPolicyDef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetryPolicyDef) ProtoMessage() {}
+
+func (x *RetryPolicyDef) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetryPolicyDef.ProtoReflect.Descriptor instead.
+func (*RetryPolicyDef) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RetryPolicyDef) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *RetryPolicyDef) GetInitialBackoff() *durationpb.Duration {
+	if x != nil {
+		return x.InitialBackoff
+	}
+	return nil
+}
+
+func (x *RetryPolicyDef) GetMaxBackoff() *durationpb.Duration {
+	if x != nil {
+		return x.MaxBackoff
+	}
+	return nil
+}
+
+func (x *RetryPolicyDef) GetJitter() float64 {
+	if x != nil {
+		return x.Jitter
+	}
+	return 0
+}
+
+func (x *RetryPolicyDef) GetAttemptTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.AttemptTimeout
+	}
+	return nil
+}
+
+func (x *RetryPolicyDef) GetExitCodes() []int32 {
+	if x != nil {
+		return x.ExitCodes
+	}
+	return nil
+}
+
+func (x *RetryPolicyDef) GetStatuses() []int32 {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+// Limits of the host resources used by a container. Zero means no limit.
+type ResourcesDef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Memory     int64        `protobuf:"varint,1,opt,name=memory,proto3" json:"memory,omitempty"`                           // Memory limit, in bytes.
+	MemorySwap int64        `protobuf:"varint,2,opt,name=memory_swap,json=memorySwap,proto3" json:"memory_swap,omitempty"` // Limit of memory plus swap, in bytes, or -1 for unlimited swap.
+	Cpus       float64      `protobuf:"fixed64,3,opt,name=cpus,proto3" json:"cpus,omitempty"`                              // CPU quota, in number of CPUs.
+	CpuShares  int64        `protobuf:"varint,4,opt,name=cpu_shares,json=cpuShares,proto3" json:"cpu_shares,omitempty"`    // CPU weight relative to the other containers.
+	PidsLimit  int64        `protobuf:"varint,5,opt,name=pids_limit,json=pidsLimit,proto3" json:"pids_limit,omitempty"`    // Maximum number of processes, or -1 for unlimited.
+	Ulimits    []*UlimitDef `protobuf:"bytes,6,rep,name=ulimits,proto3" json:"ulimits,omitempty"`                          // Limits of the container processes.
+	TmpfsSize  int64        `protobuf:"varint,7,opt,name=tmpfs_size,json=tmpfsSize,proto3" json:"tmpfs_size,omitempty"`    // Size of the tmpfs mounted at /tmp, in bytes.
+}
+
+func (x *ResourcesDef) Reset() {
+	*x = ResourcesDef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResourcesDef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourcesDef) ProtoMessage() {}
+
+func (x *ResourcesDef) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourcesDef.ProtoReflect.Descriptor instead.
+func (*ResourcesDef) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ResourcesDef) GetMemory() int64 {
+	if x != nil {
+		return x.Memory
+	}
+	return 0
+}
+
+func (x *ResourcesDef) GetMemorySwap() int64 {
+	if x != nil {
+		return x.MemorySwap
+	}
+	return 0
+}
+
+func (x *ResourcesDef) GetCpus() float64 {
+	if x != nil {
+		return x.Cpus
+	}
+	return 0
+}
+
+func (x *ResourcesDef) GetCpuShares() int64 {
+	if x != nil {
+		return x.CpuShares
+	}
+	return 0
+}
+
+func (x *ResourcesDef) GetPidsLimit() int64 {
+	if x != nil {
+		return x.PidsLimit
+	}
+	return 0
+}
+
+func (x *ResourcesDef) GetUlimits() []*UlimitDef {
+	if x != nil {
+		return x.Ulimits
+	}
+	return nil
+}
+
+func (x *ResourcesDef) GetTmpfsSize() int64 {
+	if x != nil {
+		return x.TmpfsSize
+	}
+	return 0
+}
+
+// Filesystem mounted in a container.
+type MountDef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type     string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`                          // bind, volume or tmpfs.
+	Source   string `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`                      // Host path of bind mounts or volume name of volume mounts.
+	Target   string `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`                      // Path in the container.
+	ReadOnly bool   `protobuf:"varint,4,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"` // Whether the container can only read the mount.
+	Size     int64  `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`                         // Size of tmpfs mounts, in bytes.
+}
+
+func (x *MountDef) Reset() {
+	*x = MountDef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MountDef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MountDef) ProtoMessage() {}
+
+func (x *MountDef) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MountDef.ProtoReflect.Descriptor instead.
+func (*MountDef) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *MountDef) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *MountDef) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *MountDef) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *MountDef) GetReadOnly() bool {
+	if x != nil {
+		return x.ReadOnly
+	}
+	return false
+}
+
+func (x *MountDef) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+// User-defined network created by the pipeline.
+type NetworkDef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`          // Name of the network.
+	Internal bool   `protobuf:"varint,2,opt,name=internal,proto3" json:"internal,omitempty"` // Whether the network is isolated from the outside.
+}
+
+func (x *NetworkDef) Reset() {
+	*x = NetworkDef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NetworkDef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NetworkDef) ProtoMessage() {}
+
+func (x *NetworkDef) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NetworkDef.ProtoReflect.Descriptor instead.
+func (*NetworkDef) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *NetworkDef) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NetworkDef) GetInternal() bool {
+	if x != nil {
+		return x.Internal
+	}
+	return false
+}
+
+// Long-running container started before the stages and stopped after them.
+type ServiceDef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name      string             `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`                                                                                       // Name of the service, which is its host name in the network.
+	Image     string             `protobuf:"bytes,2,opt,name=image,proto3" json:"image,omitempty"`                                                                                     // Image of the container.
+	Command   []string           `protobuf:"bytes,3,rep,name=command,proto3" json:"command,omitempty"`                                                                                 // Arguments of the container.
+	Env       map[string]string  `protobuf:"bytes,4,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"` // Environment variables of the container.
+	Network   string             `protobuf:"bytes,5,opt,name=network,proto3" json:"network,omitempty"`                                                                                 // Network of the container.
+	Mounts    []*MountDef        `protobuf:"bytes,6,rep,name=mounts,proto3" json:"mounts,omitempty"`                                                                                   // Filesystems mounted in the container.
+	Resources *ResourcesDef      `protobuf:"bytes,7,opt,name=resources,proto3" json:"resources,omitempty"`                                                                             // Resource limits of the container.
+	Ready     *ReadinessCheckDef `protobuf:"bytes,8,opt,name=ready,proto3" json:"ready,omitempty"`                                                                                     // How to check whether the service is ready.
+	Secrets   []string           `protobuf:"bytes,9,rep,name=secrets,proto3" json:"secrets,omitempty"`                                                                                 // Names of the secrets passed to the container.
+}
+
+func (x *ServiceDef) Reset() {
+	*x = ServiceDef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ServiceDef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceDef) ProtoMessage() {}
+
+func (x *ServiceDef) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceDef.ProtoReflect.Descriptor instead.
+func (*ServiceDef) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ServiceDef) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ServiceDef) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
+}
+
+func (x *ServiceDef) GetCommand() []string {
+	if x != nil {
+		return x.Command
+	}
+	return nil
+}
+
+func (x *ServiceDef) GetEnv() map[string]string {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+func (x *ServiceDef) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *ServiceDef) GetMounts() []*MountDef {
+	if x != nil {
+		return x.Mounts
+	}
+	return nil
+}
+
+func (x *ServiceDef) GetResources() *ResourcesDef {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+func (x *ServiceDef) GetReady() *ReadinessCheckDef {
+	if x != nil {
+		return x.Ready
+	}
+	return nil
+}
+
+func (x *ServiceDef) GetSecrets() []string {
+	if x != nil {
+		return x.Secrets
+	}
+	return nil
+}
+
+// Check of whether a service is ready to be used.
+type ReadinessCheckDef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Port     int32                `protobuf:"varint,1,opt,name=port,proto3" json:"port,omitempty"`        // TCP port that accepts connections once the service is ready.
+	Command  []string             `protobuf:"bytes,2,rep,name=command,proto3" json:"command,omitempty"`   // Command that exits with 0 once the service is ready.
+	Interval *durationpb.Duration `protobuf:"bytes,3,opt,name=interval,proto3" json:"interval,omitempty"` // Wait between checks.
+	Timeout  *durationpb.Duration `protobuf:"bytes,4,opt,name=timeout,proto3" json:"timeout,omitempty"`   // Maximum wait for the service to be ready.
+}
+
+func (x *ReadinessCheckDef) Reset() {
+	*x = ReadinessCheckDef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReadinessCheckDef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadinessCheckDef) ProtoMessage() {}
+
+func (x *ReadinessCheckDef) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadinessCheckDef.ProtoReflect.Descriptor instead.
+func (*ReadinessCheckDef) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ReadinessCheckDef) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *ReadinessCheckDef) GetCommand() []string {
+	if x != nil {
+		return x.Command
+	}
+	return nil
+}
+
+func (x *ReadinessCheckDef) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+func (x *ReadinessCheckDef) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+// Execution of a service.
+type ServiceExecution struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Service   *ServiceDef            `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`                      // Definition of the service executed.
+	Container string                 `protobuf:"bytes,2,opt,name=container,proto3" json:"container,omitempty"`                  // Name of the service container.
+	Pull      *StepExecution         `protobuf:"bytes,3,opt,name=pull,proto3" json:"pull,omitempty"`                            // Details of the pull of the service image.
+	Run       *StepExecution         `protobuf:"bytes,4,opt,name=run,proto3" json:"run,omitempty"`                              // Output and exit status of the service container.
+	ReadyTime *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=ready_time,json=readyTime,proto3" json:"ready_time,omitempty"` // Time the service has been found ready.
+}
+
+func (x *ServiceExecution) Reset() {
+	*x = ServiceExecution{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ServiceExecution) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceExecution) ProtoMessage() {}
+
+func (x *ServiceExecution) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceExecution.ProtoReflect.Descriptor instead.
+func (*ServiceExecution) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ServiceExecution) GetService() *ServiceDef {
+	if x != nil {
+		return x.Service
+	}
+	return nil
+}
+
+func (x *ServiceExecution) GetContainer() string {
+	if x != nil {
+		return x.Container
+	}
+	return ""
+}
+
+func (x *ServiceExecution) GetPull() *StepExecution {
+	if x != nil {
+		return x.Pull
+	}
+	return nil
+}
+
+func (x *ServiceExecution) GetRun() *StepExecution {
+	if x != nil {
+		return x.Run
+	}
+	return nil
+}
+
+func (x *ServiceExecution) GetReadyTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReadyTime
+	}
+	return nil
+}
+
+type UlimitDef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`  // Name of the limit, e.g. nofile.
+	Soft int64  `protobuf:"varint,2,opt,name=soft,proto3" json:"soft,omitempty"` // Soft limit.
+	Hard int64  `protobuf:"varint,3,opt,name=hard,proto3" json:"hard,omitempty"` // Hard limit.
+}
+
+func (x *UlimitDef) Reset() {
+	*x = UlimitDef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UlimitDef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UlimitDef) ProtoMessage() {}
+
+func (x *UlimitDef) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UlimitDef.ProtoReflect.Descriptor instead.
+func (*UlimitDef) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *UlimitDef) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UlimitDef) GetSoft() int64 {
+	if x != nil {
+		return x.Soft
+	}
+	return 0
+}
+
+func (x *UlimitDef) GetHard() int64 {
+	if x != nil {
+		return x.Hard
+	}
+	return 0
+}
+
+// Credentials used to clone a repository. Only where the secrets are read
+// from is described, never the secrets.
+type GitAuthDef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Username            string `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`                                                      // HTTPS username.
+	PasswordEnv         string `protobuf:"bytes,2,opt,name=password_env,json=passwordEnv,proto3" json:"password_env,omitempty"`                             // Environment variable containing the HTTPS password or token.
+	PasswordFile        string `protobuf:"bytes,3,opt,name=password_file,json=passwordFile,proto3" json:"password_file,omitempty"`                          // File containing the HTTPS password or token.
+	SshUser             string `protobuf:"bytes,4,opt,name=ssh_user,json=sshUser,proto3" json:"ssh_user,omitempty"`                                         // SSH user.
+	SshKeyFile          string `protobuf:"bytes,5,opt,name=ssh_key_file,json=sshKeyFile,proto3" json:"ssh_key_file,omitempty"`                              // File containing the SSH private key.
+	SshKeyEnv           string `protobuf:"bytes,6,opt,name=ssh_key_env,json=sshKeyEnv,proto3" json:"ssh_key_env,omitempty"`                                 // Environment variable containing the SSH private key.
+	SshKeyPassphraseEnv string `protobuf:"bytes,7,opt,name=ssh_key_passphrase_env,json=sshKeyPassphraseEnv,proto3" json:"ssh_key_passphrase_env,omitempty"` // Environment variable containing the passphrase of the SSH private key.
+	SshAgent            bool   `protobuf:"varint,8,opt,name=ssh_agent,json=sshAgent,proto3" json:"ssh_agent,omitempty"`                                     // Authenticate with the keys of the SSH agent.
+	SshKnownHosts       string `protobuf:"bytes,9,opt,name=ssh_known_hosts,json=sshKnownHosts,proto3" json:"ssh_known_hosts,omitempty"`                     // known_hosts file used to check the host key.
+}
+
+func (x *GitAuthDef) Reset() {
+	*x = GitAuthDef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GitAuthDef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GitAuthDef) ProtoMessage() {}
+
+func (x *GitAuthDef) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GitAuthDef.ProtoReflect.Descriptor instead.
+func (*GitAuthDef) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GitAuthDef) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *GitAuthDef) GetPasswordEnv() string {
+	if x != nil {
+		return x.PasswordEnv
+	}
+	return ""
+}
+
+func (x *GitAuthDef) GetPasswordFile() string {
+	if x != nil {
+		return x.PasswordFile
+	}
+	return ""
+}
+
+func (x *GitAuthDef) GetSshUser() string {
+	if x != nil {
+		return x.SshUser
+	}
+	return ""
+}
+
+func (x *GitAuthDef) GetSshKeyFile() string {
+	if x != nil {
+		return x.SshKeyFile
+	}
+	return ""
+}
+
+func (x *GitAuthDef) GetSshKeyEnv() string {
+	if x != nil {
+		return x.SshKeyEnv
+	}
+	return ""
+}
+
+func (x *GitAuthDef) GetSshKeyPassphraseEnv() string {
+	if x != nil {
+		return x.SshKeyPassphraseEnv
+	}
+	return ""
+}
+
+func (x *GitAuthDef) GetSshAgent() bool {
+	if x != nil {
+		return x.SshAgent
+	}
+	return false
+}
+
+func (x *GitAuthDef) GetSshKnownHosts() string {
+	if x != nil {
+		return x.SshKnownHosts
+	}
+	return ""
+}
+
+// Secret passed to the containers as an environment variable. Only where its
+// value is read from is described, never the value.
+type SecretDef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`         // Name of the secret and of its environment variable.
+	Env      string `protobuf:"bytes,2,opt,name=env,proto3" json:"env,omitempty"`           // Environment variable containing the value.
+	File     string `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`         // File containing the value.
+	Provider string `protobuf:"bytes,4,opt,name=provider,proto3" json:"provider,omitempty"` // Key of the value in the secret provider.
+}
+
+func (x *SecretDef) Reset() {
+	*x = SecretDef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_structs_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SecretDef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretDef) ProtoMessage() {}
+
+func (x *SecretDef) ProtoReflect() protoreflect.Message {
+	mi := &file_structs_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretDef.ProtoReflect.Descriptor instead.
+func (*SecretDef) Descriptor() ([]byte, []int) {
+	return file_structs_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SecretDef) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SecretDef) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+func (x *SecretDef) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *SecretDef) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+var File_structs_proto protoreflect.FileDescriptor
+
+var file_structs_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
+	0x1e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2f, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
+	0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x22, 0x91, 0x03, 0x0a, 0x11, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x45, 0x78, 0x65,
+	0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x28, 0x0a, 0x08, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69,
+	0x6e, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x6c,
+	0x69, 0x6e, 0x65, 0x44, 0x65, 0x66, 0x52, 0x08, 0x70, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65,
+	0x12, 0x26, 0x0a, 0x0f, 0x73, 0x65, 0x74, 0x75, 0x70, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f,
+	0x6d, 0x73, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x65, 0x74, 0x75, 0x70,
+	0x45, 0x72, 0x72, 0x6f, 0x72, 0x4d, 0x73, 0x67, 0x12, 0x29, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x53, 0x74, 0x61, 0x67,
+	0x65, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x73, 0x12, 0x2c, 0x0a, 0x12, 0x74, 0x65, 0x61, 0x72, 0x64, 0x6f, 0x77, 0x6e, 0x5f,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x6d, 0x73, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x10, 0x74, 0x65, 0x61, 0x72, 0x64, 0x6f, 0x77, 0x6e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x4d, 0x73,
+	0x67, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x39, 0x0a, 0x0a, 0x73, 0x74, 0x61,
+	0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74,
+	0x54, 0x69, 0x6d, 0x65, 0x12, 0x3b, 0x0a, 0x0b, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x5f, 0x74,
+	0x69, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0a, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x54, 0x69, 0x6d,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x2d, 0x0a, 0x08, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x73, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x73, 0x22, 0x80, 0x09, 0x0a, 0x0b, 0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e,
+	0x65, 0x44, 0x65, 0x66, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x28, 0x0a, 0x10, 0x64, 0x65, 0x66, 0x61,
+	0x75, 0x6c, 0x74, 0x5f, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0e, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x42, 0x61, 0x73, 0x65, 0x44,
+	0x69, 0x72, 0x12, 0x4d, 0x0a, 0x11, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x62, 0x75,
+	0x69, 0x6c, 0x64, 0x5f, 0x65, 0x6e, 0x76, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e,
+	0x50, 0x69, 0x70, 0x65, 0x6c, 0x69, 0x6e, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x44, 0x65, 0x66, 0x61,
+	0x75, 0x6c, 0x74, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x0f, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x45, 0x6e,
+	0x76, 0x12, 0x47, 0x0a, 0x0f, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x72, 0x75, 0x6e,
+	0x5f, 0x65, 0x6e, 0x76, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x50, 0x69, 0x70,
+	0x65, 0x6c, 0x69, 0x6e, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x44, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74,
+	0x52, 0x75, 0x6e, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0d, 0x64, 0x65, 0x66,
+	0x61, 0x75, 0x6c, 0x74, 0x52, 0x75, 0x6e, 0x45, 0x6e, 0x76, 0x12, 0x1d, 0x0a, 0x0a, 0x76, 0x6f,
+	0x6c, 0x75, 0x6d, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x44, 0x69, 0x72, 0x12, 0x35, 0x0a, 0x17, 0x73, 0x6b, 0x69,
+	0x70, 0x5f, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x5f, 0x63, 0x6c, 0x65,
+	0x61, 0x6e, 0x75, 0x70, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x14, 0x73, 0x6b, 0x69, 0x70,
+	0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x44, 0x69, 0x72, 0x43, 0x6c, 0x65, 0x61, 0x6e, 0x75, 0x70,
+	0x12, 0x21, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x67, 0x65, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x09, 0x2e, 0x53, 0x74, 0x61, 0x67, 0x65, 0x44, 0x65, 0x66, 0x52, 0x06, 0x73, 0x74, 0x61,
+	0x67, 0x65, 0x73, 0x12, 0x2c, 0x0a, 0x0c, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x68, 0x61, 0x6e,
+	0x64, 0x65, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x53, 0x74, 0x61, 0x67,
+	0x65, 0x44, 0x65, 0x66, 0x52, 0x0b, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x48, 0x61, 0x6e, 0x64, 0x65,
+	0x72, 0x12, 0x1f, 0x0a, 0x0b, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x4e, 0x61,
+	0x6d, 0x65, 0x12, 0x42, 0x0a, 0x0f, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x74, 0x69,
+	0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0e, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x54,
+	0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x12, 0x27, 0x0a, 0x0f, 0x6d, 0x61, 0x78, 0x5f, 0x70, 0x61,
+	0x72, 0x61, 0x6c, 0x6c, 0x65, 0x6c, 0x69, 0x73, 0x6d, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0e, 0x6d, 0x61, 0x78, 0x50, 0x61, 0x72, 0x61, 0x6c, 0x6c, 0x65, 0x6c, 0x69, 0x73, 0x6d, 0x12,
+	0x26, 0x0a, 0x0f, 0x6d, 0x61, 0x78, 0x5f, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f, 0x73, 0x69,
+	0x7a, 0x65, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x6d, 0x61, 0x78, 0x4f, 0x75, 0x74,
+	0x70, 0x75, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x6f, 0x75, 0x74, 0x70, 0x75,
+	0x74, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6f, 0x75, 0x74,
+	0x70, 0x75, 0x74, 0x44, 0x69, 0x72, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
+	0x63, 0x68, 0x65, 0x63, 0x6b, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x44, 0x69, 0x72, 0x12, 0x17, 0x0a,
+	0x07, 0x6c, 0x6f, 0x67, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x6c, 0x6f, 0x67, 0x44, 0x69, 0x72, 0x12, 0x35, 0x0a, 0x10, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c,
+	0x74, 0x5f, 0x67, 0x69, 0x74, 0x5f, 0x61, 0x75, 0x74, 0x68, 0x18, 0x10, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0b, 0x2e, 0x47, 0x69, 0x74, 0x41, 0x75, 0x74, 0x68, 0x44, 0x65, 0x66, 0x52, 0x0e, 0x64,
+	0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x47, 0x69, 0x74, 0x41, 0x75, 0x74, 0x68, 0x12, 0x22, 0x0a,
+	0x0d, 0x67, 0x69, 0x74, 0x5f, 0x63, 0x61, 0x63, 0x68, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x18, 0x11,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x67, 0x69, 0x74, 0x43, 0x61, 0x63, 0x68, 0x65, 0x44, 0x69,
+	0x72, 0x12, 0x3a, 0x0a, 0x11, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x72, 0x65, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18, 0x12, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x52,
+	0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x44, 0x65, 0x66, 0x52, 0x10, 0x64, 0x65, 0x66,
+	0x61, 0x75, 0x6c, 0x74, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x12, 0x21, 0x0a,
+	0x06, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x18, 0x13, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e,
+	0x4d, 0x6f, 0x75, 0x6e, 0x74, 0x44, 0x65, 0x66, 0x52, 0x06, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x73,
+	0x12, 0x27, 0x0a, 0x08, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x73, 0x18, 0x14, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x44, 0x65, 0x66, 0x52,
+	0x08, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x64, 0x65, 0x66,
+	0x61, 0x75, 0x6c, 0x74, 0x5f, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x15, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0e, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x4e, 0x65, 0x74, 0x77, 0x6f,
+	0x72, 0x6b, 0x12, 0x27, 0x0a, 0x08, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x18, 0x16,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x44, 0x65,
+	0x66, 0x52, 0x08, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x12, 0x24, 0x0a, 0x07, 0x73,
+	0x65, 0x63, 0x72, 0x65, 0x74, 0x73, 0x18, 0x17, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x53,
+	0x65, 0x63, 0x72, 0x65, 0x74, 0x44, 0x65, 0x66, 0x52, 0x07, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74,
+	0x73, 0x1a, 0x42, 0x0a, 0x14, 0x44, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x42, 0x75, 0x69, 0x6c,
+	0x64, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x40, 0x0a, 0x12, 0x44, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74,
+	0x52, 0x75, 0x6e, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
+	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xf3, 0x04, 0x0a, 0x0e, 0x53, 0x74, 0x61, 0x67,
+	0x65, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x39, 0x0a, 0x0a, 0x73, 0x74,
+	0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72,
+	0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x3b, 0x0a, 0x0b, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x5f,
+	0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0a, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x54, 0x69,
+	0x6d, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f,
+	0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69,
+	0x6e, 0x65, 0x72, 0x49, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f,
+	0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74,
+	0x49, 0x64, 0x12, 0x24, 0x0a, 0x05, 0x73, 0x65, 0x74, 0x75, 0x70, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x0e, 0x2e, 0x53, 0x74, 0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f,
+	0x6e, 0x52, 0x05, 0x73, 0x65, 0x74, 0x75, 0x70, 0x12, 0x24, 0x0a, 0x05, 0x62, 0x75, 0x69, 0x6c,
+	0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x53, 0x74, 0x65, 0x70, 0x45, 0x78,
+	0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x05, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x12, 0x20,
+	0x0a, 0x03, 0x72, 0x75, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x53, 0x74,
+	0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x03, 0x72, 0x75, 0x6e,
+	0x12, 0x2a, 0x0a, 0x08, 0x74, 0x65, 0x61, 0x72, 0x64, 0x6f, 0x77, 0x6e, 0x18, 0x08, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x53, 0x74, 0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x08, 0x74, 0x65, 0x61, 0x72, 0x64, 0x6f, 0x77, 0x6e, 0x12, 0x2e, 0x0a, 0x06,
+	0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x16, 0x2e, 0x53,
+	0x74, 0x61, 0x67, 0x65, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x2a, 0x0a, 0x08,
+	0x61, 0x74, 0x74, 0x65, 0x6d, 0x70, 0x74, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e,
+	0x2e, 0x53, 0x74, 0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08,
+	0x61, 0x74, 0x74, 0x65, 0x6d, 0x70, 0x74, 0x73, 0x12, 0x19, 0x0a, 0x08, 0x69, 0x6d, 0x61, 0x67,
+	0x65, 0x5f, 0x69, 0x64, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x69, 0x6d, 0x61, 0x67,
+	0x65, 0x49, 0x64, 0x12, 0x1f, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x67, 0x65, 0x18, 0x0c, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x09, 0x2e, 0x53, 0x74, 0x61, 0x67, 0x65, 0x44, 0x65, 0x66, 0x52, 0x05, 0x73,
+	0x74, 0x61, 0x67, 0x65, 0x22, 0x77, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x06,
+	0x0a, 0x02, 0x4f, 0x4b, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x53, 0x45, 0x54, 0x55, 0x50, 0x5f,
+	0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x42, 0x55, 0x49, 0x4c, 0x44,
+	0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x02, 0x12, 0x0d, 0x0a, 0x09, 0x52, 0x55, 0x4e, 0x5f,
+	0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x03, 0x12, 0x12, 0x0a, 0x0e, 0x54, 0x45, 0x41, 0x52, 0x44,
+	0x4f, 0x57, 0x4e, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x04, 0x12, 0x11, 0x0a, 0x0d, 0x54,
+	0x49, 0x4d, 0x45, 0x4f, 0x55, 0x54, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x0b, 0x12, 0x0d,
+	0x0a, 0x09, 0x4f, 0x4f, 0x4d, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x0c, 0x22, 0xd2, 0x03,
+	0x0a, 0x0d, 0x53, 0x74, 0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x14, 0x0a, 0x05, 0x73, 0x74, 0x64, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x73, 0x74, 0x64, 0x69, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x64, 0x6f, 0x75, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x64, 0x6f, 0x75, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x73, 0x74, 0x64, 0x65, 0x72, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73,
+	0x74, 0x64, 0x65, 0x72, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x63, 0x6d, 0x64, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x63, 0x6d, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x63, 0x6d, 0x64, 0x5f, 0x64,
+	0x69, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6d, 0x64, 0x44, 0x69, 0x72,
+	0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x43, 0x6f, 0x64,
+	0x65, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x76, 0x18, 0x07, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03,
+	0x65, 0x6e, 0x76, 0x12, 0x39, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d,
+	0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x3b,
+	0x0a, 0x0b, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52,
+	0x0a, 0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x2a, 0x0a, 0x0a, 0x73,
+	0x74, 0x64, 0x69, 0x6e, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0b, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x09, 0x73, 0x74,
+	0x64, 0x69, 0x6e, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x2c, 0x0a, 0x0b, 0x73, 0x74, 0x64, 0x6f, 0x75,
+	0x74, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x0a, 0x73, 0x74, 0x64, 0x6f, 0x75,
+	0x74, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x2c, 0x0a, 0x0b, 0x73, 0x74, 0x64, 0x65, 0x72, 0x72, 0x5f,
+	0x66, 0x69, 0x6c, 0x65, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x0a, 0x73, 0x74, 0x64, 0x65, 0x72, 0x72, 0x46,
+	0x69, 0x6c, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x6f, 0x6f, 0x6d, 0x5f, 0x6b, 0x69, 0x6c, 0x6c, 0x65,
+	0x64, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x6f, 0x6f, 0x6d, 0x4b, 0x69, 0x6c, 0x6c,
+	0x65, 0x64, 0x22, 0x4c, 0x0a, 0x0a, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x46, 0x69, 0x6c, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x70, 0x61, 0x74, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68, 0x61, 0x32,
+	0x35, 0x36, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36,
+	0x22, 0xf3, 0x07, 0x0a, 0x08, 0x53, 0x74, 0x61, 0x67, 0x65, 0x44, 0x65, 0x66, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x12, 0x10, 0x0a, 0x03, 0x64, 0x69, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x64, 0x69, 0x72, 0x12, 0x19, 0x0a, 0x08, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x62, 0x61, 0x73, 0x65, 0x44, 0x69, 0x72, 0x12, 0x34,
+	0x0a, 0x09, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x65, 0x6e, 0x76, 0x18, 0x04, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x17, 0x2e, 0x53, 0x74, 0x61, 0x67, 0x65, 0x44, 0x65, 0x66, 0x2e, 0x42, 0x75, 0x69,
+	0x6c, 0x64, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x62, 0x75, 0x69, 0x6c,
+	0x64, 0x45, 0x6e, 0x76, 0x12, 0x2e, 0x0a, 0x07, 0x72, 0x75, 0x6e, 0x5f, 0x65, 0x6e, 0x76, 0x18,
+	0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x53, 0x74, 0x61, 0x67, 0x65, 0x44, 0x65, 0x66,
+	0x2e, 0x52, 0x75, 0x6e, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x72, 0x75,
+	0x6e, 0x45, 0x6e, 0x76, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x65, 0x70, 0x6f, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x72, 0x65, 0x70, 0x6f, 0x12, 0x2f, 0x0a, 0x14, 0x72, 0x65, 0x70, 0x6f,
+	0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x65, 0x6e, 0x76, 0x5f, 0x76, 0x61, 0x72,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x11, 0x72, 0x65, 0x70, 0x6f, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x45, 0x6e, 0x76, 0x56, 0x61, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6d, 0x61,
+	0x67, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x12,
+	0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18,
+	0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72,
+	0x49, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x5f, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x4e,
+	0x61, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x5f, 0x64, 0x69,
+	0x72, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x44,
+	0x69, 0x72, 0x12, 0x2a, 0x0a, 0x11, 0x72, 0x75, 0x6e, 0x5f, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73,
+	0x73, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x73, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x05, 0x52, 0x0f, 0x72,
+	0x75, 0x6e, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x43, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x33,
+	0x0a, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x74, 0x69, 0x6d, 0x65,
+	0x6f, 0x75, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x64, 0x65, 0x70, 0x65, 0x6e, 0x64, 0x73, 0x5f, 0x6f,
+	0x6e, 0x18, 0x0e, 0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x64, 0x65, 0x70, 0x65, 0x6e, 0x64, 0x73,
+	0x4f, 0x6e, 0x12, 0x25, 0x0a, 0x05, 0x72, 0x65, 0x74, 0x72, 0x79, 0x18, 0x0f, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x0f, 0x2e, 0x52, 0x65, 0x74, 0x72, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44,
+	0x65, 0x66, 0x52, 0x05, 0x72, 0x65, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x72, 0x65, 0x66,
+	0x18, 0x10, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x72, 0x65, 0x66, 0x12, 0x16, 0x0a, 0x06, 0x63,
+	0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x18, 0x11, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6d,
+	0x6d, 0x69, 0x74, 0x12, 0x26, 0x0a, 0x08, 0x67, 0x69, 0x74, 0x5f, 0x61, 0x75, 0x74, 0x68, 0x18,
+	0x12, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x47, 0x69, 0x74, 0x41, 0x75, 0x74, 0x68, 0x44,
+	0x65, 0x66, 0x52, 0x07, 0x67, 0x69, 0x74, 0x41, 0x75, 0x74, 0x68, 0x12, 0x1f, 0x0a, 0x0b, 0x63,
+	0x6c, 0x6f, 0x6e, 0x65, 0x5f, 0x64, 0x65, 0x70, 0x74, 0x68, 0x18, 0x13, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x0a, 0x63, 0x6c, 0x6f, 0x6e, 0x65, 0x44, 0x65, 0x70, 0x74, 0x68, 0x12, 0x23, 0x0a, 0x0d,
+	0x73, 0x69, 0x6e, 0x67, 0x6c, 0x65, 0x5f, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x18, 0x14, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0c, 0x73, 0x69, 0x6e, 0x67, 0x6c, 0x65, 0x42, 0x72, 0x61, 0x6e, 0x63,
+	0x68, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x75, 0x62, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65, 0x73, 0x18,
+	0x15, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x73, 0x75, 0x62, 0x6d, 0x6f, 0x64, 0x75, 0x6c, 0x65,
+	0x73, 0x12, 0x27, 0x0a, 0x0f, 0x73, 0x70, 0x61, 0x72, 0x73, 0x65, 0x5f, 0x63, 0x68, 0x65, 0x63,
+	0x6b, 0x6f, 0x75, 0x74, 0x18, 0x16, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x73, 0x70, 0x61, 0x72,
+	0x73, 0x65, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x12, 0x2b, 0x0a, 0x09, 0x72, 0x65,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18, 0x17, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e,
+	0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x44, 0x65, 0x66, 0x52, 0x09, 0x72, 0x65,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x12, 0x21, 0x0a, 0x06, 0x6d, 0x6f, 0x75, 0x6e, 0x74,
+	0x73, 0x18, 0x18, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4d, 0x6f, 0x75, 0x6e, 0x74, 0x44,
+	0x65, 0x66, 0x52, 0x06, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x6e, 0x65,
+	0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x19, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6e, 0x65, 0x74,
+	0x77, 0x6f, 0x72, 0x6b, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x73, 0x18,
+	0x1a, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x73, 0x1a, 0x3b,
+	0x0a, 0x0d, 0x42, 0x75, 0x69, 0x6c, 0x64, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x39, 0x0a, 0x0b, 0x52,
+	0x75, 0x6e, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xca, 0x02, 0x0a, 0x0e, 0x52, 0x65, 0x74, 0x72, 0x79,
+	0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x65, 0x66, 0x12, 0x21, 0x0a, 0x0c, 0x6d, 0x61, 0x78,
+	0x5f, 0x61, 0x74, 0x74, 0x65, 0x6d, 0x70, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0b, 0x6d, 0x61, 0x78, 0x41, 0x74, 0x74, 0x65, 0x6d, 0x70, 0x74, 0x73, 0x12, 0x42, 0x0a, 0x0f,
+	0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x5f, 0x62, 0x61, 0x63, 0x6b, 0x6f, 0x66, 0x66, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x0e, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x42, 0x61, 0x63, 0x6b, 0x6f, 0x66, 0x66,
+	0x12, 0x3a, 0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x5f, 0x62, 0x61, 0x63, 0x6b, 0x6f, 0x66, 0x66, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x0a, 0x6d, 0x61, 0x78, 0x42, 0x61, 0x63, 0x6b, 0x6f, 0x66, 0x66, 0x12, 0x16, 0x0a, 0x06,
+	0x6a, 0x69, 0x74, 0x74, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x6a, 0x69,
+	0x74, 0x74, 0x65, 0x72, 0x12, 0x42, 0x0a, 0x0f, 0x61, 0x74, 0x74, 0x65, 0x6d, 0x70, 0x74, 0x5f,
+	0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0e, 0x61, 0x74, 0x74, 0x65, 0x6d, 0x70,
+	0x74, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x78, 0x69, 0x74,
+	0x5f, 0x63, 0x6f, 0x64, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x05, 0x52, 0x09, 0x65, 0x78,
+	0x69, 0x74, 0x43, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x65, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x05, 0x52, 0x08, 0x73, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x65, 0x73, 0x22, 0xde, 0x01, 0x0a, 0x0c, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x73, 0x44, 0x65, 0x66, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x12, 0x1f, 0x0a, 0x0b,
+	0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x5f, 0x73, 0x77, 0x61, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0a, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x53, 0x77, 0x61, 0x70, 0x12, 0x12, 0x0a,
+	0x04, 0x63, 0x70, 0x75, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x63, 0x70, 0x75,
+	0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x70, 0x75, 0x5f, 0x73, 0x68, 0x61, 0x72, 0x65, 0x73, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x70, 0x75, 0x53, 0x68, 0x61, 0x72, 0x65, 0x73,
+	0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x69, 0x64, 0x73, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x70, 0x69, 0x64, 0x73, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12,
+	0x24, 0x0a, 0x07, 0x75, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x0a, 0x2e, 0x55, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x44, 0x65, 0x66, 0x52, 0x07, 0x75, 0x6c,
+	0x69, 0x6d, 0x69, 0x74, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x6d, 0x70, 0x66, 0x73, 0x5f, 0x73,
+	0x69, 0x7a, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x6d, 0x70, 0x66, 0x73,
+	0x53, 0x69, 0x7a, 0x65, 0x22, 0x7f, 0x0a, 0x08, 0x4d, 0x6f, 0x75, 0x6e, 0x74, 0x44, 0x65, 0x66,
+	0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x74, 0x79, 0x70, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06,
+	0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61,
+	0x72, 0x67, 0x65, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x72, 0x65, 0x61, 0x64, 0x5f, 0x6f, 0x6e, 0x6c,
+	0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x72, 0x65, 0x61, 0x64, 0x4f, 0x6e, 0x6c,
+	0x79, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x04, 0x73, 0x69, 0x7a, 0x65, 0x22, 0x3c, 0x0a, 0x0a, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b,
+	0x44, 0x65, 0x66, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72,
+	0x6e, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72,
+	0x6e, 0x61, 0x6c, 0x22, 0xde, 0x02, 0x0a, 0x0a, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x44,
+	0x65, 0x66, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x63,
+	0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x12, 0x26, 0x0a, 0x03, 0x65, 0x6e, 0x76, 0x18, 0x04, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x44, 0x65, 0x66,
+	0x2e, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x03, 0x65, 0x6e, 0x76, 0x12, 0x18,
+	0x0a, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12, 0x21, 0x0a, 0x06, 0x6d, 0x6f, 0x75, 0x6e,
+	0x74, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4d, 0x6f, 0x75, 0x6e, 0x74,
+	0x44, 0x65, 0x66, 0x52, 0x06, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x12, 0x2b, 0x0a, 0x09, 0x72,
+	0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d,
+	0x2e, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x44, 0x65, 0x66, 0x52, 0x09, 0x72,
+	0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x12, 0x28, 0x0a, 0x05, 0x72, 0x65, 0x61, 0x64,
+	0x79, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x52, 0x65, 0x61, 0x64, 0x69, 0x6e,
+	0x65, 0x73, 0x73, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x44, 0x65, 0x66, 0x52, 0x05, 0x72, 0x65, 0x61,
+	0x64, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x73, 0x18, 0x09, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x73, 0x1a, 0x36, 0x0a, 0x08,
+	0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x22, 0xad, 0x01, 0x0a, 0x11, 0x52, 0x65, 0x61, 0x64, 0x69, 0x6e, 0x65,
+	0x73, 0x73, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x44, 0x65, 0x66, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f,
+	0x72, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x18,
+	0x0a, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x12, 0x35, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x76, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12,
+	0x33, 0x0a, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x74, 0x69, 0x6d,
+	0x65, 0x6f, 0x75, 0x74, 0x22, 0xd8, 0x01, 0x0a, 0x10, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x25, 0x0a, 0x07, 0x73, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x44, 0x65, 0x66, 0x52, 0x07, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x12, 0x22,
+	0x0a, 0x04, 0x70, 0x75, 0x6c, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x53,
+	0x74, 0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x04, 0x70, 0x75,
+	0x6c, 0x6c, 0x12, 0x20, 0x0a, 0x03, 0x72, 0x75, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0e, 0x2e, 0x53, 0x74, 0x65, 0x70, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x03, 0x72, 0x75, 0x6e, 0x12, 0x39, 0x0a, 0x0a, 0x72, 0x65, 0x61, 0x64, 0x79, 0x5f, 0x74, 0x69,
+	0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x72, 0x65, 0x61, 0x64, 0x79, 0x54, 0x69, 0x6d, 0x65, 0x22,
+	0x47, 0x0a, 0x09, 0x55, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x44, 0x65, 0x66, 0x12, 0x12, 0x0a, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x73, 0x6f, 0x66, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04,
+	0x73, 0x6f, 0x66, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x72, 0x64, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x04, 0x68, 0x61, 0x72, 0x64, 0x22, 0xc7, 0x02, 0x0a, 0x0a, 0x47, 0x69, 0x74,
+	0x41, 0x75, 0x74, 0x68, 0x44, 0x65, 0x66, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x5f,
+	0x65, 0x6e, 0x76, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x70, 0x61, 0x73, 0x73, 0x77,
+	0x6f, 0x72, 0x64, 0x45, 0x6e, 0x76, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f,
+	0x72, 0x64, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x70,
+	0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x73,
+	0x73, 0x68, 0x5f, 0x75, 0x73, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73,
+	0x73, 0x68, 0x55, 0x73, 0x65, 0x72, 0x12, 0x20, 0x0a, 0x0c, 0x73, 0x73, 0x68, 0x5f, 0x6b, 0x65,
+	0x79, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x73,
+	0x68, 0x4b, 0x65, 0x79, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x1e, 0x0a, 0x0b, 0x73, 0x73, 0x68, 0x5f,
+	0x6b, 0x65, 0x79, 0x5f, 0x65, 0x6e, 0x76, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73,
+	0x73, 0x68, 0x4b, 0x65, 0x79, 0x45, 0x6e, 0x76, 0x12, 0x33, 0x0a, 0x16, 0x73, 0x73, 0x68, 0x5f,
+	0x6b, 0x65, 0x79, 0x5f, 0x70, 0x61, 0x73, 0x73, 0x70, 0x68, 0x72, 0x61, 0x73, 0x65, 0x5f, 0x65,
+	0x6e, 0x76, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x13, 0x73, 0x73, 0x68, 0x4b, 0x65, 0x79,
+	0x50, 0x61, 0x73, 0x73, 0x70, 0x68, 0x72, 0x61, 0x73, 0x65, 0x45, 0x6e, 0x76, 0x12, 0x1b, 0x0a,
+	0x09, 0x73, 0x73, 0x68, 0x5f, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x08, 0x73, 0x73, 0x68, 0x41, 0x67, 0x65, 0x6e, 0x74, 0x12, 0x26, 0x0a, 0x0f, 0x73, 0x73,
+	0x68, 0x5f, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x5f, 0x68, 0x6f, 0x73, 0x74, 0x73, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x73, 0x68, 0x4b, 0x6e, 0x6f, 0x77, 0x6e, 0x48, 0x6f, 0x73,
+	0x74, 0x73, 0x22, 0x61, 0x0a, 0x09, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x44, 0x65, 0x66, 0x12,
+	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x76, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x65, 0x6e, 0x76, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x6f,
+	0x76, 0x69, 0x64, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x72, 0x6f,
+	0x76, 0x69, 0x64, 0x65, 0x72, 0x42, 0x20, 0x5a, 0x1e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x64, 0x61, 0x64, 0x6f, 0x73, 0x6a, 0x75, 0x73, 0x62, 0x72, 0x2f, 0x65,
+	0x78, 0x65, 0x63, 0x75, 0x74, 0x6f, 0x72, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_structs_proto_rawDescOnce sync.Once
+	file_structs_proto_rawDescData = file_structs_proto_rawDesc
+)
+
+func file_structs_proto_rawDescGZIP() []byte {
+	file_structs_proto_rawDescOnce.Do(func() {
+		file_structs_proto_rawDescData = protoimpl.X.CompressGZIP(file_structs_proto_rawDescData)
+	})
+	return file_structs_proto_rawDescData
+}
+
+var file_structs_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_structs_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_structs_proto_goTypes = []interface{}{
+	(StageExecution_Status)(0),    // 0: StageExecution.Status
+	(*PipelineExecution)(nil),     // 1: PipelineExecution
+	(*PipelineDef)(nil),           // 2: PipelineDef
+	(*StageExecution)(nil),        // 3: StageExecution
+	(*StepExecution)(nil),         // 4: StepExecution
+	(*StreamFile)(nil),            // 5: StreamFile
+	(*StageDef)(nil),              // 6: StageDef
+	(*RetryPolicyDef)(nil),        // 7: RetryPolicyDef
+	(*ResourcesDef)(nil),          // 8: ResourcesDef
+	(*MountDef)(nil),              // 9: MountDef
+	(*NetworkDef)(nil),            // 10: NetworkDef
+	(*ServiceDef)(nil),            // 11: ServiceDef
+	(*ReadinessCheckDef)(nil),     // 12: ReadinessCheckDef
+	(*ServiceExecution)(nil),      // 13: ServiceExecution
+	(*UlimitDef)(nil),             // 14: UlimitDef
+	(*GitAuthDef)(nil),            // 15: GitAuthDef
+	(*SecretDef)(nil),             // 16: SecretDef
+	nil,                           // 17: PipelineDef.DefaultBuildEnvEntry
+	nil,                           // 18: PipelineDef.DefaultRunEnvEntry
+	nil,                           // 19: StageDef.BuildEnvEntry
+	nil,                           // 20: StageDef.RunEnvEntry
+	nil,                           // 21: ServiceDef.EnvEntry
+	(*timestamppb.Timestamp)(nil), // 22: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 23: google.protobuf.Duration
+}
+var file_structs_proto_depIdxs = []int32{
+	2,  // 0: PipelineExecution.pipeline:type_name -> PipelineDef
+	3,  // 1: PipelineExecution.results:type_name -> StageExecution
+	22, // 2: PipelineExecution.start_time:type_name -> google.protobuf.Timestamp
+	22, // 3: PipelineExecution.finish_time:type_name -> google.protobuf.Timestamp
+	13, // 4: PipelineExecution.services:type_name -> ServiceExecution
+	17, // 5: PipelineDef.default_build_env:type_name -> PipelineDef.DefaultBuildEnvEntry
+	18, // 6: PipelineDef.default_run_env:type_name -> PipelineDef.DefaultRunEnvEntry
+	6,  // 7: PipelineDef.stages:type_name -> StageDef
+	6,  // 8: PipelineDef.error_hander:type_name -> StageDef
+	23, // 9: PipelineDef.default_timeout:type_name -> google.protobuf.Duration
+	15, // 10: PipelineDef.default_git_auth:type_name -> GitAuthDef
+	8,  // 11: PipelineDef.default_resources:type_name -> ResourcesDef
+	9,  // 12: PipelineDef.mounts:type_name -> MountDef
+	10, // 13: PipelineDef.networks:type_name -> NetworkDef
+	11, // 14: PipelineDef.services:type_name -> ServiceDef
+	16, // 15: PipelineDef.secrets:type_name -> SecretDef
+	22, // 16: StageExecution.start_time:type_name -> google.protobuf.Timestamp
+	22, // 17: StageExecution.finish_time:type_name -> google.protobuf.Timestamp
+	4,  // 18: StageExecution.setup:type_name -> StepExecution
+	4,  // 19: StageExecution.build:type_name -> StepExecution
+	4,  // 20: StageExecution.run:type_name -> StepExecution
+	4,  // 21: StageExecution.teardown:type_name -> StepExecution
+	0,  // 22: StageExecution.status:type_name -> StageExecution.Status
+	4,  // 23: StageExecution.attempts:type_name -> StepExecution
+	6,  // 24: StageExecution.stage:type_name -> StageDef
+	22, // 25: StepExecution.start_time:type_name -> google.protobuf.Timestamp
+	22, // 26: StepExecution.finish_time:type_name -> google.protobuf.Timestamp
+	5,  // 27: StepExecution.stdin_file:type_name -> StreamFile
+	5,  // 28: StepExecution.stdout_file:type_name -> StreamFile
+	5,  // 29: StepExecution.stderr_file:type_name -> StreamFile
+	19, // 30: StageDef.build_env:type_name -> StageDef.BuildEnvEntry
+	20, // 31: StageDef.run_env:type_name -> StageDef.RunEnvEntry
+	23, // 32: StageDef.timeout:type_name -> google.protobuf.Duration
+	7,  // 33: StageDef.retry:type_name -> RetryPolicyDef
+	15, // 34: StageDef.git_auth:type_name -> GitAuthDef
+	8,  // 35: StageDef.resources:type_name -> ResourcesDef
+	9,  // 36: StageDef.mounts:type_name -> MountDef
+	23, // 37: RetryPolicyDef.initial_backoff:type_name -> google.protobuf.Duration
+	23, // 38: RetryPolicyDef.max_backoff:type_name -> google.protobuf.Duration
+	23, // 39: RetryPolicyDef.attempt_timeout:type_name -> google.protobuf.Duration
+	14, // 40: ResourcesDef.ulimits:type_name -> UlimitDef
+	21, // 41: ServiceDef.env:type_name -> ServiceDef.EnvEntry
+	9,  // 42: ServiceDef.mounts:type_name -> MountDef
+	8,  // 43: ServiceDef.resources:type_name -> ResourcesDef
+	12, // 44: ServiceDef.ready:type_name -> ReadinessCheckDef
+	23, // 45: ReadinessCheckDef.interval:type_name -> google.protobuf.Duration
+	23, // 46: ReadinessCheckDef.timeout:type_name -> google.protobuf.Duration
+	11, // 47: ServiceExecution.service:type_name -> ServiceDef
+	4,  // 48: ServiceExecution.pull:type_name -> StepExecution
+	4,  // 49: ServiceExecution.run:type_name -> StepExecution
+	22, // 50: ServiceExecution.ready_time:type_name -> google.protobuf.Timestamp
+	51, // [51:51] is the sub-list for method output_type
+	51, // [51:51] is the sub-list for method input_type
+	51, // [51:51] is the sub-list for extension type_name
+	51, // [51:51] is the sub-list for extension extendee
+	0,  // [0:51] is the sub-list for field type_name
+}
+
+func init() { file_structs_proto_init() }
+func file_structs_proto_init() {
+	if File_structs_proto != nil {
+		return
 	}
 	if !protoimpl.UnsafeEnabled {
 		file_structs_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
@@ -803,6 +2371,18 @@ func file_structs_proto_init() {
 			}
 		}
 		file_structs_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamFile); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_structs_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*StageDef); i {
 			case 0:
 				return &v.state
@@ -814,6 +2394,126 @@ func file_structs_proto_init() {
 				return nil
 			}
 		}
+		file_structs_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RetryPolicyDef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_structs_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResourcesDef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_structs_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MountDef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_structs_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NetworkDef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_structs_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ServiceDef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_structs_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReadinessCheckDef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_structs_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ServiceExecution); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_structs_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UlimitDef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_structs_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GitAuthDef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_structs_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SecretDef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -821,7 +2521,7 @@ func file_structs_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_structs_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   9,
+			NumMessages:   21,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
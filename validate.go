@@ -0,0 +1,581 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dadosjusbr/executor/status"
+)
+
+// ValidationError describes an invalid field of the pipeline spec.
+type ValidationError struct {
+	Path string // JSON path of the field, e.g. stages[2].run-env.
+	Msg  string // Description of the problem.
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+}
+
+// ValidationErrors lists every problem found in the pipeline spec.
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add appends the problem of the field at path.
+func (errs *ValidationErrors) add(path, format string, args ...interface{}) {
+	*errs = append(*errs, &ValidationError{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+var (
+	// envVarName matches the names of environment variables accepted by
+	// shells.
+	envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// imageName matches the image names accepted by docker. The ContainerID
+	// names both the built image and the container.
+	imageName = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	// scpLikeURL matches repository URLs like git@github.com:org/repo.git.
+	scpLikeURL = regexp.MustCompile(`^[A-Za-z0-9_.-]+@[A-Za-z0-9_.-]+:[^/].*$`)
+	// commitHash matches full or abbreviated commit hashes.
+	commitHash = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+	// objectName matches the volume and network names accepted by docker.
+	objectName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+	// mountPath matches the mount paths that can be passed to docker run
+	// --mount, which splits its value at commas, in a shell command line.
+	mountPath = regexp.MustCompile(`^/[^\s,"'$\x60\\]*$`)
+)
+
+// Validate checks the pipeline spec and returns ValidationErrors listing
+// every problem found, or nil. The directories of stages built from local
+// sources must exist, so the spec should be validated after setting
+// DefaultBaseDir.
+func (p *Pipeline) Validate() error {
+	if errs := p.validate(true); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate returns the problems of the pipeline spec. The directories of the
+// local stage sources are only checked if checkSources is true.
+func (p *Pipeline) validate(checkSources bool) ValidationErrors {
+	var errs ValidationErrors
+	validateEnv(&errs, "default-build-env", p.DefaultBuildEnv)
+	validateEnv(&errs, "default-run-env", p.DefaultRunEnv)
+	p.DefaultGitAuth.validate(&errs, "default-git-auth")
+	p.DefaultResources.validate(&errs, "default-resources")
+	validateMounts(&errs, "mounts", p.Mounts, p.reservedTargets(p.DefaultResources), checkSources)
+	networks := make(map[string]int)
+	for i, n := range p.Networks {
+		path := fmt.Sprintf("networks[%d].name", i)
+		switch j, ok := networks[n.Name]; {
+		case !objectName.MatchString(n.Name):
+			errs.add(path, "invalid network name %q: it must contain only letters, digits and separators (_, ., -)", n.Name)
+		case slices.Contains(predefinedNetworks, n.Name):
+			errs.add(path, "network %s is predefined by docker", n.Name)
+		case ok:
+			errs.add(path, "duplicate network %s, also defined by networks[%d]", n.Name, j)
+		default:
+			networks[n.Name] = i
+		}
+	}
+	validateNetwork(&errs, "default-network", p.DefaultNetwork)
+	secrets := make(map[string]int)
+	for i, s := range p.Secrets {
+		path := fmt.Sprintf("secrets[%d]", i)
+		switch j, ok := secrets[s.Name]; {
+		case s.Name == "":
+			errs.add(path+".name", "must be set")
+		case !envVarName.MatchString(s.Name):
+			errs.add(path+".name", "invalid environment variable name %q", s.Name)
+		case ok:
+			errs.add(path+".name", "duplicate secret %s, also defined by secrets[%d]", s.Name, j)
+		default:
+			secrets[s.Name] = i
+		}
+		s.validate(&errs, path)
+	}
+	services := make(map[string]int)
+	for i, s := range p.Services {
+		path := fmt.Sprintf("services[%d]", i)
+		s.validate(&errs, path, *p, checkSources)
+		if j, ok := services[s.Name]; ok && s.Name != "" {
+			errs.add(path+".name", "duplicate service name %q, also used by services[%d]", s.Name, j)
+		} else {
+			services[s.Name] = i
+		}
+	}
+	names := make(map[string]int)
+	ids := make(map[string]int)
+	// Cycles are only searched for if the dependencies are well defined.
+	findCycles := true
+	for i, s := range p.Stages {
+		path := fmt.Sprintf("stages[%d]", i)
+		s.validate(&errs, path, *p, checkSources)
+		if s.Name == "" {
+			continue
+		}
+		if j, ok := names[s.Name]; ok {
+			errs.add(path+".name", "duplicate stage name %q, also used by stages[%d]", s.Name, j)
+			findCycles = false
+		} else {
+			names[s.Name] = i
+		}
+		if j, ok := ids[s.containerID()]; ok && s.Name != p.Stages[j].Name {
+			errs.add(path+".name", "container name %q is also used by stages[%d]", s.containerID(), j)
+		} else {
+			ids[s.containerID()] = i
+		}
+	}
+	for i, s := range p.Stages {
+		for j, d := range s.DependsOn {
+			path := fmt.Sprintf("stages[%d].depends-on[%d]", i, j)
+			switch _, ok := names[d]; {
+			case !ok:
+				errs.add(path, "unknown stage %q", d)
+				findCycles = false
+			case d == s.Name:
+				errs.add(path, "stage depends on itself")
+				findCycles = false
+			}
+		}
+	}
+	if findCycles {
+		if _, err := newStageGraph(p.Stages); err != nil {
+			errs.add("stages", "%v", err)
+		}
+	}
+	if !reflect.ValueOf(p.ErrorHandler).IsZero() {
+		p.ErrorHandler.validate(&errs, "error-handler", *p, checkSources)
+	}
+	return errs
+}
+
+// validate appends the problems of the stage spec, whose JSON path is path.
+func (stage *Stage) validate(errs *ValidationErrors, path string, pipeline Pipeline, checkSources bool) {
+	if stage.Name == "" {
+		errs.add(path+".name", "must be set")
+	}
+	if stage.Repo != "" && stage.Image != "" {
+		errs.add(path+".image", "repo and image can not be set at the same time")
+	}
+	switch id := stage.containerID(); {
+	case stage.ContainerID != "" && !imageName.MatchString(id):
+		errs.add(path+".container-id", "invalid container name %q: it must contain only lowercase letters, digits and separators (., _, -)", id)
+	case stage.Name != "" && !imageName.MatchString(id):
+		errs.add(path+".name", "invalid container name %q derived from the name: it must contain only lowercase letters, digits, spaces and separators (., _, -)", id)
+	}
+	validateEnv(errs, path+".build-env", stage.BuildEnv)
+	validateEnv(errs, path+".run-env", stage.RunEnv)
+	if v := stage.RepoVersionEnvVar; v != "" && !envVarName.MatchString(v) {
+		errs.add(path+".repo_version_env_var", "invalid environment variable name %q", v)
+	}
+	runEnv := mergeEnv(pipeline.DefaultRunEnv, stage.RunEnv)
+	if stage.RepoVersionEnvVar != "" {
+		runEnv[stage.RepoVersionEnvVar] = ""
+	}
+	validateSecretRefs(errs, path+".secrets", stage.Secrets, pipeline, runEnv)
+	if stage.Repo != "" {
+		if err := validateRepoURL(stage.Repo); err != nil {
+			errs.add(path+".repo", "%v", err)
+		}
+	}
+	if !reflect.ValueOf(stage.GitAuth).IsZero() {
+		if stage.Repo == "" {
+			errs.add(path+".git-auth", "can only be set with repo")
+		}
+		stage.GitAuth.validate(errs, path+".git-auth")
+	}
+	if stage.Ref != "" && stage.Repo == "" {
+		errs.add(path+".ref", "can only be set with repo")
+	}
+	switch {
+	case stage.Commit != "" && stage.Repo == "":
+		errs.add(path+".commit", "can only be set with repo")
+	case stage.Commit != "" && !commitHash.MatchString(stage.Commit):
+		errs.add(path+".commit", "invalid commit %q: it must have 4 to 40 hexadecimal digits", stage.Commit)
+	}
+	cloneOptions := []struct {
+		name string
+		set  bool
+	}{
+		{"clone-depth", stage.CloneDepth != 0},
+		{"single-branch", stage.SingleBranch},
+		{"submodules", stage.Submodules},
+		{"sparse-checkout", stage.SparseCheckout},
+	}
+	for _, o := range cloneOptions {
+		if o.set && stage.Repo == "" {
+			errs.add(path+"."+o.name, "can only be set with repo")
+		}
+	}
+	if stage.CloneDepth < 0 {
+		errs.add(path+".clone-depth", "must not be negative, got %d", stage.CloneDepth)
+	}
+	if stage.SparseCheckout && filepath.Clean(stage.Dir) == "." {
+		errs.add(path+".sparse-checkout", "dir must be set")
+	}
+	stage.Resources.validate(errs, path+".resources")
+	stage.Retry.validate(errs, path+".retry")
+	validateMounts(errs, path+".mounts", stage.Mounts, stage.reservedTargets(pipeline), checkSources)
+	validateNetwork(errs, path+".network", stage.Network)
+	for i, c := range stage.RunSuccessCodes {
+		if c < 0 || c > 255 {
+			errs.add(fmt.Sprintf("%s.run-success-codes[%d]", path, i), "exit code %d out of range 0-255", c)
+		}
+	}
+	// Stages built from local sources need their directory.
+	if checkSources && stage.Repo == "" && stage.Image == "" {
+		baseDir := stage.BaseDir
+		if baseDir == "" {
+			baseDir = pipeline.DefaultBaseDir
+		}
+		dir := filepath.Join(baseDir, stage.Dir)
+		if dir == "" {
+			dir = "."
+		}
+		field := path + ".dir"
+		if stage.Dir == "" {
+			field = path + ".base-dir"
+		}
+		if fi, err := os.Stat(dir); err != nil {
+			errs.add(field, "directory %s of the stage sources does not exist", dir)
+		} else if !fi.IsDir() {
+			errs.add(field, "%s is not a directory", dir)
+		}
+	}
+}
+
+// validateEnv appends the invalid variable names of env, whose JSON path is
+// path.
+func validateEnv(errs *ValidationErrors, path string, env map[string]string) {
+	for _, k := range sortedKeys(env) {
+		if !envVarName.MatchString(k) {
+			errs.add(path+"."+k, "invalid environment variable name %q", k)
+		}
+	}
+}
+
+// validate appends the problems of the credentials spec, whose JSON path is
+// path.
+func (a GitAuth) validate(errs *ValidationErrors, path string) {
+	for _, f := range []struct{ field, name string }{
+		{"password-env", a.PasswordEnv},
+		{"ssh-key-env", a.SSHKeyEnv},
+		{"ssh-key-passphrase-env", a.SSHKeyPassphraseEnv},
+	} {
+		if f.name != "" && !envVarName.MatchString(f.name) {
+			errs.add(path+"."+f.field, "invalid environment variable name %q", f.name)
+		}
+	}
+	if a.PasswordEnv != "" && a.PasswordFile != "" {
+		errs.add(path+".password-file", "password-env and password-file can not be set at the same time")
+	}
+	if a.SSHKeyEnv != "" && a.SSHKeyFile != "" {
+		errs.add(path+".ssh-key-file", "ssh-key-env and ssh-key-file can not be set at the same time")
+	}
+	switch {
+	case a.isHTTP() && a.isSSH():
+		errs.add(path, "https (username, password-*) and ssh (ssh-*) credentials can not be set at the same time")
+	case a.isHTTP() && a.PasswordEnv == "" && a.PasswordFile == "":
+		errs.add(path, "https credentials need password-env or password-file")
+	case a.isSSH() && a.SSHKeyEnv == "" && a.SSHKeyFile == "" && !a.SSHAgent:
+		errs.add(path, "ssh credentials need ssh-key-file, ssh-key-env or ssh-agent")
+	}
+}
+
+// validate appends the problems of the resources spec, whose JSON path is
+// path.
+func (r Resources) validate(errs *ValidationErrors, path string) {
+	for _, f := range []struct {
+		field     string
+		value     int64
+		unlimited bool // Whether -1 means unlimited.
+	}{
+		{"memory", int64(r.Memory), false},
+		{"memory-swap", int64(r.MemorySwap), true},
+		{"cpu-shares", r.CPUShares, false},
+		{"pids-limit", r.PidsLimit, true},
+		{"tmpfs-size", int64(r.TmpfsSize), false},
+	} {
+		if f.value < 0 && !(f.unlimited && f.value == -1) {
+			errs.add(path+"."+f.field, "must not be negative, got %d", f.value)
+		}
+	}
+	if r.CPUs < 0 {
+		errs.add(path+".cpus", "must not be negative, got %g", r.CPUs)
+	}
+	if r.Memory > 0 && r.MemorySwap > 0 && r.MemorySwap < r.Memory {
+		errs.add(path+".memory-swap", "must not be less than memory (%s), got %s", r.Memory, r.MemorySwap)
+	}
+	names := make(map[string]int)
+	for i, u := range r.Ulimits {
+		p := fmt.Sprintf("%s.ulimits[%d]", path, i)
+		if u.Name == "" {
+			errs.add(p+".name", "must be set")
+		} else if j, ok := names[u.Name]; ok {
+			errs.add(p+".name", "duplicate ulimit %q, also set by ulimits[%d]", u.Name, j)
+		} else {
+			names[u.Name] = i
+		}
+		if u.Soft < 0 || u.Hard < 0 {
+			errs.add(p, "limits must not be negative")
+		} else if u.hard() < u.Soft {
+			errs.add(p+".hard", "must not be less than soft (%d), got %d", u.Soft, u.Hard)
+		}
+	}
+}
+
+// validate appends the problems of the retry policy, whose JSON path is path.
+func (rp RetryPolicy) validate(errs *ValidationErrors, path string) {
+	if rp.MaxAttempts < 0 {
+		errs.add(path+".max-attempts", "must not be negative, got %d", rp.MaxAttempts)
+	}
+	for _, f := range []struct {
+		field string
+		value Duration
+	}{
+		{"initial-backoff", rp.InitialBackoff},
+		{"max-backoff", rp.MaxBackoff},
+		{"attempt-timeout", rp.AttemptTimeout},
+	} {
+		if f.value < 0 {
+			errs.add(path+"."+f.field, "must not be negative, got %s", f.value)
+		}
+	}
+	if rp.InitialBackoff > 0 && rp.MaxBackoff > 0 && rp.MaxBackoff < rp.InitialBackoff {
+		errs.add(path+".max-backoff", "must not be less than initial-backoff (%s), got %s", rp.InitialBackoff, rp.MaxBackoff)
+	}
+	if rp.Jitter < 0 || rp.Jitter > 1 {
+		errs.add(path+".jitter", "must be between 0 and 1, got %g", rp.Jitter)
+	}
+	for i, c := range rp.ExitCodes {
+		if c < 0 || c > 255 {
+			errs.add(fmt.Sprintf("%s.exit-codes[%d]", path, i), "exit code %d out of range 0-255", c)
+		}
+	}
+	for i, c := range rp.Statuses {
+		if !slices.Contains(attemptStatuses, c) {
+			errs.add(fmt.Sprintf("%s.statuses[%d]", path, i), "status %d(%s) is never the status of an attempt, it must be RunError, TimeoutError or OOMError", c, status.Text(c))
+		}
+	}
+}
+
+// validate appends the problems of the service spec, whose JSON path is path.
+func (s Service) validate(errs *ValidationErrors, path string, pipeline Pipeline, checkSources bool) {
+	switch {
+	case s.Name == "":
+		errs.add(path+".name", "must be set")
+	case !imageName.MatchString(s.Name):
+		errs.add(path+".name", "invalid service name %q: it must contain only lowercase letters, digits and separators (., _, -)", s.Name)
+	}
+	if s.Image == "" {
+		errs.add(path+".image", "must be set")
+	}
+	validateEnv(errs, path+".env", s.Env)
+	validateSecretRefs(errs, path+".secrets", s.Secrets, pipeline, s.Env)
+	validateNetwork(errs, path+".network", s.Network)
+	// The stages reach services by name only in user-defined networks.
+	switch network := s.network(pipeline); network {
+	case "":
+		errs.add(path+".network", "must be set, unless default-network is set")
+	case BridgeNetwork, NoNetwork:
+		errs.add(path+".network", "services must be in a user-defined network or in the host network, got %s", network)
+	}
+	s.Resources.validate(errs, path+".resources")
+	validateMounts(errs, path+".mounts", s.Mounts, (&Pipeline{}).reservedTargets(s.Resources), checkSources)
+	if s.Ready.Port < 0 || s.Ready.Port > 65535 {
+		errs.add(path+".ready.port", "port %d out of range 0-65535", s.Ready.Port)
+	}
+	if s.Ready.Interval < 0 {
+		errs.add(path+".ready.interval", "must not be negative, got %s", s.Ready.Interval)
+	}
+	if s.Ready.Timeout < 0 {
+		errs.add(path+".ready.timeout", "must not be negative, got %s", s.Ready.Timeout)
+	}
+}
+
+// validate appends the problems of the secret spec, whose JSON path is path.
+func (s Secret) validate(errs *ValidationErrors, path string) {
+	sources := 0
+	for _, src := range []string{s.Env, s.File, s.Provider} {
+		if src != "" {
+			sources++
+		}
+	}
+	switch {
+	case sources == 0:
+		errs.add(path, "one of env, file or provider must be set")
+	case sources > 1:
+		errs.add(path, "only one of env, file or provider can be set")
+	}
+	if s.Env != "" && !envVarName.MatchString(s.Env) {
+		errs.add(path+".env", "invalid environment variable name %q", s.Env)
+	}
+}
+
+// validateSecretRefs appends the problems of the secret names, whose JSON
+// path is path. The secrets must be defined by the pipeline and must not
+// clash with the variables of env, which the container also receives.
+func validateSecretRefs(errs *ValidationErrors, path string, names []string, pipeline Pipeline, env map[string]string) {
+	seen := make(map[string]int)
+	for i, n := range names {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		j, dup := seen[n]
+		_, clash := env[n]
+		switch {
+		case !slices.ContainsFunc(pipeline.Secrets, func(s Secret) bool { return s.Name == n }):
+			errs.add(p, "unknown secret %q", n)
+		case dup:
+			errs.add(p, "duplicate secret %s, also listed by %s[%d]", n, path, j)
+		case clash:
+			errs.add(p, "secret %s clashes with the environment variable of the same name", n)
+		default:
+			seen[n] = i
+		}
+	}
+}
+
+// validateNetwork appends the problem of the network name, whose JSON path is
+// path, if any. The network is not required to be a pipeline network, since
+// it may be created outside the executor.
+func validateNetwork(errs *ValidationErrors, path, network string) {
+	if network != "" && !objectName.MatchString(network) {
+		errs.add(path, "invalid network name %q: it must contain only letters, digits and separators (_, ., -)", network)
+	}
+}
+
+// reservedTarget is a container path in which only the executor mounts
+// filesystems.
+type reservedTarget struct {
+	dir   string
+	field string // Field setting the mount.
+}
+
+// reservedTargets returns the container paths in which the stages mount the
+// shared volume and the tmpfs of the resources.
+func (p *Pipeline) reservedTargets(resources Resources) []reservedTarget {
+	var targets []reservedTarget
+	if p.VolumeName != "" && p.VolumeDir != "" {
+		targets = append(targets, reservedTarget{p.VolumeDir, "volume-dir"})
+	}
+	if resources.TmpfsSize != 0 {
+		targets = append(targets, reservedTarget{tmpDir, "tmpfs-size"})
+	}
+	return targets
+}
+
+// reservedTargets returns the container paths in which the stage mounts the
+// shared volume and the tmpfs of its resources.
+func (stage *Stage) reservedTargets(pipeline Pipeline) []reservedTarget {
+	if stage.VolumeName != "" {
+		pipeline.VolumeName = stage.VolumeName
+	}
+	if stage.VolumeDir != "" {
+		pipeline.VolumeDir = stage.VolumeDir
+	}
+	return pipeline.reservedTargets(stage.Resources.withDefaults(pipeline.DefaultResources))
+}
+
+// validateMounts appends the problems of the mounts, whose JSON path is path.
+// The sources of bind mounts must exist if checkSources is true.
+func validateMounts(errs *ValidationErrors, path string, mounts []Mount, reserved []reservedTarget, checkSources bool) {
+	targets := make(map[string]int)
+	for i, m := range mounts {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		switch m.Type {
+		case BindMount:
+			switch {
+			case !mountPath.MatchString(m.Source):
+				errs.add(p+".source", "invalid host path %q: it must be absolute and must not contain spaces, commas, quotes, $, ` or \\", m.Source)
+			case checkSources:
+				if _, err := os.Stat(m.Source); err != nil {
+					errs.add(p+".source", "host path %s does not exist", m.Source)
+				}
+			}
+		case VolumeMount:
+			if !objectName.MatchString(m.Source) {
+				errs.add(p+".source", "invalid volume name %q: it must contain only letters, digits and separators (_, ., -)", m.Source)
+			}
+		case TmpfsMount:
+			if m.Source != "" {
+				errs.add(p+".source", "can not be set in tmpfs mounts")
+			}
+		default:
+			errs.add(p+".type", "unknown mount type %q: it must be one of %q", m.Type, mountTypes)
+		}
+		switch {
+		case m.Size < 0:
+			errs.add(p+".size", "must not be negative, got %d", m.Size)
+		case m.Size != 0 && m.Type != TmpfsMount:
+			errs.add(p+".size", "can only be set in tmpfs mounts")
+		}
+		if !mountPath.MatchString(m.Target) {
+			errs.add(p+".target", "invalid container path %q: it must be absolute and must not contain spaces, commas, quotes, $, ` or \\", m.Target)
+			continue
+		}
+		target := filepath.Clean(m.Target)
+		if j, ok := targets[target]; ok {
+			errs.add(p+".target", "duplicate target %s, also used by %s[%d]", target, path, j)
+		} else {
+			targets[target] = i
+		}
+		for _, r := range reserved {
+			if filepath.Clean(r.dir) == target {
+				errs.add(p+".target", "%s is already mounted by %s", target, r.field)
+			}
+		}
+	}
+}
+
+// validateRepoURL checks whether the repository can be cloned from repo,
+// which can omit the https scheme, e.g. github.com/dadosjusbr/coletor-cnj.
+func validateRepoURL(repo string) error {
+	if scpLikeURL.MatchString(repo) {
+		return nil
+	}
+	u, err := url.Parse(repo)
+	if err != nil {
+		// The parsing error includes the URL, which may contain credentials.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("malformed repository URL: %w", err)
+	}
+	_, hasPassword := u.User.Password()
+	host, _, _ := strings.Cut(u.Path, "/")
+	if hasPassword || (u.User != nil && u.Scheme != "ssh") || (u.Scheme == "" && strings.Contains(host, "@")) {
+		return fmt.Errorf("credentials must not be in the repository URL, set them in git-auth")
+	}
+	switch u.Scheme {
+	case "":
+		if strings.HasPrefix(u.Path, "/") || !strings.Contains(u.Path, "/") {
+			return fmt.Errorf("malformed repository URL %q: it must be like github.com/org/repo", repo)
+		}
+	case "http", "https", "ssh", "git":
+		if u.Host == "" {
+			return fmt.Errorf("malformed repository URL %q: missing host", repo)
+		}
+	case "file":
+		if u.Path == "" {
+			return fmt.Errorf("malformed repository URL %q: missing path", repo)
+		}
+	default:
+		return fmt.Errorf("unsupported repository URL scheme %q", u.Scheme)
+	}
+	return nil
+}